@@ -1,13 +1,37 @@
 package bytehouse
 
+import "time"
+
 const (
 	InsertBlockSize        = "insert_block_size"
+	InsertBlockBytes       = "insert_block_bytes"
+	InsertFlushInterval    = "insert_flush_interval"
 	InsertBlockParallelism = "insert_block_parallelism"
 	InsertConnectionCount  = "insert_connection_count"
+	// Compression is the compression of input inserted from readers and of external tables from readers:
+	// auto, none, gzip, zstd, lz4, bz2 or xz. auto detects gzip, zstd, lz4, bz2 and xz input by its magic bytes.
+	Compression = "compression"
+	// DeadlineMaxExecutionTime sends the remaining deadline of the ctx of a query to the server as max_execution_time,
+	// in whole seconds of at least 1, so that the server also stops the query on time. The query then fails with
+	// a *sdk.TimeoutError telling if the ctx deadline or the server limit was exceeded.
+	DeadlineMaxExecutionTime = "deadline_max_execution_time"
+	// DeadlineExecutionSpeedCheck is sent as timeout_before_checking_execution_speed with the max_execution_time of
+	// DeadlineMaxExecutionTime if not zero, after which the server fails the query early if it estimates that the
+	// query cannot finish before the deadline.
+	DeadlineExecutionSpeedCheck = "deadline_execution_speed_check"
 )
 
+// Default holds the default value of each client setting.
+// InsertBlockBytes and InsertFlushInterval are disabled when zero,
+// otherwise an insert block is flushed as soon as any of
+// InsertBlockSize, InsertBlockBytes or InsertFlushInterval is reached.
 var Default = map[string]interface{}{
-	InsertBlockSize:        65536,
-	InsertConnectionCount:  1,
-	InsertBlockParallelism: 1,
+	InsertBlockSize:             65536,
+	InsertBlockBytes:            0,
+	InsertFlushInterval:         time.Duration(0),
+	InsertConnectionCount:       1,
+	InsertBlockParallelism:      1,
+	Compression:                 "auto",
+	DeadlineMaxExecutionTime:    false,
+	DeadlineExecutionSpeedCheck: time.Duration(0),
 }
@@ -0,0 +1,154 @@
+package conn
+
+import (
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/bytehouse-cloud/driver-go/driver/protocol"
+)
+
+// DefaultCancelDrainTimeout is the time given to the server to end a cancelled query,
+// after which the connection is closed
+const DefaultCancelDrainTimeout = 10 * time.Second
+
+// queryCancel is the cancellation of a query, after which the remaining packets of the query are drained
+// so that the connection can be reused
+type queryCancel struct {
+	cancelOnce sync.Once
+	cancelled  chan struct{}
+	// finished is closed when the last packet of the query is read, or reading fails
+	finishOnce sync.Once
+	finished   chan struct{}
+	// dropped is closed when the connection of the cancelled query is closed instead of drained
+	dropOnce sync.Once
+	dropped  chan struct{}
+	// sent is closed when the cancel is written
+	sent chan struct{}
+}
+
+func newQueryCancel() *queryCancel {
+	return &queryCancel{
+		cancelled: make(chan struct{}),
+		finished:  make(chan struct{}),
+		dropped:   make(chan struct{}),
+		sent:      make(chan struct{}),
+	}
+}
+
+// cancel tells if the query is cancelled by this call, false if it was already cancelled or finished
+func (c *queryCancel) cancel() bool {
+	if c.isFinished() {
+		return false
+	}
+	var cancelled bool
+	c.cancelOnce.Do(func() {
+		close(c.cancelled)
+		cancelled = true
+	})
+	return cancelled
+}
+
+func (c *queryCancel) finish() {
+	c.finishOnce.Do(func() {
+		close(c.finished)
+	})
+}
+
+func (c *queryCancel) isFinished() bool {
+	select {
+	case <-c.finished:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *queryCancel) drop() {
+	c.dropOnce.Do(func() {
+		close(c.dropped)
+	})
+}
+
+// Cancel cancels the current query, non-blocking process.
+// The server is sent a cancel and the remaining packets of the query are drained by the response stream,
+// after which the connection can be reused. The connection is closed if the packets cannot be drained
+// within the cancel drain timeout, DefaultCancelDrainTimeout by default.
+func (g *GatewayConn) Cancel() {
+	g.cancelQuery(g.queryCancel)
+}
+
+func (g *GatewayConn) cancelQuery(qc *queryCancel) {
+	if qc == nil || !qc.cancel() {
+		return
+	}
+	c := g.conn
+	drop := func() {
+		g.dropConn(c)
+		qc.drop()
+	}
+
+	timeout := g.cancelDrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultCancelDrainTimeout
+	}
+	time.AfterFunc(timeout, func() {
+		if qc.isFinished() {
+			return
+		}
+		if g.logf != nil {
+			g.logf("cancelled query not ended by server after %v, closing connection", timeout)
+		}
+		drop()
+	})
+
+	go func() {
+		defer close(qc.sent)
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+					r,
+					string(debug.Stack()))
+			}
+		}()
+		if err := g.writeUvarint(protocol.ClientCancel); err == nil {
+			if err = g.flush(); err == nil {
+				return
+			}
+		}
+		drop()
+	}()
+}
+
+// waitCancelledQuery waits for the cancel of the previous query to be sent and the packets of the query to be drained
+// if it was cancelled, or its connection to be closed
+func (g *GatewayConn) waitCancelledQuery() {
+	qc := g.queryCancel
+	if qc == nil {
+		return
+	}
+	select {
+	case <-qc.cancelled:
+	default:
+		return
+	}
+	<-qc.sent
+	select {
+	case <-qc.finished:
+	case <-qc.dropped:
+	}
+}
+
+// dropConn closes c, of which the packets cannot be read anymore, to be reconnected by the next query
+func (g *GatewayConn) dropConn(c *connect) {
+	if c == nil {
+		return
+	}
+	g.dropMu.Lock()
+	defer g.dropMu.Unlock()
+	if g.conn == c {
+		g.connected = false
+	}
+	_ = c.Close()
+}
@@ -0,0 +1,125 @@
+package conn
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/protocol"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+// newPipeGatewayConn returns a connection in query of which server is the other end
+func newPipeGatewayConn(drainTimeout time.Duration) (g *GatewayConn, server net.Conn) {
+	client, server := net.Pipe()
+	var r io.Reader = client
+	c := &connect{
+		Conn:           client,
+		receiveTimeout: time.Minute,
+		sendTimeout:    time.Minute,
+		zReader:        bytepool.NewZReaderDefault(&r),
+		bWriter:        bufio.NewWriter(client),
+	}
+	return &GatewayConn{
+		conn:               c,
+		encoder:            ch_encoding.NewEncoder(c),
+		decoder:            ch_encoding.NewDecoder(c),
+		connected:          true,
+		inQuery:            true,
+		queryCancel:        newQueryCancel(),
+		cancelDrainTimeout: drainTimeout,
+		serverInfo:         &data.ServerInfo{},
+	}, server
+}
+
+func writePackets(t *testing.T, w io.Writer, packets ...byte) {
+	_, err := w.Write(packets)
+	require.NoError(t, err)
+}
+
+func readPacket(t *testing.T, r io.Reader) byte {
+	b := make([]byte, 1)
+	_, err := io.ReadFull(r, b)
+	require.NoError(t, err)
+	return b[0]
+}
+
+func TestGatewayConn_Cancel(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		writePackets(t, server, protocol.ServerPong)
+		require.Equal(t, byte(protocol.ClientCancel), readPacket(t, server))
+		// packets sent before the cancel is handled are drained
+		writePackets(t, server, protocol.ServerPong, protocol.ServerPong, protocol.ServerEndOfStream)
+
+		require.Equal(t, byte(protocol.ClientPing), readPacket(t, server))
+		writePackets(t, server, protocol.ServerPong)
+	}()
+
+	responses := g.GetResponseStream(context.Background())
+	require.IsType(t, &response.PongPacket{}, <-responses)
+	g.Cancel()
+	g.Cancel()
+	var drained []response.Packet
+	for resp := range responses {
+		drained = append(drained, resp)
+	}
+	require.Len(t, drained, 1)
+	require.IsType(t, &response.EndOfStreamPacket{}, drained[0])
+
+	g.waitCancelledQuery()
+	require.False(t, g.InQueryingState())
+	require.True(t, g.connected)
+	// the connection is reused
+	require.NoError(t, g.Ping())
+	<-serverDone
+}
+
+func TestGatewayConn_CancelContextDone(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	go func() {
+		writePackets(t, server, protocol.ServerPong)
+		require.Equal(t, byte(protocol.ClientCancel), readPacket(t, server))
+		writePackets(t, server, protocol.ServerEndOfStream)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	responses := g.GetResponseStream(ctx)
+	for range responses {
+	}
+	g.waitCancelledQuery()
+	require.True(t, g.connected)
+}
+
+func TestGatewayConn_CancelDrainTimeout(t *testing.T) {
+	g, server := newPipeGatewayConn(50 * time.Millisecond)
+	go func() {
+		writePackets(t, server, protocol.ServerPong)
+		// the query is never ended
+		_, _ = io.Copy(io.Discard, server)
+	}()
+
+	responses := g.GetResponseStream(context.Background())
+	require.IsType(t, &response.PongPacket{}, <-responses)
+	g.Cancel()
+	var last response.Packet
+	for resp := range responses {
+		last = resp
+	}
+	require.IsType(t, &response.ExceptionPacket{}, last)
+
+	g.waitCancelledQuery()
+	require.False(t, g.connected)
+	require.True(t, g.conn.closed)
+}
@@ -109,6 +109,23 @@ func OptionReceiveTimeout(d uint64) OptionConfig {
 	}
 }
 
+// OptionKeepaliveInterval pings connections which are idle for d seconds, to keep them open and find broken ones
+func OptionKeepaliveInterval(d uint64) OptionConfig {
+	return func(connConfigs *ConnConfig) error {
+		connConfigs.keepaliveIntervalSeconds = d
+		return nil
+	}
+}
+
+// OptionKeepaliveTimeout is the time in seconds given to the server to answer a keepalive ping,
+// DefaultKeepaliveTimeout if 0
+func OptionKeepaliveTimeout(d uint64) OptionConfig {
+	return func(connConfigs *ConnConfig) error {
+		connConfigs.keepaliveTimeoutSeconds = d
+		return nil
+	}
+}
+
 func OptionLogf(logf logf) OptionConfig {
 	return func(connConfigs *ConnConfig) error {
 		connConfigs.logf = logf
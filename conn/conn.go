@@ -3,9 +3,8 @@ package conn
 import (
 	"context"
 	"fmt"
-	"log"
-	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,6 +26,18 @@ type GatewayConn struct {
 	compress  bool
 	connected bool
 	inQuery   bool
+	// queryCancel is the cancellation of the query in progress
+	queryCancel        *queryCancel
+	cancelDrainTimeout time.Duration
+	// dropMu serializes closing the connection by the response stream and the cancel of a query
+	dropMu sync.Mutex
+	// useMu guards uses, lastUsed, broken and keepaliveStop, and is held by the keepalive while it pings
+	useMu sync.Mutex
+	// uses is the number of uses of the connection in progress, which is idle if 0
+	uses          int
+	lastUsed      time.Time
+	broken        bool
+	keepaliveStop chan struct{}
 
 	database       string
 	userInfo       *UserInfo
@@ -57,7 +68,12 @@ func NewGatewayConn(
 	}
 
 	g.clone = func() *GatewayConn {
-		return NewGatewayConn(g.connConfigs, g.database, g.authentication, g.compress, g.settings)
+		// the settings are copied, since those of each connection are changed temporarily by its queries
+		settings := make(map[string]interface{}, len(g.settings))
+		for k, v := range g.settings {
+			settings[k] = v
+		}
+		return NewGatewayConn(g.connConfigs, g.database, g.authentication, g.compress, settings)
 	}
 
 	return g
@@ -96,6 +112,7 @@ func (g *GatewayConn) connect() error {
 		return err
 	}
 	g.connected = true
+	g.startKeepalive(newConn)
 	return nil
 }
 
@@ -167,6 +184,8 @@ func (g *GatewayConn) receiveHello() error {
 }
 
 func (g *GatewayConn) CheckConnection() (err error) {
+	g.beginUse()
+	defer g.endUse()
 	defer func() {
 		if err != nil {
 			g.connected = false
@@ -201,22 +220,45 @@ func (g *GatewayConn) SendQuery(query string) error {
 }
 
 func (g *GatewayConn) SendQueryFull(query, queryID string, extTables <-chan *data.Block, extTableName string) error {
-	err := g.forceConnect()
-	if err != nil {
+	if extTables == nil {
+		return g.SendQueryWithExternalTables(query, queryID)
+	}
+	return g.SendQueryWithExternalTables(query, queryID, &ExternalTableStream{Name: extTableName, Blocks: extTables})
+}
+
+// ExternalTableStream is the stream of blocks of an external table sent with a query
+type ExternalTableStream struct {
+	// Name is the name of the table in the query
+	Name string
+	// Header is the block of the columns of the table without rows, sent after Blocks to end the data of the table,
+	// which also creates the table if Blocks is empty. It is not sent if nil.
+	Header *data.Block
+	Blocks <-chan *data.Block
+}
+
+// SendQueryWithExternalTables sends query with the blocks of extTables, one table after another,
+// followed by the empty block which ends the data of the query
+func (g *GatewayConn) SendQueryWithExternalTables(query, queryID string, extTables ...*ExternalTableStream) (err error) {
+	g.beginUse()
+	defer func() {
+		// the use ends with the response stream of the query if sent
+		if err != nil {
+			g.endUse()
+		}
+	}()
+	g.waitCancelledQuery()
+	if err = g.forceConnect(); err != nil {
 		return err
 	}
+	g.queryCancel = newQueryCancel()
 	if err = g.writeUvarint(protocol.ClientQuery); err != nil {
 		return err
 	}
 	if err = g.sendQueryInfo(query, queryID); err != nil {
 		return err
 	}
-	if extTables != nil {
-		for t := range extTables {
-			if err = g.sendClientDataWithTableName(t, extTableName); err != nil {
-				return err
-			}
-		}
+	if err = g.sendExternalTables(extTables); err != nil {
+		return err
 	}
 	if err = g.SendClientData(&data.Block{}); err != nil {
 		return err
@@ -229,6 +271,23 @@ func (g *GatewayConn) SendQueryFull(query, queryID string, extTables <-chan *dat
 	return nil
 }
 
+func (g *GatewayConn) sendExternalTables(extTables []*ExternalTableStream) error {
+	for _, t := range extTables {
+		for b := range t.Blocks {
+			if err := g.sendClientDataWithTableName(b, t.Name); err != nil {
+				return err
+			}
+		}
+		if t.Header == nil {
+			continue
+		}
+		if err := g.sendClientDataWithTableName(t.Header, t.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (g *GatewayConn) sendQueryInfo(query, queryID string) error {
 	if queryID == "" {
 		newUUID, err := uuid.NewRandom()
@@ -310,26 +369,6 @@ func (g *GatewayConn) sendClientDataWithTableName(block *data.Block, tableName s
 	return g.flush()
 }
 
-// Cancel cancels the current query and disconnects.
-// Non-blocking process
-func (g *GatewayConn) Cancel() {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
-					r,
-					string(debug.Stack()))
-			}
-		}()
-		_ = g.writeUvarint(protocol.ClientCancel)
-		_ = g.flush()
-		_ = g.conn.Close()
-	}()
-
-	g.inQuery = false
-	g.connected = false
-}
-
 func (g *GatewayConn) sendBlock(block *data.Block) error {
 	g.encoder.SelectCompress(g.compress)
 	if err := data.WriteBlockToEncoder(g.encoder, block); err != nil {
@@ -343,6 +382,7 @@ func (g *GatewayConn) Close() error {
 	if g.conn == nil {
 		return nil
 	}
+	g.stopKeepalive()
 	return g.conn.Close()
 }
 
@@ -513,6 +553,11 @@ func (g *GatewayConn) GetDisplayName() string {
 	return g.serverInfo.Name
 }
 
+// Compress returns true if data blocks are sent compressed
+func (g *GatewayConn) Compress() bool {
+	return g.compress
+}
+
 func (g *GatewayConn) InAnsiSQLMode() bool {
 	if g == nil {
 		return false
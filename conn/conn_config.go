@@ -39,4 +39,6 @@ type ConnConfig struct {
 	connTimeoutSeconds, receiveTimeoutSeconds, sendTimeoutSeconds uint64 //in seconds
 	dialStrategy                                                  DialStrategy
 	logf                                                          func(string, ...interface{})
+	// keepaliveIntervalSeconds is the idle time after which connections are pinged, disabled if 0
+	keepaliveIntervalSeconds, keepaliveTimeoutSeconds uint64
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool/mocks"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
 	"github.com/bytehouse-cloud/driver-go/driver/protocol"
 	"github.com/bytehouse-cloud/driver-go/utils"
 )
@@ -501,3 +502,46 @@ func TestGatewayConn_SettingsChecked(t *testing.T) {
 		t.Run(tt.name, tt.test)
 	}
 }
+
+func TestGatewayConn_SendExternalTables(t *testing.T) {
+	var buffer bytes.Buffer
+	g := &GatewayConn{
+		encoder: ch_encoding.NewEncoder(&buffer),
+		decoder: ch_encoding.NewDecoder(&buffer),
+	}
+
+	newBlockStream := func(values ...interface{}) <-chan *data.Block {
+		b, err := data.NewBlock([]string{"a"}, []column.CHColumnType{column.UINT32}, len(values))
+		require.NoError(t, err)
+		_, err = b.Columns[0].Data.ReadFromValues(values)
+		require.NoError(t, err)
+		blocks := make(chan *data.Block, 1)
+		blocks <- b
+		close(blocks)
+		return blocks
+	}
+	header, err := data.NewBlock([]string{"a"}, []column.CHColumnType{column.UINT32}, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, g.sendExternalTables([]*ExternalTableStream{
+		{Name: "t1", Header: header, Blocks: newBlockStream(uint32(1), uint32(2))},
+		{Name: "t2", Blocks: newBlockStream(uint32(3))},
+	}))
+
+	type sentBlock struct {
+		name    string
+		numRows int
+	}
+	var sent []sentBlock
+	for buffer.Len() > 0 {
+		packet, err := g.readUvariant()
+		require.NoError(t, err)
+		require.Equal(t, uint64(protocol.ClientData), packet)
+		name, err := g.decoder.String()
+		require.NoError(t, err)
+		b, err := data.ReadBlockFromDecoder(g.decoder)
+		require.NoError(t, err)
+		sent = append(sent, sentBlock{name: name, numRows: b.NumRows})
+	}
+	require.Equal(t, []sentBlock{{"t1", 2}, {"t1", 0}, {"t2", 1}}, sent)
+}
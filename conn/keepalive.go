@@ -0,0 +1,141 @@
+package conn
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+)
+
+// DefaultKeepaliveTimeout is the time given to the server to answer a keepalive ping,
+// after which the connection is closed as broken
+const DefaultKeepaliveTimeout = 10 * time.Second
+
+// beginUse marks the connection in use by a query or ping, waiting for the keepalive ping in progress if any.
+// Uses may be nested, such as the queries setting the conn configs sent before a query on a new connection.
+func (g *GatewayConn) beginUse() {
+	g.useMu.Lock()
+	defer g.useMu.Unlock()
+	g.uses++
+}
+
+// endUse ends a use of the connection, which is idle from now if it was the last one
+func (g *GatewayConn) endUse() {
+	g.useMu.Lock()
+	defer g.useMu.Unlock()
+	if g.uses > 0 {
+		g.uses--
+	}
+	g.lastUsed = time.Now()
+}
+
+// Broken tells if the connection was closed after failing a keepalive ping, so that it should be discarded
+func (g *GatewayConn) Broken() bool {
+	g.useMu.Lock()
+	defer g.useMu.Unlock()
+	return g.broken
+}
+
+// startKeepalive starts pinging c, the new connection of g, when it is idle for the keepalive interval if set.
+// Pings are used rather than ClientKeepAlive, since the pong of the server both tells that the connection works
+// and extends the receive timeout of the connection.
+func (g *GatewayConn) startKeepalive(c *connect) {
+	g.useMu.Lock()
+	defer g.useMu.Unlock()
+	g.broken = false
+	g.lastUsed = time.Now()
+	if g.keepaliveStop != nil {
+		close(g.keepaliveStop)
+		g.keepaliveStop = nil
+	}
+
+	if g.connConfigs == nil || g.connConfigs.keepaliveIntervalSeconds == 0 {
+		return
+	}
+	interval := time.Duration(g.connConfigs.keepaliveIntervalSeconds) * time.Second
+	timeout := time.Duration(g.connConfigs.keepaliveTimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = DefaultKeepaliveTimeout
+	}
+	stop := make(chan struct{})
+	g.keepaliveStop = stop
+	go g.keepalive(c, interval, timeout, stop)
+}
+
+// stopKeepalive stops the keepalive of the connection, waiting for the ping in progress if any
+func (g *GatewayConn) stopKeepalive() {
+	g.useMu.Lock()
+	defer g.useMu.Unlock()
+	if g.keepaliveStop != nil {
+		close(g.keepaliveStop)
+		g.keepaliveStop = nil
+	}
+}
+
+func (g *GatewayConn) keepalive(c *connect, interval, timeout time.Duration, stop <-chan struct{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		next, ok := g.keepaliveOnce(c, interval, timeout)
+		if !ok {
+			return
+		}
+		timer.Reset(next)
+	}
+}
+
+// keepaliveOnce pings c if it is idle for interval, and returns the time until it should be pinged next,
+// or false if the keepalive of c is over since it is not the connection of g anymore or failed the ping
+func (g *GatewayConn) keepaliveOnce(c *connect, interval, timeout time.Duration) (time.Duration, bool) {
+	g.useMu.Lock()
+	defer g.useMu.Unlock()
+
+	if g.uses > 0 {
+		return interval, true
+	}
+	// the connection is only replaced while in use
+	g.dropMu.Lock()
+	alive := g.conn == c && g.connected
+	g.dropMu.Unlock()
+	if !alive {
+		return 0, false
+	}
+	if idle := time.Since(g.lastUsed); idle < interval {
+		return interval - idle, true
+	}
+
+	if err := g.pingWithTimeout(c, timeout); err != nil {
+		if g.logf != nil {
+			g.logf("keepalive ping failed, closing connection: %v", err)
+		}
+		g.broken = true
+		g.dropConn(c)
+		return 0, false
+	}
+	g.lastUsed = time.Now()
+	return interval, true
+}
+
+// pingWithTimeout pings c, which is closed if the server does not answer within timeout
+func (g *GatewayConn) pingWithTimeout(c *connect, timeout time.Duration) error {
+	timer := time.AfterFunc(timeout, func() {
+		g.dropConn(c)
+	})
+	err := g.Ping()
+	if !timer.Stop() && err == nil {
+		err = NewErrBadConnection("keepalive ping timed out")
+	}
+	return err
+}
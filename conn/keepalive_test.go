@@ -0,0 +1,81 @@
+package conn
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/protocol"
+)
+
+func TestGatewayConn_KeepaliveOnce(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	g.inQuery = false
+	c := g.conn
+
+	g.lastUsed = time.Now()
+	next, ok := g.keepaliveOnce(c, time.Hour, time.Minute)
+	require.True(t, ok)
+	require.True(t, next > 59*time.Minute && next <= time.Hour, "not idle long enough, got %v", next)
+
+	g.beginUse()
+	g.lastUsed = time.Time{}
+	next, ok = g.keepaliveOnce(c, time.Hour, time.Minute)
+	require.True(t, ok)
+	require.Equal(t, time.Hour, next, "in use")
+	g.endUse()
+	g.lastUsed = time.Time{}
+
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		require.Equal(t, byte(protocol.ClientPing), readPacket(t, server))
+		writePackets(t, server, protocol.ServerPong)
+	}()
+	next, ok = g.keepaliveOnce(c, time.Hour, time.Minute)
+	<-serverDone
+	require.True(t, ok)
+	require.Equal(t, time.Hour, next)
+	require.WithinDuration(t, time.Now(), g.lastUsed, time.Minute)
+	require.False(t, g.Broken())
+	require.True(t, g.connected)
+}
+
+func TestGatewayConn_KeepaliveTimeout(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	g.inQuery = false
+	defer server.Close()
+	go func() {
+		// the ping is never answered
+		readPacket(t, server)
+	}()
+
+	_, ok := g.keepaliveOnce(g.conn, time.Millisecond, 50*time.Millisecond)
+	require.False(t, ok)
+	require.True(t, g.Broken())
+	require.False(t, g.connected)
+	require.True(t, g.conn.closed)
+}
+
+func TestGatewayConn_Keepalive(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	g.inQuery = false
+	g.connConfigs = &ConnConfig{keepaliveIntervalSeconds: 1}
+
+	pinged := make(chan struct{})
+	go func() {
+		require.Equal(t, byte(protocol.ClientPing), readPacket(t, server))
+		writePackets(t, server, protocol.ServerPong)
+		close(pinged)
+	}()
+	g.startKeepalive(g.conn)
+
+	select {
+	case <-pinged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle connection not pinged")
+	}
+	require.NoError(t, g.Close())
+	require.Nil(t, g.keepaliveStop)
+}
@@ -10,8 +10,30 @@ import (
 )
 
 // GetResponseStream Read Response(s) until EOS or Exception
+// If ctx is done, the query is cancelled. Once cancelled, the remaining responses are drained
+// and only the last one is sent if the stream has room for it.
 func (g *GatewayConn) GetResponseStream(ctx context.Context) <-chan response.Packet {
 	responseChannel := make(chan response.Packet, 10)
+	qc := g.queryCancel
+	if qc == nil {
+		qc = newQueryCancel()
+		g.queryCancel = qc
+	}
+	c, decoder := g.conn, g.decoder
+
+	// sendLast sends the last response, without blocking if the query is cancelled or ctx is done
+	sendLast := func(resp response.Packet) {
+		select {
+		case responseChannel <- resp:
+			return
+		case <-qc.cancelled:
+		case <-ctx.Done():
+		}
+		select {
+		case responseChannel <- resp:
+		default:
+		}
+	}
 
 	go func() {
 		defer func() {
@@ -24,27 +46,46 @@ func (g *GatewayConn) GetResponseStream(ctx context.Context) <-chan response.Pac
 		defer close(responseChannel)
 		defer func() {
 			g.inQuery = false
+			g.endUse()
 		}()
+		defer qc.finish()
 
 		for {
-			select {
-			case <-ctx.Done():
-				return
-			default:
+			if ctx.Err() != nil {
+				g.cancelQuery(qc)
 			}
 
-			resp, err := response.ReadPacketWithLocation(g.decoder, g.compress, data.ClickHouseRevision, g.serverInfo.Timezone)
+			resp, err := response.ReadPacketWithLocation(decoder, g.compress, data.ClickHouseRevision, g.serverInfo.Timezone)
 			if err != nil {
-				responseChannel <- &response.ExceptionPacket{
-					Message: err.Error(),
+				select {
+				case <-qc.cancelled:
+					// the cancelled query cannot be drained
+					g.dropConn(c)
+				default:
 				}
+				sendLast(&response.ExceptionPacket{
+					Message: err.Error(),
+				})
 				return
 			}
-			responseChannel <- resp
 			switch resp.(type) {
 			case *response.ExceptionPacket, *response.EndOfStreamPacket:
+				qc.finish()
+				sendLast(resp)
 				return
 			}
+
+			select {
+			case <-qc.cancelled:
+				continue
+			default:
+			}
+			select {
+			case responseChannel <- resp:
+			case <-qc.cancelled:
+			case <-ctx.Done():
+				g.cancelQuery(qc)
+			}
 		}
 	}()
 
@@ -0,0 +1,79 @@
+package conn
+
+import (
+	"context"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/protocol"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+// TablesStatus requests the status of tables from the server, which only answers with the tables which exist.
+// Tables without database are looked up in the current database of the connection.
+// The connection is closed if ctx is done before the server answers.
+func (g *GatewayConn) TablesStatus(ctx context.Context, tables []response.TableName) (*response.TablesStatusPacket, error) {
+	g.beginUse()
+	defer g.endUse()
+	g.waitCancelledQuery()
+	if err := g.forceConnect(); err != nil {
+		return nil, err
+	}
+	if g.serverInfo.Revision < protocol.DBMS_MIN_REVISION_WITH_TABLES_STATUS {
+		return nil, errors.ErrorfWithCaller("tables status is not supported by server revision %v", g.serverInfo.Revision)
+	}
+
+	c := g.conn
+	if done := ctx.Done(); done != nil {
+		answered := make(chan struct{})
+		defer close(answered)
+		go func() {
+			select {
+			case <-done:
+				g.dropConn(c)
+			case <-answered:
+			}
+		}()
+	}
+
+	resp, err := g.requestTablesStatus(tables)
+	if err != nil {
+		// the answer of the server cannot be read anymore
+		g.dropConn(c)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	switch resp := resp.(type) {
+	case *response.TablesStatusPacket:
+		return resp, nil
+	case *response.ExceptionPacket:
+		return nil, resp
+	default:
+		g.dropConn(c)
+		return nil, errors.ErrorfWithCaller("unexpected answer to tables status request: %v", resp)
+	}
+}
+
+func (g *GatewayConn) requestTablesStatus(tables []response.TableName) (response.Packet, error) {
+	if err := g.writeUvarint(protocol.ClientTablesStatusRequest); err != nil {
+		return nil, err
+	}
+	if err := g.writeUvarint(uint64(len(tables))); err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		if err := g.writeString(t.Database); err != nil {
+			return nil, err
+		}
+		if err := g.writeString(t.Table); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.flush(); err != nil {
+		return nil, err
+	}
+	return response.ReadPacketWithLocation(g.decoder, g.compress, data.ClickHouseRevision, g.serverInfo.Timezone)
+}
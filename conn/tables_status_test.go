@@ -0,0 +1,84 @@
+package conn
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/protocol"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+func TestGatewayConn_TablesStatus(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	g.inQuery = false
+	g.serverInfo.Revision = protocol.VERSION_REVISION
+
+	tables := []response.TableName{{Database: "db", Table: "local"}, {Table: "replicated"}}
+	answer := &response.TablesStatusPacket{Statuses: []*response.TableStatus{
+		{TableName: tables[1], IsReplicated: true, AbsoluteDelay: 5},
+	}}
+	go func() {
+		var r io.Reader = server
+		decoder := ch_encoding.NewDecoder(bytepool.NewZReaderDefault(&r))
+		packet, err := decoder.Uvarint()
+		require.NoError(t, err)
+		require.Equal(t, uint64(protocol.ClientTablesStatusRequest), packet)
+		n, err := decoder.Uvarint()
+		require.NoError(t, err)
+		var requested []response.TableName
+		for i := uint64(0); i < n; i++ {
+			var table response.TableName
+			table.Database, err = decoder.String()
+			require.NoError(t, err)
+			table.Table, err = decoder.String()
+			require.NoError(t, err)
+			requested = append(requested, table)
+		}
+		require.Equal(t, tables, requested)
+
+		w := bufio.NewWriter(server)
+		encoder := ch_encoding.NewEncoder(w)
+		require.NoError(t, response.WritePacket(answer, encoder, false, 0))
+		require.NoError(t, encoder.Flush())
+		require.NoError(t, w.Flush())
+	}()
+
+	p, err := g.TablesStatus(context.Background(), tables)
+	require.NoError(t, err)
+	require.Equal(t, answer, p)
+	require.True(t, g.connected)
+}
+
+func TestGatewayConn_TablesStatusContextDone(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	defer server.Close()
+	g.inQuery = false
+	g.serverInfo.Revision = protocol.VERSION_REVISION
+	go func() {
+		// the request is never answered
+		_, _ = io.Copy(io.Discard, server)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := g.TablesStatus(ctx, []response.TableName{{Database: "db", Table: "t"}})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, g.connected)
+}
+
+func TestGatewayConn_TablesStatusNotSupported(t *testing.T) {
+	g, server := newPipeGatewayConn(time.Minute)
+	defer server.Close()
+	g.inQuery = false
+	g.serverInfo.Revision = protocol.DBMS_MIN_REVISION_WITH_TABLES_STATUS - 1
+
+	_, err := g.TablesStatus(context.Background(), nil)
+	require.Error(t, err)
+}
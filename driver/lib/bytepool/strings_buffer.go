@@ -99,6 +99,19 @@ func (s *StringsBuffer) Len() int {
 	return len(s.offsets)
 }
 
+// Size returns the total number of bytes written to the buffer
+func (s *StringsBuffer) Size() int {
+	return s.buffer.Len()
+}
+
+// ElemSize returns the number of bytes of the ith string
+func (s *StringsBuffer) ElemSize(i int) int {
+	if i+1 < len(s.offsets) {
+		return s.offsets[i+1] - s.offsets[i]
+	}
+	return s.buffer.Len() - s.offsets[i]
+}
+
 func (s *StringsBuffer) Close() {
 	putStringsBufferToPool(s)
 }
@@ -1,6 +1,7 @@
 package bytepool
 
 import (
+	"bytes"
 	"encoding/binary"
 	"io"
 
@@ -12,6 +13,8 @@ const (
 	defaultChannelSize = 8
 )
 
+var newLine = []byte{'\n'}
+
 type ZReader struct {
 	r          *io.Reader
 	buffer     []byte
@@ -19,6 +22,20 @@ type ZReader struct {
 	forReceive chan []byte
 	forRead    chan []byte
 	exception  error
+
+	// stale is the start of the data in buffer, bytes before it are left over from shifting data
+	stale int
+	// base and baseLines are the number of bytes and newlines read before buffer[stale]
+	base      int64
+	baseLines int
+	// staleNewLine is true if the byte read right before buffer[stale] is a newline
+	staleNewLine bool
+
+	// recording is true once Record is called, recorded holds the bytes read from position recordStart,
+	// copied from buffer before they are replaced
+	recording   bool
+	recordStart int64
+	recorded    []byte
 }
 
 func NewZReaderDefault(r *io.Reader) *ZReader {
@@ -105,9 +122,12 @@ func (z *ZReader) fillBufferIfEmpty() error {
 	if !ok {
 		return z.exception
 	}
+	z.keepRecorded()
+	z.rebase(z.Position(), z.linesBefore(), z.lastByteIsNewLine())
 	z.forReceive <- z.buffer[:cap(z.buffer)]
 	z.buffer = buf
 	z.offset = 0
+	z.stale = 0
 	return nil
 }
 
@@ -139,37 +159,136 @@ func (z *ZReader) UnreadCurrentBuffer(n int) {
 	z.offset -= n
 }
 
+// Position returns the number of bytes consumed from the underlying reader
+func (z *ZReader) Position() int64 {
+	return z.base + int64(z.offset-z.stale)
+}
+
+// Line returns the 1-based line number of the last byte read, a newline belongs to the line it ends
+func (z *ZReader) Line() int {
+	lines := z.linesBefore()
+	if z.lastByteIsNewLine() {
+		return lines
+	}
+	return lines + 1
+}
+
+// AtLineStart returns true if nothing or a newline was the last byte read
+func (z *ZReader) AtLineStart() bool {
+	return z.Position() == 0 || z.lastByteIsNewLine()
+}
+
+func (z *ZReader) lastByteIsNewLine() bool {
+	if z.offset > z.stale {
+		return z.buffer[z.offset-1] == '\n'
+	}
+	return z.staleNewLine
+}
+
+// linesBefore returns the number of newlines before the current position
+func (z *ZReader) linesBefore() int {
+	return z.baseLines + bytes.Count(z.buffer[z.stale:z.offset], newLine)
+}
+
+// rebase makes the current offset the start of the data in buffer,
+// given the position, lines and last byte read at the offset
+func (z *ZReader) rebase(position int64, lines int, lastNewLine bool) {
+	z.stale = z.offset
+	z.base = position
+	z.baseLines = lines
+	z.staleNewLine = lastNewLine
+}
+
+// PrependCurrentBuffer puts pre back in front of the unread data,
+// pre is expected to be the bytes last read from z.
 func (z *ZReader) PrependCurrentBuffer(pre []byte) {
 	// If have enough space behind offset, copy over pre to before offset
 	if len(pre) <= z.offset {
-		z.offset -= copy(z.buffer[z.offset-len(pre):z.offset], pre)
+		z.prependInBuffer(pre)
 		return
 	}
 
 	// Else flush original data all the way to right
 	z.shiftDataInBufferRight()
 	if len(pre) <= z.offset {
-		z.offset -= copy(z.buffer[z.offset-len(pre):z.offset], pre)
+		z.prependInBuffer(pre)
 		return
 	}
 
 	// If still not enough space make a new buffer
+	z.keepRecorded()
+	position, lines := z.Position(), z.linesBefore()
 	newBuffer := make([]byte, len(pre)+z.bufferBalance())
 	copy(newBuffer, pre)
 	copy(newBuffer[len(pre):], z.buffer[z.offset:])
 	z.buffer = newBuffer
 	z.offset = 0
+	// byte before pre is no longer known, Line may be off by one until next newline
+	z.rebase(position-int64(len(pre)), lines-bytes.Count(pre, newLine), false)
+}
+
+// prependInBuffer copies pre to before offset, which must have enough space
+func (z *ZReader) prependInBuffer(pre []byte) {
+	position, lines := z.Position(), z.linesBefore()
+	z.offset -= copy(z.buffer[z.offset-len(pre):z.offset], pre)
+	if z.offset < z.stale { // pre is copied over stale bytes
+		// byte before pre is no longer known, Line may be off by one until next newline
+		z.rebase(position-int64(len(pre)), lines-bytes.Count(pre, newLine), false)
+	}
 }
 
 // shiftDataInBufferRight shifts data in the buffer to the rightmost if buffer has additional capacity
-// It also updates the offset accordingly
+// It also updates the offset accordingly, bytes before the new offset become stale
 func (z *ZReader) shiftDataInBufferRight() {
+	z.keepRecorded()
+	position, lines, lastNewLine := z.Position(), z.linesBefore(), z.lastByteIsNewLine()
+
 	b := z.bufferBalance()
 	l := len(z.buffer)
 	z.buffer = z.buffer[:cap(z.buffer)]
 	newOffset := len(z.buffer) - b
 	copy(z.buffer[newOffset:], z.buffer[z.offset:l])
 	z.offset = newOffset
+	z.rebase(position, lines, lastNewLine)
+}
+
+// Record starts recording the bytes read from the current position, replacing those recorded before
+func (z *ZReader) Record() {
+	z.recording = true
+	z.recordStart = z.Position()
+	z.recorded = z.recorded[:0]
+}
+
+// Recorded returns the bytes read since Record was called, valid until the next call to Record
+func (z *ZReader) Recorded() []byte {
+	if !z.recording {
+		return nil
+	}
+	z.keepRecorded()
+	return z.recorded
+}
+
+// keepRecorded copies the bytes read into recorded until the current position,
+// called before the bytes read from buffer are replaced
+func (z *ZReader) keepRecorded() {
+	if !z.recording {
+		return
+	}
+	position := z.Position()
+	if position <= z.recordStart {
+		z.recorded = z.recorded[:0]
+		return
+	}
+	recordEnd := z.recordStart + int64(len(z.recorded))
+	if position <= recordEnd { // bytes were unread since
+		z.recorded = z.recorded[:position-z.recordStart]
+		return
+	}
+	from := z.stale + int(recordEnd-z.base)
+	if from < z.stale { // bytes before the buffer are lost
+		from = z.stale
+	}
+	z.recorded = append(z.recorded, z.buffer[from:z.offset]...)
 }
 
 func (z *ZReader) Close() error {
@@ -147,3 +147,84 @@ func TestZReader_PrependCurrentBuffer(t *testing.T) {
 		})
 	}
 }
+
+func TestZReader_PositionAndLine(t *testing.T) {
+	input := []byte("ab\ncd\nef\ngh")
+	z := NewZReader(pointer.IoReader(bytes.NewBuffer(input)), 4, 2)
+	require.Equal(t, int64(0), z.Position())
+	require.Equal(t, 1, z.Line())
+
+	b := make([]byte, 5)
+	require.NoError(t, z.ReadFull(b))
+	require.Equal(t, int64(5), z.Position())
+	require.Equal(t, 2, z.Line())
+
+	// put back "d"
+	z.PrependCurrentBuffer(b[4:])
+	require.Equal(t, int64(4), z.Position())
+	require.Equal(t, 2, z.Line())
+
+	rest := make([]byte, len(input)-4)
+	require.NoError(t, z.ReadFull(rest))
+	require.Equal(t, input[4:], rest)
+	require.Equal(t, int64(len(input)), z.Position())
+	require.Equal(t, 4, z.Line())
+}
+
+func TestZReader_PositionAndLine_Shift(t *testing.T) {
+	input := []byte("ab\ncd\nef\n")
+	z := NewZReader(pointer.IoReader(bytes.NewBuffer(input)), 100, 1)
+
+	b := make([]byte, 4)
+	require.NoError(t, z.ReadFull(b))
+	z.shiftDataInBufferRight()
+	require.Equal(t, int64(4), z.Position())
+	require.Equal(t, 2, z.Line())
+	require.False(t, z.AtLineStart())
+	// bytes before the data are only stale, not rewritten
+	require.Equal(t, input, z.buffer[:len(input)])
+
+	d, err := z.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('d'), d)
+
+	// put back "cd", "c" is copied over the stale bytes
+	z.PrependCurrentBuffer([]byte("cd"))
+	require.Equal(t, int64(3), z.Position())
+
+	rest := make([]byte, len(input)-3)
+	require.NoError(t, z.ReadFull(rest))
+	require.Equal(t, input[3:], rest)
+	require.Equal(t, int64(len(input)), z.Position())
+	require.Equal(t, 3, z.Line())
+	require.True(t, z.AtLineStart())
+}
+
+func TestZReader_Record(t *testing.T) {
+	input := []byte("ab\ncdefghij\nk")
+	// buffers of 4 bytes, so that the record spans buffers
+	z := NewZReader(pointer.IoReader(bytes.NewBuffer(input)), 4, 2)
+	require.Nil(t, z.Recorded())
+
+	b := make([]byte, 3)
+	require.NoError(t, z.ReadFull(b))
+	z.Record()
+	require.Empty(t, z.Recorded())
+
+	b = make([]byte, 9)
+	require.NoError(t, z.ReadFull(b))
+	require.Equal(t, "cdefghij\n", string(z.Recorded()))
+
+	// unread bytes are not recorded
+	z.UnreadCurrentBuffer(1)
+	require.Equal(t, "cdefghij", string(z.Recorded()))
+	c, err := z.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('\n'), c)
+
+	z.Record()
+	c, err = z.ReadByte()
+	require.NoError(t, err)
+	require.Equal(t, byte('k'), c)
+	require.Equal(t, "k", string(z.Recorded()))
+}
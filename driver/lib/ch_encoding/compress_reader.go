@@ -7,6 +7,7 @@ import (
 	"github.com/dennwc/varint"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/cityhash102"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/lz4"
 	"github.com/bytehouse-cloud/driver-go/errors"
 )
@@ -38,6 +39,35 @@ func NewCompressReader(r io.Reader) *compressReader {
 	return p
 }
 
+// CompressedBlockLen returns the length of the compressed block which starts with header, the first HeaderSize bytes
+// of the block, and whether header is valid for a block compressed with LZ4 or ZSTD
+func CompressedBlockLen(header []byte) (int, bool) {
+	if len(header) < HeaderSize {
+		return 0, false
+	}
+	switch header[16] {
+	case LZ4, ZSTD:
+	default:
+		return 0, false
+	}
+	compressedSize := int(binary.LittleEndian.Uint32(header[17:]))
+	if compressedSize < CompressHeaderSize {
+		return 0, false
+	}
+	return ChecksumSize + compressedSize, true
+}
+
+// IsCompressedBlock reports whether b is a whole compressed block with a valid checksum
+func IsCompressedBlock(b []byte) bool {
+	n, ok := CompressedBlockLen(b)
+	if !ok || n != len(b) {
+		return false
+	}
+	checkSum := cityhash102.CityHash128(b[ChecksumSize:], uint32(n-ChecksumSize))
+	return binary.LittleEndian.Uint64(b) == checkSum.Lower64() &&
+		binary.LittleEndian.Uint64(b[8:]) == checkSum.Higher64()
+}
+
 func (cr *compressReader) ReadUvarint() (uint64, error) {
 	if len(cr.data)-cr.pos < varint.MaxLen64 {
 		return binary.ReadUvarint(cr)
@@ -85,13 +115,13 @@ func (cr *compressReader) ReadByte() (byte, error) {
 func (cr *compressReader) readCompressedData() (err error) {
 	cr.pos = 0
 	var n int
-	n, err = cr.reader.Read(cr.header)
+	n, err = io.ReadFull(cr.reader, cr.header)
+	if err == io.ErrUnexpectedEOF {
+		return errors.ErrorfWithCaller("decompression header EOF, read %v of %v bytes", n, len(cr.header))
+	}
 	if err != nil {
 		return
 	}
-	if n != len(cr.header) {
-		return errors.ErrorfWithCaller("lz4 decompression header EOF")
-	}
 
 	compressedSize := int(binary.LittleEndian.Uint32(cr.header[17:])) - 9
 	decompressedSize := int(binary.LittleEndian.Uint32(cr.header[21:]))
@@ -109,23 +139,23 @@ func (cr *compressReader) readCompressedData() (err error) {
 	cr.data = cr.data[:decompressedSize]
 
 	// @TODO checksum
-	if cr.header[16] == LZ4 {
-		n, err = cr.reader.Read(cr.zdata)
-		if err != nil {
-			return
-		}
-
-		if n != len(cr.zdata) {
-			return errors.ErrorfWithCaller("decompress read size does not match")
-		}
-
-		_, err = lz4.Decode(cr.data, cr.zdata)
-		if err != nil {
-			return
-		}
-	} else {
+	switch cr.header[16] {
+	case LZ4, ZSTD:
+	default:
 		return errors.ErrorfWithCaller("unknown compression method: 0x%02x ", cr.header[16])
 	}
+	if _, err = io.ReadFull(cr.reader, cr.zdata); err != nil {
+		return errors.ErrorfWithCaller("decompress read size does not match: %v", err)
+	}
 
-	return nil
+	if cr.header[16] == ZSTD {
+		initZstd()
+		cr.data, err = zstdDecoder.DecodeAll(cr.zdata, cr.data[:0])
+		if err == nil && len(cr.data) != decompressedSize {
+			err = errors.ErrorfWithCaller("decompressed size does not match, expected: %v, got: %v", decompressedSize, len(cr.data))
+		}
+		return err
+	}
+	_, err = lz4.Decode(cr.data, cr.zdata)
+	return err
 }
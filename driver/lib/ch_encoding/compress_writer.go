@@ -11,6 +11,7 @@ import (
 
 type compressWriter struct {
 	writer io.Writer
+	method CompressionMethodByte
 	// data uncompressed
 	data []byte
 	// data position
@@ -21,7 +22,15 @@ type compressWriter struct {
 
 // NewCompressWriter wrap the io.Writer
 func NewCompressWriter(w io.Writer) *compressWriter {
-	p := &compressWriter{writer: w}
+	return NewCompressWriterWithMethod(w, LZ4)
+}
+
+// NewCompressWriterWithMethod wraps the io.Writer to compress with method, which is either LZ4 or ZSTD
+func NewCompressWriterWithMethod(w io.Writer, method CompressionMethodByte) *compressWriter {
+	if method == ZSTD {
+		initZstd()
+	}
+	p := &compressWriter{writer: w, method: method}
 	//p.data = make([]byte, BlockMaxSize, BlockMaxSize)
 	p.data = bytepool.GetBytesWithLen(BlockMaxSize)
 
@@ -56,13 +65,16 @@ func (cw *compressWriter) Flush() (err error) {
 	}
 
 	// write the headers
-	compressedSize, err := lz4.Encode(cw.zdata[HeaderSize:], cw.data[:cw.pos])
-	if err != nil {
+	var compressedSize int
+	if cw.method == ZSTD {
+		cw.zdata = zstdEncoder.EncodeAll(cw.data[:cw.pos], cw.zdata[:HeaderSize])
+		compressedSize = len(cw.zdata) - HeaderSize
+	} else if compressedSize, err = lz4.Encode(cw.zdata[HeaderSize:], cw.data[:cw.pos]); err != nil {
 		return err
 	}
 	compressedSize += CompressHeaderSize
 	// fill the header, compressed_size_32 + uncompressed_size_32
-	cw.zdata[16] = LZ4
+	cw.zdata[16] = byte(cw.method)
 	binary.LittleEndian.PutUint32(cw.zdata[17:], uint32(compressedSize))
 	binary.LittleEndian.PutUint32(cw.zdata[21:], uint32(cw.pos))
 
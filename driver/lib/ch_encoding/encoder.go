@@ -28,6 +28,14 @@ func NewEncoderWithCompress(w io.Writer) *Encoder {
 	}
 }
 
+// NewEncoderWithCompressMethod is NewEncoderWithCompress with the compression method, which is either LZ4 or ZSTD
+func NewEncoderWithCompressMethod(w io.Writer, method CompressionMethodByte) *Encoder {
+	return &Encoder{
+		output:         w,
+		compressOutput: NewCompressWriterWithMethod(w, method),
+	}
+}
+
 // Write writes len(p) bytes from p to the output data stream
 func (enc *Encoder) Write(p []byte) (n int, err error) {
 	return enc.GetOutput().Write(p)
@@ -70,6 +78,19 @@ func (enc *Encoder) String(s string) error {
 	return nil
 }
 
+// WriteRaw writes p to the underlying output without compressing it,
+// pending compressed data is flushed first to keep the order of writes.
+// p is expected to be already compressed if the encoder is in compressed mode.
+func (enc *Encoder) WriteRaw(p []byte) error {
+	if enc.compress && enc.compressOutput != nil {
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+	}
+	_, err := enc.output.Write(p)
+	return err
+}
+
 func (enc *Encoder) Flush() error {
 	if f, ok := enc.GetOutput().(Flusher); ok {
 		return f.Flush()
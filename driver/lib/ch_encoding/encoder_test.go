@@ -136,6 +136,30 @@ func TestEncoderDecoder(t *testing.T) {
 				require.Equal(t, data, n)
 			},
 		},
+		{
+			name: "Test Read/Write String Compressed with ZSTD",
+			test: func(t *testing.T) {
+				var buffer bytes.Buffer
+
+				encoder := NewEncoderWithCompressMethod(&buffer, ZSTD)
+				encoder.SelectCompress(true)
+
+				decoder := NewDecoderWithCompress(&buffer)
+				decoder.SetCompress(true)
+
+				// larger than BlockMaxSize to be written in multiple compressed blocks
+				data := string(bytes.Repeat([]byte("zstd"), BlockMaxSize/2))
+
+				require.NoError(t, encoder.String(data))
+				require.NoError(t, encoder.Flush())
+				require.Equal(t, byte(ZSTD), buffer.Bytes()[ChecksumSize])
+				require.Less(t, buffer.Len(), len(data))
+
+				s, err := decoder.String()
+				require.NoError(t, err)
+				require.Equal(t, data, s)
+			},
+		},
 		{
 			name: "Test Read/Write UInt32",
 			test: func(t *testing.T) {
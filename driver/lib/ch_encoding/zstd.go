@@ -0,0 +1,22 @@
+package ch_encoding
+
+import (
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	zstdOnce sync.Once
+	// zstdEncoder and zstdDecoder are shared as they are safe for concurrent use of EncodeAll and DecodeAll
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func initZstd() {
+	zstdOnce.Do(func() {
+		// errors are only returned for invalid options
+		zstdEncoder, _ = zstd.NewWriter(nil)
+		zstdDecoder, _ = zstd.NewReader(nil)
+	})
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+// ArrowSchema returns the Arrow schema of records of blocks with cols, see column.ArrowType for the mapping of types
+func ArrowSchema(cols []*column.CHColumn) *arrow.Schema {
+	fields := make([]arrow.Field, len(cols))
+	for i, col := range cols {
+		fields[i].Name = col.Name
+		fields[i].Type, fields[i].Nullable = column.ArrowType(col.Data)
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// BlockToArrowRecord returns the rows of b as an Arrow record, which must be released by the caller.
+// The record does not refer to the memory of b, which can be closed after.
+func BlockToArrowRecord(b *Block) arrow.Record {
+	cols := make([]arrow.Array, len(b.Columns))
+	for i, col := range b.Columns {
+		cols[i] = column.NewArrowArray(col.Data, b.NumRows)
+	}
+	record := array.NewRecord(ArrowSchema(b.Columns), cols, int64(b.NumRows))
+	for _, col := range cols {
+		col.Release()
+	}
+	return record
+}
+
+// ArrowRecordToBlock returns the rows of record as a block with the columns of sample.
+// Columns are matched to the columns of record by name, values of other types than the ones given
+// by ArrowSchema of sample are converted, see column.ReadFromArrow.
+func ArrowRecordToBlock(record arrow.Record, sample *Block) (*Block, error) {
+	fields := make([]int, len(sample.Columns))
+	for i, col := range sample.Columns {
+		indices := record.Schema().FieldIndices(col.Name)
+		if len(indices) == 0 {
+			return nil, errors.ErrorfWithCaller("column %v not found in Arrow record", col.Name)
+		}
+		fields[i] = indices[0]
+	}
+
+	b := sample.StructureCopy(int(record.NumRows()))
+	for i, col := range b.Columns {
+		if _, err := column.ReadFromArrow(col.Data, record.Column(fields[i])); err != nil {
+			b.Close()
+			return nil, errors.ErrorfWithCaller("failed to read column %v: %v", col.Name, err)
+		}
+	}
+	return b, nil
+}
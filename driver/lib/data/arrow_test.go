@@ -0,0 +1,121 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestBlockToArrowRecord_RoundTrip(t *testing.T) {
+	names := []string{"i", "n", "f", "d", "s", "ns", "lc", "dt", "dec", "arr", "m", "tup", "uuid"}
+	types := []column.CHColumnType{
+		"Int64", "Nullable(UInt16)", "Float32", "Date32", "String", "Nullable(String)", "LowCardinality(String)",
+		"DateTime64(3, 'UTC')", "Decimal(18, 4)", "Array(Nullable(Int8))", "Map(String, Array(Int32))",
+		"Tuple(String, Float64)", "UUID",
+	}
+	texts := [][]string{
+		{"-1", "9223372036854775807", "0"},
+		{"1", "NULL", "65535"},
+		{"1.5", "-0.25", "3"},
+		{"1969-12-31", "2022-01-02", "1970-01-01"},
+		{"a", "NULL", ""},
+		{"NULL", "NULL", "x"},
+		{"x", "y", "x"},
+		{"2022-01-02 03:04:05.123", "1970-01-01 00:00:00.000", "2001-02-03 04:05:06.007"},
+		{"1.2345", "-7", "0"},
+		{"[1, NULL]", "[]", "[-3]"},
+		{"{'a': [1, 2]}", "{}", "{'b': [], 'c': [3]}"},
+		{"('x', 1.5)", "('', 0)", "('z', -2)"},
+		{"123e4567-e89b-12d3-a456-426614174000", "00000000-0000-0000-0000-000000000000", "ffffffff-ffff-ffff-ffff-ffffffffffff"},
+	}
+	b, err := NewBlock(names, types, 3)
+	require.NoError(t, err)
+	defer b.Close()
+	_, _, err = b.ReadFromColumnTexts(texts)
+	require.NoError(t, err)
+
+	record := BlockToArrowRecord(b)
+	defer record.Release()
+	require.Equal(t, int64(3), record.NumRows())
+	require.True(t, record.Schema().Field(1).Nullable)
+	require.Equal(t, "[1 (null) 65535]", record.Column(1).String())
+
+	got, err := ArrowRecordToBlock(record, b)
+	require.NoError(t, err)
+	defer got.Close()
+	require.Equal(t, 3, got.NumRows)
+	for i, col := range b.Columns {
+		for row := 0; row < b.NumRows; row++ {
+			require.Equal(t, col.Data.GetString(row), got.Columns[i].Data.GetString(row), "row %v column %v", row, names[i])
+		}
+	}
+}
+
+func TestArrowRecordToBlock(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "b", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "a", Type: arrow.BinaryTypes.String},
+	}, nil)
+	rb := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer rb.Release()
+	rb.Field(0).(*array.Int32Builder).AppendValues([]int32{1, 0, 3}, []bool{true, false, true})
+	rb.Field(1).(*array.StringBuilder).AppendValues([]string{"x", "NULL", "'z'"}, nil)
+	record := rb.NewRecord()
+	defer record.Release()
+
+	tests := []struct {
+		name    string
+		names   []string
+		types   []column.CHColumnType
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:  "Should match columns by name",
+			names: []string{"a", "b"},
+			types: []column.CHColumnType{"String", "Nullable(Int32)"},
+			want:  [][]string{{"x", "NULL", "'z'"}, {"1", column.NULLDisplay, "3"}},
+		},
+		{
+			name:  "Should convert values of other types",
+			names: []string{"b", "a"},
+			types: []column.CHColumnType{"Int64", "Nullable(String)"},
+			want:  [][]string{{"1", "0", "3"}, {"x", "NULL", "'z'"}},
+		},
+		{
+			name:    "Should fail if a column is not in the record",
+			names:   []string{"a", "c"},
+			types:   []column.CHColumnType{"String", "Int32"},
+			wantErr: true,
+		},
+		{
+			name:    "Should fail if values cannot be converted",
+			names:   []string{"a"},
+			types:   []column.CHColumnType{"Int32"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := NewBlock(tt.names, tt.types, 0)
+			require.NoError(t, err)
+			b, err := ArrowRecordToBlock(record, sample)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer b.Close()
+			for i, col := range b.Columns {
+				for row, want := range tt.want[i] {
+					require.Equal(t, want, col.Data.GetString(row))
+				}
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package data
 
 import (
+	"bytes"
+	"io"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -15,6 +17,10 @@ type Block struct {
 	NumColumns int
 	NumRows    int
 	Columns    []*column.CHColumn
+
+	// encoded holds the block already written by Encode
+	encoded           []byte
+	encodedCompressed bool
 }
 
 func NewBlockWithLocation(colNames []string, colTypes []column.CHColumnType, numRows int, location *time.Location) (*Block, error) {
@@ -54,15 +60,26 @@ func ReadBlockFromDecoder(decoder *ch_encoding.Decoder) (*Block, error) {
 }
 
 func ReadBlockFromDecoderWithLocation(decoder *ch_encoding.Decoder, location *time.Location) (*Block, error) {
+	info, err := readBlockInfo(decoder)
+	if err != nil {
+		return nil, err
+	}
+	block, err := ReadNativeBlockFromDecoder(decoder, location)
+	if err != nil {
+		return nil, err
+	}
+	block.info = info
+	return block, nil
+}
+
+// ReadNativeBlockFromDecoder reads a block of the Native format, which is a block without its block info.
+// io.EOF is only returned if the input ends before the block, io.ErrUnexpectedEOF if it ends within the block.
+func ReadNativeBlockFromDecoder(decoder *ch_encoding.Decoder, location *time.Location) (*Block, error) {
 	var (
 		block Block
 		i     uint64
 		err   error
 	)
-	block.info, err = readBlockInfo(decoder)
-	if err != nil {
-		return nil, err
-	}
 	i, err = decoder.Uvarint()
 	if err != nil {
 		return nil, err
@@ -70,25 +87,40 @@ func ReadBlockFromDecoderWithLocation(decoder *ch_encoding.Decoder, location *ti
 	block.NumColumns = int(i)
 	i, err = decoder.Uvarint()
 	if err != nil {
-		return nil, err
+		return nil, unexpectedEOF(err)
 	}
 	block.NumRows = int(i)
 	block.Columns = make([]*column.CHColumn, block.NumColumns)
 	for j := range block.Columns {
 		if block.Columns[j], err = column.ReadColumnFromDecoderWithLocation(decoder, block.NumRows, location); err != nil {
-			return nil, err
+			return nil, unexpectedEOF(err)
 		}
 	}
 	return &block, nil
 }
 
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
 func WriteBlockToEncoder(encoder *ch_encoding.Encoder, b *Block) error {
+	if b.encoded != nil && b.encodedCompressed == encoder.IsCompressed() {
+		return encoder.WriteRaw(b.encoded)
+	}
 	if b.info == nil {
 		b.info = &blockInfo{}
 	}
 	if err := writeBlockInfo(encoder, b.info); err != nil {
 		return err
 	}
+	return WriteNativeBlockToEncoder(encoder, b)
+}
+
+// WriteNativeBlockToEncoder writes b in the Native format, which is the block without its block info
+func WriteNativeBlockToEncoder(encoder *ch_encoding.Encoder, b *Block) error {
 	if err := encoder.Uvarint(uint64(b.NumColumns)); err != nil {
 		return err
 	}
@@ -175,7 +207,35 @@ func (b *Block) ReadFromColumnValues(colValues [][]interface{}) (rowsRead, colum
 	return rowsRead, len(colValues), nil
 }
 
+// Encode writes the block ahead of time, compressed if compress is true.
+// Later calls to WriteBlockToEncoder with an encoder of the same compression
+// only copy the encoded bytes, which allows encoding blocks in parallel.
+func (b *Block) Encode(compress bool) error {
+	var (
+		buf     bytes.Buffer
+		encoder *ch_encoding.Encoder
+	)
+	if compress {
+		encoder = ch_encoding.NewEncoderWithCompress(&buf)
+		encoder.SelectCompress(true)
+	} else {
+		encoder = ch_encoding.NewEncoder(&buf)
+	}
+
+	b.encoded = nil
+	if err := WriteBlockToEncoder(encoder, b); err != nil {
+		return err
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	b.encoded = buf.Bytes()
+	b.encodedCompressed = compress
+	return nil
+}
+
 func (b *Block) Close() error {
+	b.encoded = nil
 	for i := range b.Columns {
 		if err := b.Columns[i].Close(); err != nil {
 			return err
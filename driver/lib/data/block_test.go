@@ -665,3 +665,46 @@ func TestReadFromColumnsValueToBlock_ThenWriteToEncoder_ThenDecodeBack(t *testin
 		})
 	}
 }
+
+func TestBlock_Encode(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		b, err := NewBlock([]string{"a", "b"}, []column.CHColumnType{column.INT32, column.STRING}, 3)
+		require.NoError(t, err)
+		_, _, err = b.ReadFromColumnValues([][]interface{}{
+			{int32(1), int32(2), int32(3)},
+			{"x", "y", "z"},
+		})
+		require.NoError(t, err)
+
+		var direct bytes.Buffer
+		encoder := ch_encoding.NewEncoder(&direct)
+		if compress {
+			encoder = ch_encoding.NewEncoderWithCompress(&direct)
+		}
+		encoder.SelectCompress(compress)
+		require.NoError(t, WriteBlockToEncoder(encoder, b))
+		encoder.SelectCompress(false)
+
+		require.NoError(t, b.Encode(compress))
+		var preEncoded bytes.Buffer
+		encoder = ch_encoding.NewEncoder(&preEncoded)
+		if compress {
+			encoder = ch_encoding.NewEncoderWithCompress(&preEncoded)
+		}
+		encoder.SelectCompress(compress)
+		require.NoError(t, WriteBlockToEncoder(encoder, b))
+		encoder.SelectCompress(false)
+
+		assert.Equal(t, direct.Bytes(), preEncoded.Bytes())
+
+		decoder := ch_encoding.NewDecoder(&preEncoded)
+		if compress {
+			decoder = ch_encoding.NewDecoderWithCompress(&preEncoded)
+		}
+		decoder.SetCompress(compress)
+		decoded, err := ReadBlockFromDecoder(decoder)
+		require.NoError(t, err)
+		assert.Equal(t, 3, decoded.NumRows)
+		assert.Equal(t, "z", decoded.Columns[1].Data.GetString(2))
+	}
+}
@@ -0,0 +1,527 @@
+package column
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/bitutil"
+	"github.com/apache/arrow/go/v10/arrow/decimal128"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/shopspring/decimal"
+
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+const (
+	// maxArrowDecimalPrecision is the largest precision of decimals converted to Arrow Decimal128,
+	// larger decimals are converted to strings
+	maxArrowDecimalPrecision = 38
+	arrowDateFormat          = "2006-01-02"
+)
+
+// ArrowType returns the Arrow type of the values of data and whether they can be null.
+// Types without an Arrow equivalent, such as UUID, IPv4 and Enum8, are converted to strings.
+func ArrowType(data CHColumnData) (dataType arrow.DataType, nullable bool) {
+	switch data := data.(type) {
+	case *NullableColumnData:
+		dataType, _ = ArrowType(data.innerColumnData)
+		return dataType, true
+	case *LowCardinalityColumnData:
+		dataType, _ = ArrowType(data.keys)
+		return dataType, data.isNullableCol
+	case *Int8ColumnData:
+		return arrow.PrimitiveTypes.Int8, false
+	case *Int16ColumnData:
+		return arrow.PrimitiveTypes.Int16, false
+	case *Int32ColumnData:
+		return arrow.PrimitiveTypes.Int32, false
+	case *Int64ColumnData:
+		return arrow.PrimitiveTypes.Int64, false
+	case *UInt8ColumnData:
+		return arrow.PrimitiveTypes.Uint8, false
+	case *UInt16ColumnData:
+		return arrow.PrimitiveTypes.Uint16, false
+	case *UInt32ColumnData:
+		return arrow.PrimitiveTypes.Uint32, false
+	case *UInt64ColumnData:
+		return arrow.PrimitiveTypes.Uint64, false
+	case *Float32ColumnData:
+		return arrow.PrimitiveTypes.Float32, false
+	case *Float64ColumnData:
+		return arrow.PrimitiveTypes.Float64, false
+	case *BoolColumnData:
+		return arrow.FixedWidthTypes.Boolean, false
+	case *FixedStringColumnData:
+		return &arrow.FixedSizeBinaryType{ByteWidth: len(data.mask)}, false
+	case *DateColumnData, *Date32ColumnData:
+		return arrow.FixedWidthTypes.Date32, false
+	case *DateTimeColumnData:
+		return &arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"}, false
+	case *DateTime64ColumnData:
+		return &arrow.TimestampType{Unit: arrowTimeUnit(data.precision), TimeZone: "UTC"}, false
+	case *DecimalColumnData:
+		if data.precision > maxArrowDecimalPrecision {
+			return arrow.BinaryTypes.String, false
+		}
+		return &arrow.Decimal128Type{Precision: int32(data.precision), Scale: int32(data.scale)}, false
+	case *ArrayColumnData:
+		elemType, elemNullable := ArrowType(data.generateInnerData(0))
+		return arrow.ListOfField(arrow.Field{Name: "element", Type: elemType, Nullable: elemNullable}), false
+	case *MapColumnData:
+		keyType, _ := ArrowType(data.generateKeys(0))
+		valueType, _ := ArrowType(data.generateValues(0))
+		return arrow.MapOf(keyType, valueType), false
+	case *TupleColumnData:
+		fields := make([]arrow.Field, len(data.innerColumnsData))
+		for i, inner := range data.innerColumnsData {
+			fields[i].Name = strconv.Itoa(i + 1) // unnamed tuple elements are numbered from 1 as in ClickHouse
+			fields[i].Type, fields[i].Nullable = ArrowType(inner)
+		}
+		return arrow.StructOf(fields...), false
+	default:
+		return arrow.BinaryTypes.String, false
+	}
+}
+
+// NewArrowArray returns the first numRows values of data as an Arrow array of the type returned by ArrowType.
+// Values of numbers and Date32, including nullable ones, are copied as is instead of being appended one by one.
+func NewArrowArray(data CHColumnData, numRows int) arrow.Array {
+	var mask []byte
+	inner := data
+	if nullable, ok := data.(*NullableColumnData); ok {
+		mask, inner = nullable.mask, nullable.innerColumnData
+	}
+	raw, dataType, ok := arrowFixedWidth(inner)
+	if !ok {
+		return newArrowArrayByRow(data, numRows)
+	}
+
+	values := memory.NewResizableBuffer(memory.DefaultAllocator)
+	defer values.Release()
+	values.Resize(numRows * arrowByteWidth(dataType))
+	copy(values.Bytes(), raw)
+
+	var (
+		validity  *memory.Buffer
+		nullCount int
+	)
+	if mask != nil {
+		validity = memory.NewResizableBuffer(memory.DefaultAllocator)
+		defer validity.Release()
+		validity.Resize(int(bitutil.BytesForBits(int64(numRows))))
+		for row := 0; row < numRows; row++ {
+			if mask[row] != 0 {
+				nullCount++
+				continue
+			}
+			bitutil.SetBit(validity.Bytes(), row)
+		}
+	}
+
+	arrayData := array.NewData(dataType, numRows, []*memory.Buffer{validity, values}, nil, nullCount, 0)
+	defer arrayData.Release()
+	return array.MakeFromData(arrayData)
+}
+
+func newArrowArrayByRow(data CHColumnData, numRows int) arrow.Array {
+	dataType, _ := ArrowType(data)
+	b := array.NewBuilder(memory.DefaultAllocator, dataType)
+	defer b.Release()
+	reserveArrowBuilder(b, numRows)
+	for row := 0; row < numRows; row++ {
+		AppendArrow(b, data, row)
+	}
+	return b.NewArray()
+}
+
+// reserveArrowBuilder reserves n values in b and at least one value in the builders of its elements,
+// arrays of no values still need their buffers to be written to Parquet
+func reserveArrowBuilder(b array.Builder, n int) {
+	b.Reserve(n)
+	switch b := b.(type) {
+	case *array.ListBuilder:
+		reserveArrowBuilder(b.ValueBuilder(), n)
+	case *array.MapBuilder:
+		reserveArrowBuilder(b.KeyBuilder(), n)
+		reserveArrowBuilder(b.ItemBuilder(), n)
+	case *array.StructBuilder:
+		for i := 0; i < b.NumField(); i++ {
+			reserveArrowBuilder(b.FieldBuilder(i), n)
+		}
+	}
+}
+
+// arrowFixedWidth returns the values of data and their Arrow type if they have the same layout in Arrow
+func arrowFixedWidth(data CHColumnData) ([]byte, arrow.DataType, bool) {
+	switch data := data.(type) {
+	case *Int8ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Int8, true
+	case *Int16ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Int16, true
+	case *Int32ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Int32, true
+	case *Int64ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Int64, true
+	case *UInt8ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Uint8, true
+	case *UInt16ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Uint16, true
+	case *UInt32ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Uint32, true
+	case *UInt64ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Uint64, true
+	case *Float32ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Float32, true
+	case *Float64ColumnData:
+		return data.raw, arrow.PrimitiveTypes.Float64, true
+	case *Date32ColumnData:
+		return data.raw, arrow.FixedWidthTypes.Date32, true
+	}
+	return nil, nil, false
+}
+
+func arrowByteWidth(dataType arrow.DataType) int {
+	return dataType.(arrow.FixedWidthDataType).BitWidth() / 8
+}
+
+// AppendArrow appends the value of data at row to b, a builder of the type returned by ArrowType
+func AppendArrow(b array.Builder, data CHColumnData, row int) {
+	switch data := data.(type) {
+	case *NullableColumnData:
+		if data.mask[row] != 0 {
+			b.AppendNull()
+			return
+		}
+		AppendArrow(b, data.innerColumnData, row)
+	case *LowCardinalityColumnData:
+		index := int(data.getIndex(row))
+		if data.isNullableCol && index == 0 {
+			b.AppendNull()
+			return
+		}
+		AppendArrow(b, data.keys, index)
+	case *Int8ColumnData:
+		b.(*array.Int8Builder).Append(data.get(row))
+	case *Int16ColumnData:
+		b.(*array.Int16Builder).Append(data.get(row))
+	case *Int32ColumnData:
+		b.(*array.Int32Builder).Append(data.get(row))
+	case *Int64ColumnData:
+		b.(*array.Int64Builder).Append(data.get(row))
+	case *UInt8ColumnData:
+		b.(*array.Uint8Builder).Append(data.get(row))
+	case *UInt16ColumnData:
+		b.(*array.Uint16Builder).Append(data.get(row))
+	case *UInt32ColumnData:
+		b.(*array.Uint32Builder).Append(data.get(row))
+	case *UInt64ColumnData:
+		b.(*array.Uint64Builder).Append(data.get(row))
+	case *Float32ColumnData:
+		b.(*array.Float32Builder).Append(data.get(row))
+	case *Float64ColumnData:
+		b.(*array.Float64Builder).Append(data.get(row))
+	case *BoolColumnData:
+		b.(*array.BooleanBuilder).Append(data.raw[row] != 0)
+	case *FixedStringColumnData:
+		b.(*array.FixedSizeBinaryBuilder).Append(getRowRaw(data.raw, row, len(data.mask)))
+	case *DateColumnData, *Date32ColumnData:
+		b.(*array.Date32Builder).Append(arrow.Date32FromTime(data.GetValue(row).(time.Time)))
+	case *DateTimeColumnData, *DateTime64ColumnData:
+		tb := b.(*array.TimestampBuilder)
+		tb.Append(arrowTimestamp(data.GetValue(row).(time.Time), tb.Type().(*arrow.TimestampType).Unit))
+	case *DecimalColumnData:
+		if data.precision > maxArrowDecimalPrecision {
+			b.(*array.StringBuilder).Append(data.GetString(row))
+			return
+		}
+		v := data.GetValue(row).(decimal.Decimal)
+		b.(*array.Decimal128Builder).Append(decimal128.FromBigInt(v.Shift(int32(data.scale)).BigInt()))
+	case *ArrayColumnData:
+		lb := b.(*array.ListBuilder)
+		lb.Append(true)
+		for i := data.findOffset(row - 1); i < data.findOffset(row); i++ {
+			AppendArrow(lb.ValueBuilder(), data.innerColumnData, i)
+		}
+	case *MapColumnData:
+		mb := b.(*array.MapBuilder)
+		mb.Append(true)
+		for i := data.findOffset(row - 1); i < data.findOffset(row); i++ {
+			AppendArrow(mb.KeyBuilder(), data.keyColumnData, i)
+			AppendArrow(mb.ItemBuilder(), data.valueColumnData, i)
+		}
+	case *TupleColumnData:
+		sb := b.(*array.StructBuilder)
+		sb.Append(true)
+		for i, inner := range data.innerColumnsData {
+			AppendArrow(sb.FieldBuilder(i), inner, row)
+		}
+	default:
+		b.(*array.StringBuilder).Append(data.GetString(row))
+	}
+}
+
+// arrowTimeUnit returns the Arrow time unit which holds times of DateTime64 with precision
+func arrowTimeUnit(precision int) arrow.TimeUnit {
+	switch {
+	case precision == 0:
+		return arrow.Second
+	case precision <= 3:
+		return arrow.Millisecond
+	case precision <= 6:
+		return arrow.Microsecond
+	default:
+		return arrow.Nanosecond
+	}
+}
+
+func arrowTimestamp(t time.Time, unit arrow.TimeUnit) arrow.Timestamp {
+	switch unit {
+	case arrow.Second:
+		return arrow.Timestamp(t.Unix())
+	case arrow.Millisecond:
+		return arrow.Timestamp(t.UnixNano() / int64(time.Millisecond))
+	case arrow.Microsecond:
+		return arrow.Timestamp(t.UnixNano() / int64(time.Microsecond))
+	default:
+		return arrow.Timestamp(t.UnixNano())
+	}
+}
+
+// ReadFromArrow reads the values of arr into data, which has at least arr.Len() rows, and returns the number of rows read.
+// Values of the same type as ArrowType of data are copied as is, values of other types are converted through text,
+// nulls are read as the default value of data if it is not nullable.
+func ReadFromArrow(data CHColumnData, arr arrow.Array) (int, error) {
+	if arr.Len() == 0 {
+		return 0, nil
+	}
+	switch data := data.(type) {
+	case *NullableColumnData:
+		for row := 0; row < arr.Len(); row++ {
+			if arr.IsNull(row) {
+				data.mask[row] = 1
+			}
+		}
+		return ReadFromArrow(data.innerColumnData, arr)
+	case *StringColumnData:
+		if n, ok := readArrowStrings(data, arr); ok {
+			return n, nil
+		}
+	}
+
+	raw, dataType, ok := arrowFixedWidth(data)
+	if !ok || !arrow.TypeEqual(dataType, arr.DataType()) {
+		return readArrowTexts(data, arr)
+	}
+	width := arrowByteWidth(dataType)
+	values := arr.Data().Buffers()[1].Bytes()[arr.Data().Offset()*width:]
+	copy(raw[:arr.Len()*width], values)
+	for row := 0; row < arr.Len(); row++ {
+		if arr.IsNull(row) {
+			for i := row * width; i < (row+1)*width; i++ {
+				raw[i] = 0
+			}
+		}
+	}
+	return arr.Len(), nil
+}
+
+// readArrowStrings reads arr into data if arr is an array of strings or bytes,
+// the values are copied to a single buffer as arr may be released before data
+func readArrowStrings(data *StringColumnData, arr arrow.Array) (int, bool) {
+	var (
+		valueBytes []byte
+		value      func(row int) string
+	)
+	switch arr := arr.(type) {
+	case *array.String:
+		valueBytes, value = arr.ValueBytes(), arr.Value
+	case *array.Binary:
+		valueBytes, value = arr.ValueBytes(), arr.ValueString
+	default:
+		return 0, false
+	}
+
+	buf := make([]byte, 0, len(valueBytes))
+	for row := 0; row < arr.Len(); row++ {
+		start := len(buf)
+		if !arr.IsNull(row) {
+			buf = append(buf, value(row)...)
+		}
+		data.raw[row] = buf[start:len(buf):len(buf)]
+	}
+	return arr.Len(), true
+}
+
+func readArrowTexts(data CHColumnData, arr arrow.Array) (int, error) {
+	var (
+		texts = make([]string, arr.Len())
+		buf   []byte
+		err   error
+	)
+	for row := range texts {
+		if buf, err = AppendArrowText(buf[:0], data, arr, row); err != nil {
+			return row, err
+		}
+		texts[row] = string(buf)
+	}
+	return data.ReadFromTexts(texts)
+}
+
+// AppendArrowText appends the value of arr at row as text to be read by ReadFromTexts of data.
+// A null is appended as the default value of data if data is not nullable.
+func AppendArrowText(dst []byte, data CHColumnData, arr arrow.Array, row int) ([]byte, error) {
+	if arr.IsNull(row) {
+		return append(dst, arrowNullText(data)...), nil
+	}
+	if isArrowStringColumn(data) {
+		if s, ok := arrowString(arr, row); ok {
+			// quoted so that strings such as NULL are not read as null
+			dst = append(dst, doubleQuote)
+			dst = append(dst, s...)
+			return append(dst, doubleQuote), nil
+		}
+	}
+	return appendArrowText(dst, arr, row, false)
+}
+
+func arrowNullText(data CHColumnData) string {
+	switch data.(type) {
+	case *NullableColumnData, *LowCardinalityColumnData:
+		return NULL
+	}
+	return data.ZeroString()
+}
+
+func isArrowStringColumn(data CHColumnData) bool {
+	switch data := data.(type) {
+	case *StringColumnData, *FixedStringColumnData:
+		return true
+	case *NullableColumnData:
+		return isArrowStringColumn(data.innerColumnData)
+	}
+	return false
+}
+
+// arrowString returns the value of arr at row if arr is an array of strings or bytes
+func arrowString(arr arrow.Array, row int) (string, bool) {
+	switch arr := arr.(type) {
+	case *array.Dictionary:
+		return arrowString(arr.Dictionary(), arr.GetValueIndex(row))
+	case *array.String:
+		return arr.Value(row), true
+	case *array.LargeString:
+		return arr.Value(row), true
+	case *array.Binary:
+		return string(arr.Value(row)), true
+	case *array.LargeBinary:
+		return string(arr.Value(row)), true
+	case *array.FixedSizeBinary:
+		return string(arr.Value(row)), true
+	}
+	return "", false
+}
+
+// appendArrowText appends the value of arr at row as ClickHouse text, strings and times are quoted if nested
+// in arrays, maps or tuples
+func appendArrowText(dst []byte, arr arrow.Array, row int, nested bool) ([]byte, error) {
+	if arr.IsNull(row) {
+		return append(dst, NULL...), nil
+	}
+	if s, ok := arrowString(arr, row); ok {
+		return appendArrowQuoted(dst, s, nested), nil
+	}
+
+	var err error
+	switch arr := arr.(type) {
+	case *array.Dictionary:
+		return appendArrowText(dst, arr.Dictionary(), arr.GetValueIndex(row), nested)
+	case *array.Boolean:
+		if arr.Value(row) {
+			return append(dst, '1'), nil
+		}
+		return append(dst, '0'), nil
+	case *array.Int8:
+		return strconv.AppendInt(dst, int64(arr.Value(row)), 10), nil
+	case *array.Int16:
+		return strconv.AppendInt(dst, int64(arr.Value(row)), 10), nil
+	case *array.Int32:
+		return strconv.AppendInt(dst, int64(arr.Value(row)), 10), nil
+	case *array.Int64:
+		return strconv.AppendInt(dst, arr.Value(row), 10), nil
+	case *array.Uint8:
+		return strconv.AppendUint(dst, uint64(arr.Value(row)), 10), nil
+	case *array.Uint16:
+		return strconv.AppendUint(dst, uint64(arr.Value(row)), 10), nil
+	case *array.Uint32:
+		return strconv.AppendUint(dst, uint64(arr.Value(row)), 10), nil
+	case *array.Uint64:
+		return strconv.AppendUint(dst, arr.Value(row), 10), nil
+	case *array.Float32:
+		return strconv.AppendFloat(dst, float64(arr.Value(row)), 'g', -1, 32), nil
+	case *array.Float64:
+		return strconv.AppendFloat(dst, arr.Value(row), 'g', -1, 64), nil
+	case *array.Decimal128:
+		scale := arr.DataType().(*arrow.Decimal128Type).Scale
+		return append(dst, decimal.NewFromBigInt(arr.Value(row).BigInt(), -scale).String()...), nil
+	case *array.Date32:
+		return appendArrowQuoted(dst, arr.Value(row).ToTime().Format(arrowDateFormat), nested), nil
+	case *array.Date64:
+		return appendArrowQuoted(dst, arr.Value(row).ToTime().Format(arrowDateFormat), nested), nil
+	case *array.Timestamp:
+		unit := arr.DataType().(*arrow.TimestampType).Unit
+		return appendArrowQuoted(dst, arr.Value(row).ToTime(unit).Format(time.RFC3339Nano), nested), nil
+	case *array.List:
+		start, end := arrowListOffsets(arr.Offsets(), arr, row)
+		return appendArrowElems(dst, '[', ']', start, end, func(dst []byte, i int) ([]byte, error) {
+			return appendArrowText(dst, arr.ListValues(), i, true)
+		})
+	case *array.Map:
+		start, end := arrowListOffsets(arr.Offsets(), arr, row)
+		return appendArrowElems(dst, '{', '}', start, end, func(dst []byte, i int) ([]byte, error) {
+			if dst, err = appendArrowText(dst, arr.Keys(), i, true); err != nil {
+				return dst, err
+			}
+			return appendArrowText(append(dst, ": "...), arr.Items(), i, true)
+		})
+	case *array.Struct:
+		return appendArrowElems(dst, '(', ')', 0, arr.NumField(), func(dst []byte, i int) ([]byte, error) {
+			return appendArrowText(dst, arr.Field(i), row, true)
+		})
+	}
+	return dst, errors.ErrorfWithCaller("unsupported Arrow type: %v", arr.DataType())
+}
+
+// arrowListOffsets returns the range of the elements of the list of arr at row, unlike ValueOffsets of arrays of
+// lists it takes into account the offset of sliced arrays
+func arrowListOffsets(offsets []int32, arr arrow.Array, row int) (start, end int) {
+	i := arr.Data().Offset() + row
+	return int(offsets[i]), int(offsets[i+1])
+}
+
+// appendArrowElems appends the elements from start to end separated by commas and enclosed in openBracket and closeBracket
+func appendArrowElems(dst []byte, openBracket, closeBracket byte, start, end int,
+	appendElem func(dst []byte, i int) ([]byte, error),
+) ([]byte, error) {
+	var err error
+	dst = append(dst, openBracket)
+	for i := start; i < end; i++ {
+		if i > start {
+			dst = append(dst, ", "...)
+		}
+		if dst, err = appendElem(dst, i); err != nil {
+			return dst, err
+		}
+	}
+	return append(dst, closeBracket), nil
+}
+
+func appendArrowQuoted(dst []byte, s string, quoted bool) []byte {
+	if !quoted {
+		return append(dst, s...)
+	}
+	dst = append(dst, singleQuote)
+	dst = append(dst, s...)
+	return append(dst, singleQuote)
+}
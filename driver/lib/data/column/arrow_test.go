@@ -0,0 +1,72 @@
+package column
+
+import (
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow/array"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrowType(t *testing.T) {
+	tests := []struct {
+		columnType   CHColumnType
+		wantType     string
+		wantNullable bool
+	}{
+		{columnType: "UInt16", wantType: "uint16"},
+		{columnType: "Nullable(Float32)", wantType: "float32", wantNullable: true},
+		{columnType: "FixedString(4)", wantType: "fixed_size_binary[4]"},
+		{columnType: "DateTime64(6, 'Asia/Singapore')", wantType: "timestamp[us, tz=UTC]"},
+		{columnType: "Decimal(18, 4)", wantType: "decimal(18, 4)"},
+		{columnType: "Decimal(76, 4)", wantType: "utf8"},
+		{columnType: "LowCardinality(Nullable(String))", wantType: "utf8", wantNullable: true},
+		{columnType: "Array(Nullable(Int8))", wantType: "list<element: int8, nullable>"},
+		{columnType: "Map(String, Array(Date))", wantType: "map<utf8, list<element: date32>>"},
+		{columnType: "Tuple(Int64, UUID)", wantType: "struct<1: int64, 2: utf8>"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.columnType), func(t *testing.T) {
+			gen, err := GenerateColumnDataFactory(tt.columnType)
+			require.NoError(t, err)
+			gotType, gotNullable := ArrowType(gen(0))
+			require.Equal(t, tt.wantType, gotType.String())
+			require.Equal(t, tt.wantNullable, gotNullable)
+		})
+	}
+}
+
+func TestAppendArrow(t *testing.T) {
+	tests := []struct {
+		columnType CHColumnType
+		texts      []string
+		want       string
+	}{
+		{columnType: "Int32", texts: []string{"1", "-2"}, want: "[1 -2]"},
+		{columnType: "Nullable(String)", texts: []string{"a", "NULL"}, want: `["a" (null)]`},
+		{columnType: "LowCardinality(Nullable(String))", texts: []string{"NULL", "b", "b"}, want: `[(null) "b" "b"]`},
+		{columnType: "Decimal(9, 2)", texts: []string{"1.5", "-0.01"}, want: "[{150 0} {18446744073709551615 -1}]"}, // 150 and -1 at scale 2
+		{columnType: "Array(Int16)", texts: []string{"[1, 2]", "[]", "[3]"}, want: "[[1 2] [] [3]]"},
+		{columnType: "Map(String, UInt8)", texts: []string{"{'b': 1, 'a': 2}"}, want: `[{["b" "a"] [1 2]}]`},
+		{columnType: "Tuple(Int8, String)", texts: []string{"(1, 'x')"}, want: `{[1] ["x"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.columnType), func(t *testing.T) {
+			gen, err := GenerateColumnDataFactory(tt.columnType)
+			require.NoError(t, err)
+			data := gen(len(tt.texts))
+			_, err = data.ReadFromTexts(tt.texts)
+			require.NoError(t, err)
+
+			dataType, _ := ArrowType(data)
+			b := array.NewBuilder(memory.DefaultAllocator, dataType)
+			defer b.Release()
+			for i := range tt.texts {
+				AppendArrow(b, data, i)
+			}
+			arr := b.NewArray()
+			defer arr.Release()
+			require.Equal(t, tt.want, arr.String())
+		})
+	}
+}
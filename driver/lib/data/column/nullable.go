@@ -62,6 +62,23 @@ func (n *NullableColumnData) ReadFromTexts(texts []string) (int, error) {
 	return n.innerColumnData.ReadFromTexts(textsCopy)
 }
 
+// ReadFromTextsWithNulls is ReadFromTexts with the NULL rows given by nulls instead of told by the texts,
+// for formats in which any text may be a value
+func (n *NullableColumnData) ReadFromTextsWithNulls(texts []string, nulls []bool) (int, error) {
+	textsCopy := make([]string, len(texts))
+	copy(textsCopy, texts)
+
+	dummyString := n.innerColumnData.ZeroString()
+	for i, null := range nulls {
+		if null {
+			n.mask[i] = 1
+			textsCopy[i] = dummyString
+		}
+	}
+
+	return n.innerColumnData.ReadFromTexts(textsCopy)
+}
+
 func (n *NullableColumnData) GetValue(row int) interface{} {
 	if n.mask[row] == 0 {
 		return n.innerColumnData.GetValue(row)
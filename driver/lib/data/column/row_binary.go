@@ -0,0 +1,249 @@
+package column
+
+import (
+	"net"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+// RowBinaryValueReader reads a value encoded in RowBinary, as a value accepted by ReadFromValues
+type RowBinaryValueReader func(decoder *ch_encoding.Decoder) (interface{}, error)
+
+// NewRowBinaryValueReader returns the reader of values of column data like data, which has at least one row.
+// Values of types with a fixed size, such as numbers, decimals and dates, are read into a column of one row
+// owned by the reader, as their RowBinary encoding is the same as the encoding of a column of one row.
+// Maps are read as map[interface{}]interface{} like other values of Map columns, which does not keep the order of keys.
+func NewRowBinaryValueReader(data CHColumnData) RowBinaryValueReader {
+	switch data := data.(type) {
+	case *NullableColumnData:
+		return newNullableRowBinaryReader(NewRowBinaryValueReader(data.innerColumnData))
+	case *LowCardinalityColumnData:
+		readKey := NewRowBinaryValueReader(data.generateKeys(1))
+		if data.isNullableCol {
+			return newNullableRowBinaryReader(readKey)
+		}
+		return readKey
+	case *ArrayColumnData:
+		readElem := NewRowBinaryValueReader(data.generateInnerData(1))
+		return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+			n, err := decoder.Uvarint()
+			if err != nil {
+				return nil, err
+			}
+			elems := make([]interface{}, n)
+			for i := range elems {
+				if elems[i], err = readElem(decoder); err != nil {
+					return nil, err
+				}
+			}
+			return elems, nil
+		}
+	case *MapColumnData:
+		readKey := NewRowBinaryValueReader(data.generateKeys(1))
+		readValue := NewRowBinaryValueReader(data.generateValues(1))
+		return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+			n, err := decoder.Uvarint()
+			if err != nil {
+				return nil, err
+			}
+			m := make(map[interface{}]interface{}, n)
+			for i := uint64(0); i < n; i++ {
+				key, err := readKey(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if m[key], err = readValue(decoder); err != nil {
+					return nil, err
+				}
+			}
+			return m, nil
+		}
+	case *TupleColumnData:
+		readElems := make([]RowBinaryValueReader, len(data.innerColumnsData))
+		for i, inner := range data.innerColumnsData {
+			readElems[i] = NewRowBinaryValueReader(inner)
+		}
+		return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+			elems := make([]interface{}, len(readElems))
+			var err error
+			for i, readElem := range readElems {
+				if elems[i], err = readElem(decoder); err != nil {
+					return nil, err
+				}
+			}
+			return elems, nil
+		}
+	case *StringColumnData:
+		return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+			return decoder.String()
+		}
+	case *BoolColumnData:
+		// GetValue of BoolColumnData is the raw uint8, ReadFromValues takes a bool
+		return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+			return decoder.Bool()
+		}
+	case *IPv4ColumnData:
+		return newIPRowBinaryReader(net.IPv4len)
+	case *IPv6ColumnData:
+		return newIPRowBinaryReader(net.IPv6len)
+	default:
+		return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+			if err := data.ReadFromDecoder(decoder); err != nil {
+				return nil, err
+			}
+			return data.GetValue(0), nil
+		}
+	}
+}
+
+// newIPRowBinaryReader reads each address into a new net.IP, GetValue of IP columns returns a slice of their buffer
+func newIPRowBinaryReader(size int) RowBinaryValueReader {
+	return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+		ip := make(net.IP, size)
+		if _, err := decoder.Read(ip); err != nil {
+			return nil, err
+		}
+		return ip, nil
+	}
+}
+
+func newNullableRowBinaryReader(readValue RowBinaryValueReader) RowBinaryValueReader {
+	return func(decoder *ch_encoding.Decoder) (interface{}, error) {
+		isNull, err := decoder.Bool()
+		if err != nil || isNull {
+			return nil, err
+		}
+		return readValue(decoder)
+	}
+}
+
+// WriteRowBinary writes the value of data at row encoded in RowBinary
+func WriteRowBinary(encoder *ch_encoding.Encoder, data CHColumnData, row int) error {
+	switch data := data.(type) {
+	case *NullableColumnData:
+		isNull := data.mask[row] != 0
+		if err := encoder.Bool(isNull); err != nil || isNull {
+			return err
+		}
+		return WriteRowBinary(encoder, data.innerColumnData, row)
+	case *LowCardinalityColumnData:
+		index := int(data.getIndex(row))
+		if data.isNullableCol {
+			isNull := index == 0
+			if err := encoder.Bool(isNull); err != nil || isNull {
+				return err
+			}
+		}
+		return WriteRowBinary(encoder, data.keys, index)
+	case *ArrayColumnData:
+		start, end := data.findOffset(row-1), data.findOffset(row)
+		if err := encoder.Uvarint(uint64(end - start)); err != nil {
+			return err
+		}
+		for i := start; i < end; i++ {
+			if err := WriteRowBinary(encoder, data.innerColumnData, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *MapColumnData:
+		start, end := data.findOffset(row-1), data.findOffset(row)
+		if err := encoder.Uvarint(uint64(end - start)); err != nil {
+			return err
+		}
+		for i := start; i < end; i++ {
+			if err := WriteRowBinary(encoder, data.keyColumnData, i); err != nil {
+				return err
+			}
+			if err := WriteRowBinary(encoder, data.valueColumnData, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *TupleColumnData:
+		for _, inner := range data.innerColumnsData {
+			if err := WriteRowBinary(encoder, inner, row); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *StringColumnData:
+		if err := encoder.Uvarint(uint64(len(data.raw[row]))); err != nil {
+			return err
+		}
+		_, err := encoder.Write(data.raw[row])
+		return err
+	case *BitMapColumnData:
+		if err := encoder.Uvarint(uint64(len(data.raw[row]))); err != nil {
+			return err
+		}
+		_, err := encoder.Write(data.raw[row])
+		return err
+	}
+
+	raw, ok := fixedSizeRaw(data)
+	if !ok {
+		return errors.ErrorfWithCaller("column data %T is not supported in RowBinary", data)
+	}
+	size := len(raw) / data.Len()
+	_, err := encoder.Write(raw[row*size : (row+1)*size])
+	return err
+}
+
+// fixedSizeRaw returns the encoded values of data if all values have the same size
+func fixedSizeRaw(data CHColumnData) ([]byte, bool) {
+	switch data := data.(type) {
+	case *Int8ColumnData:
+		return data.raw, true
+	case *Int16ColumnData:
+		return data.raw, true
+	case *Int32ColumnData:
+		return data.raw, true
+	case *Int64ColumnData:
+		return data.raw, true
+	case *UInt8ColumnData:
+		return data.raw, true
+	case *UInt16ColumnData:
+		return data.raw, true
+	case *UInt32ColumnData:
+		return data.raw, true
+	case *UInt64ColumnData:
+		return data.raw, true
+	case *Float32ColumnData:
+		return data.raw, true
+	case *Float64ColumnData:
+		return data.raw, true
+	case *BigIntColumnData:
+		return data.raw, true
+	case *DecimalColumnData:
+		return data.raw, true
+	case *BoolColumnData:
+		return data.raw, true
+	case *FixedStringColumnData:
+		return data.raw, true
+	case *DateColumnData:
+		return data.raw, true
+	case *Date32ColumnData:
+		return data.raw, true
+	case *DateTimeColumnData:
+		return data.raw, true
+	case *DateTime64ColumnData:
+		return data.raw, true
+	case *UUIDColumnData:
+		return data.raw, true
+	case *IPv4ColumnData:
+		return data.raw, true
+	case *IPv6ColumnData:
+		return data.raw, true
+	case *Enum8ColumnData:
+		return data.raw, true
+	case *Enum16ColumnData:
+		return data.raw, true
+	case *NothingColumnData:
+		return data.raw, true
+	case *TimeColumnData:
+		return data.baseColumn.raw, true
+	}
+	return nil, false
+}
@@ -183,7 +183,22 @@ func (t *TupleColumnData) Zero() interface{} {
 }
 
 func (t *TupleColumnData) ZeroString() string {
-	return emptyTuple
+	if t.Len() == 0 {
+		return emptyTuple
+	}
+
+	var builder strings.Builder
+
+	builder.WriteByte(roundOpenBracket)
+	for i, innerColumnData := range t.innerColumnsData {
+		if i != 0 {
+			builder.WriteString(listSeparator)
+		}
+
+		builderWriteKind(&builder, innerColumnData.ZeroString(), reflect.ValueOf(innerColumnData.Zero()).Type().Kind())
+	}
+	builder.WriteByte(roundCloseBracket)
+	return builder.String()
 }
 
 func (t *TupleColumnData) Len() int {
@@ -403,3 +403,14 @@ func TestTupleColumnData_CloseFail(t *testing.T) {
 	_, err := GenerateColumnDataFactory("Tuple(Unsupported)")
 	require.Error(t, err)
 }
+
+func TestTupleColumnData_ZeroString(t *testing.T) {
+	tuple := MustMakeColumnData("Tuple(Int32, String, Array(UInt8))", 1)
+	require.Equal(t, "(0, '', [])", tuple.ZeroString())
+
+	// the zero string can be read, e.g. for nulls of Nullable(Tuple)
+	nullable := MustMakeColumnData("Nullable(Tuple(Int32, String))", 2)
+	_, err := nullable.ReadFromTexts([]string{"(1, 'a')", "NULL"})
+	require.NoError(t, err)
+	require.Nil(t, nullable.GetValue(1))
+}
@@ -288,6 +288,14 @@ var Default = map[string]interface{}{
 	"format_csv_delimiter":            ",",
 	"format_csv_write_utf8_with_bom":  false,
 
+	"format_csv_quote":                                  "",
+	"format_csv_null_representation":                    "\\N",
+	"input_format_csv_skip_first_lines":                 uint64(0),
+	"input_format_csv_trim_whitespaces":                 true,
+	"input_format_csv_comment_char":                     "",
+	"input_format_csv_allow_variable_number_of_columns": false,
+	"output_format_csv_crlf_end_of_line":                false,
+
 	"format_protobuf_enable_multiple_message": true,
 	"format_protobuf_default_length_parser":   false,
 	"rm_zknodes_while_alter_engine":           false,
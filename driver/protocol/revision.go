@@ -3,6 +3,7 @@ package protocol
 const (
 	VERSION_REVISION                                = 54428
 	DBMS_MIN_REVISION_WITH_CLIENT_INFO              = 54032
+	DBMS_MIN_REVISION_WITH_TABLES_STATUS            = 54226
 	DBMS_MIN_REVISION_WITH_SERVER_TIMEZONE          = 54058
 	DBMS_MIN_REVISION_WITH_QUOTA_KEY_IN_CLIENT_INFO = 54060
 	DBMS_MIN_REVISION_WITH_SERVER_DISPLAY_NAME      = 54372
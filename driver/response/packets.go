@@ -99,7 +99,7 @@ func WritePacket(p Packet, encoder *ch_encoding.Encoder, compress bool, revision
 			return err
 		}
 		return writeExtremesPacket(p, encoder, compress)
-	case *tableStatusPacket:
+	case *TablesStatusPacket:
 		if err = encoder.Uvarint(protocol.ServerTablesStatus); err != nil {
 			return err
 		}
@@ -140,9 +140,9 @@ func TestReadPacket(t *testing.T) {
 				binary.LittleEndian.PutUint64(b, protocol.ServerTablesStatus)
 				decoder := ch_encoding.NewDecoder(bytepool.NewZReader(pointer.IoReader(bytes.NewReader(b)), 100, 100))
 				p, err := ReadPacket(decoder, false, 0)
-				require.Equal(t, err, ErrTableStatusNotSupported)
-				require.IsType(t, &tableStatusPacket{}, p)
-				require.Equal(t, "!tableStatusPacket: NotSupported", p.String())
+				require.NoError(t, err)
+				require.Equal(t, &TablesStatusPacket{Statuses: []*TableStatus{}}, p)
+				require.Equal(t, "Tables Status: []", p.String())
 				require.NoError(t, p.Close())
 			},
 		},
@@ -342,8 +342,18 @@ func TestWritePacket(t *testing.T) {
 			test: func(t *testing.T) {
 				var buffer bytes.Buffer
 				encoder := ch_encoding.NewEncoder(&buffer)
-				err := WritePacket(&tableStatusPacket{}, encoder, false, 0)
-				require.Equal(t, err, ErrTableStatusNotSupported)
+				p := &TablesStatusPacket{Statuses: []*TableStatus{
+					{TableName: TableName{Database: "db", Table: "local"}},
+					{TableName: TableName{Database: "db", Table: "replicated"}, IsReplicated: true, AbsoluteDelay: 300},
+				}}
+				require.NoError(t, WritePacket(p, encoder, false, 0))
+				require.NoError(t, encoder.Flush())
+
+				decoder := ch_encoding.NewDecoder(bytepool.NewZReader(pointer.IoReader(&buffer), 100, 100))
+				read, err := ReadPacket(decoder, false, 0)
+				require.NoError(t, err)
+				require.Equal(t, p, read)
+				require.Equal(t, "Tables Status: [db.local, db.replicated (delay: 300s)]", read.String())
 			},
 		},
 		{
@@ -6,6 +6,13 @@ import (
 	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
 )
 
+// TableColumnsPacket describes the columns of the table of an insert, sent before the sample block.
+// Description lists a column per line after a header, e.g.
+//
+//	columns format version: 1
+//	2 columns:
+//	`id` UInt64
+//	`name` String	DEFAULT	\'unknown\'
 type TableColumnsPacket struct {
 	Table       string
 	Description string
@@ -24,6 +31,79 @@ func (s *TableColumnsPacket) String() string {
 
 func (s *TableColumnsPacket) packet() {}
 
+// ColumnDefaults returns the expressions of the columns with a DEFAULT in Description by name,
+// columns which are MATERIALIZED or ALIAS cannot be inserted and are not returned
+func (s *TableColumnsPacket) ColumnDefaults() map[string]string {
+	defaults := make(map[string]string)
+	for _, line := range strings.Split(s.Description, "\n") {
+		name, rest, ok := cutBackQuoted(line)
+		if !ok {
+			continue
+		}
+		// type, kind of default and its expression, followed by comment, codec and ttl if any
+		fields := strings.Split(strings.TrimPrefix(rest, " "), "\t")
+		if len(fields) < 3 || fields[1] != "DEFAULT" {
+			continue
+		}
+		defaults[name] = unescape(fields[2])
+	}
+	return defaults
+}
+
+// cutBackQuoted returns the back quoted name at the start of line and the rest of line after it
+func cutBackQuoted(line string) (name, rest string, ok bool) {
+	if !strings.HasPrefix(line, "`") {
+		return "", "", false
+	}
+	var sb strings.Builder
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				sb.WriteByte(line[i])
+			}
+		case '`':
+			return sb.String(), line[i+1:], true
+		default:
+			sb.WriteByte(line[i])
+		}
+	}
+	return "", "", false
+}
+
+// unescape reverts the escaping of backslashes, quotes and control characters in s
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case '0':
+			sb.WriteByte(0)
+		default:
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
 func readTableColumnsPacket(decoder *ch_encoding.Decoder) (*TableColumnsPacket, error) {
 	var (
 		err                error
@@ -0,0 +1,29 @@
+package response
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableColumnsPacket_ColumnDefaults(t *testing.T) {
+	p := &TableColumnsPacket{
+		Table: "sample_table",
+		Description: "columns format version: 1\n" +
+			"6 columns:\n" +
+			"`id` UInt64\n" +
+			"`name` String\tDEFAULT\t\\'it\\\\\\'s\\'\n" +
+			"`score` Decimal(10, 2)\tDEFAULT\t-1.5\tCOMMENT \\'score of user\\'\n" +
+			"`created` DateTime\tDEFAULT\tnow()\n" +
+			"`day` Date\tMATERIALIZED\ttoDate(created)\n" +
+			"`back\\`quoted` Int32\tDEFAULT\t1\n",
+	}
+
+	require.Equal(t, map[string]string{
+		"name":        `'it\'s'`,
+		"score":       "-1.5",
+		"created":     "now()",
+		"back`quoted": "1",
+	}, p.ColumnDefaults())
+	require.Empty(t, (&TableColumnsPacket{}).ColumnDefaults())
+}
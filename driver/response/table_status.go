@@ -1,29 +1,108 @@
 package response
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
-	"github.com/bytehouse-cloud/driver-go/errors"
 )
 
-type tableStatusPacket struct{}
+// TableName is the name of a table of which the status is requested with ClientTablesStatusRequest
+type TableName struct {
+	Database string
+	Table    string
+}
 
-func (t *tableStatusPacket) Close() error {
-	return nil
+func (t TableName) String() string {
+	return t.Database + "." + t.Table
 }
 
-func (t *tableStatusPacket) String() string {
-	return "!tableStatusPacket: NotSupported"
+// TableStatus is the status of a table on the server
+type TableStatus struct {
+	TableName
+	IsReplicated bool
+	// AbsoluteDelay is the replication delay of the table in seconds, only set if IsReplicated
+	AbsoluteDelay uint32
 }
 
-func (t *tableStatusPacket) packet() {
+// TablesStatusPacket is the answer of the server to ClientTablesStatusRequest,
+// holding the status of each of the requested tables which exist on the server
+type TablesStatusPacket struct {
+	Statuses []*TableStatus
+}
+
+func (t *TablesStatusPacket) Close() error {
+	return nil
 }
 
-func readTableStatusPacket(decoder *ch_encoding.Decoder) (*tableStatusPacket, error) {
-	return nil, ErrTableStatusNotSupported
+func (t *TablesStatusPacket) String() string {
+	var buf strings.Builder
+	buf.WriteString("Tables Status: [")
+	for i, s := range t.Statuses {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(s.TableName.String())
+		if s.IsReplicated {
+			buf.WriteString(fmt.Sprintf(" (delay: %vs)", s.AbsoluteDelay))
+		}
+	}
+	buf.WriteByte(squareCloseBracket)
+	return buf.String()
 }
 
-func writeTableStatusPacket(tsPacket *tableStatusPacket, encoder *ch_encoding.Encoder) error {
-	return ErrTableStatusNotSupported
+func (t *TablesStatusPacket) packet() {
 }
 
-var ErrTableStatusNotSupported = errors.ErrorfWithCaller("table status packet unsupported")
+func readTableStatusPacket(decoder *ch_encoding.Decoder) (*TablesStatusPacket, error) {
+	n, err := decoder.Uvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	tablesStatus := &TablesStatusPacket{Statuses: make([]*TableStatus, n)}
+	for i := range tablesStatus.Statuses {
+		var s TableStatus
+		if s.Database, err = decoder.String(); err != nil {
+			return nil, err
+		}
+		if s.Table, err = decoder.String(); err != nil {
+			return nil, err
+		}
+		if s.IsReplicated, err = decoder.Bool(); err != nil {
+			return nil, err
+		}
+		if s.IsReplicated {
+			delay, err := decoder.Uvarint()
+			if err != nil {
+				return nil, err
+			}
+			s.AbsoluteDelay = uint32(delay)
+		}
+		tablesStatus.Statuses[i] = &s
+	}
+	return tablesStatus, nil
+}
+
+func writeTableStatusPacket(tsPacket *TablesStatusPacket, encoder *ch_encoding.Encoder) error {
+	if err := encoder.Uvarint(uint64(len(tsPacket.Statuses))); err != nil {
+		return err
+	}
+	for _, s := range tsPacket.Statuses {
+		if err := encoder.String(s.Database); err != nil {
+			return err
+		}
+		if err := encoder.String(s.Table); err != nil {
+			return err
+		}
+		if err := encoder.Bool(s.IsReplicated); err != nil {
+			return err
+		}
+		if s.IsReplicated {
+			if err := encoder.Uvarint(uint64(s.AbsoluteDelay)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
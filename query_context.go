@@ -38,6 +38,18 @@ func NewQueryContext(ctx context.Context) *QueryContext {
 	}
 }
 
+// WithContext returns a copy of q with ctx as its parent context instead, keeping the settings and query ID of q
+func (q *QueryContext) WithContext(ctx context.Context) *QueryContext {
+	return &QueryContext{
+		Context:               ctx,
+		querySettings:         copySettings(q.querySettings),
+		clientSettings:        copySettings(q.clientSettings),
+		persistentConnConfigs: copySettings(q.persistentConnConfigs),
+		temporaryConnConfigs:  copySettings(q.temporaryConnConfigs),
+		queryID:               q.queryID,
+	}
+}
+
 // AddQuerySetting adds a query setting to the query context which will be applied for the query
 func (q *QueryContext) AddQuerySetting(name string, value interface{}) error {
 	v, err := settings.SettingToValue(name, value)
@@ -103,6 +115,14 @@ func clientSettingToValue(name string, value interface{}) (interface{}, error) {
 	return value, nil
 }
 
+func copySettings(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
 func isType(a, b interface{}) bool {
 	return reflect.TypeOf(a) == reflect.TypeOf(b)
 }
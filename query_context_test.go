@@ -3,6 +3,7 @@ package bytehouse
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -24,6 +25,35 @@ func TestNewQueryContext(t *testing.T) {
 				require.Equal(t, settings["log_queries"], true)
 			},
 		},
+		{
+			name: "Can add client setting of matching type only",
+			test: func(t *testing.T) {
+				qc := NewQueryContext(context.Background())
+				require.NoError(t, qc.AddClientSetting(InsertBlockBytes, 1<<20))
+				require.NoError(t, qc.AddClientSetting(InsertFlushInterval, time.Second))
+				require.Error(t, qc.AddClientSetting(InsertFlushInterval, 1))
+				require.Equal(t, time.Second, qc.GetClientSettings()[InsertFlushInterval])
+			},
+		},
+		{
+			name: "Can replace parent context",
+			test: func(t *testing.T) {
+				parent, cancel := context.WithCancel(context.Background())
+				qc := NewQueryContext(parent)
+				require.NoError(t, qc.AddQuerySetting("log_queries", "true"))
+				qc.SetQueryID("id")
+				cancel()
+
+				detached := qc.WithContext(context.Background())
+				require.NoError(t, detached.Err())
+				require.Error(t, qc.Err())
+				require.Equal(t, "id", detached.GetQueryID())
+				require.Equal(t, true, detached.GetQuerySettings()["log_queries"])
+
+				require.NoError(t, detached.AddQuerySetting("log_queries", "false"))
+				require.Equal(t, true, qc.GetQuerySettings()["log_queries"], "settings are copied")
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, tt.test)
@@ -0,0 +1,102 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bytehouse-cloud/driver-go"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+const (
+	maxExecutionTimeSetting               = "max_execution_time"
+	executionSpeedCheckTimeSetting        = "timeout_before_checking_execution_speed"
+	timeoutExceededCode            uint32 = 159
+)
+
+// TimeoutError is the error of a query which exceeded either the deadline of its ctx
+// or the max_execution_time of the server, returned if bytehouse.DeadlineMaxExecutionTime is enabled
+type TimeoutError struct {
+	// Server is true if the server stopped the query at max_execution_time,
+	// false if the query was cancelled at the ctx deadline
+	Server bool
+	// Err is the exception of the server if Server, otherwise the error of ctx
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Server {
+		return fmt.Sprintf("%s: query exceeded server max_execution_time: %v", errors.DriverGoErrorPrefix, e.Err)
+	}
+	return fmt.Sprintf("%s: query exceeded ctx deadline: %v", errors.DriverGoErrorPrefix, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout is always true, like that of net.Error
+func (e *TimeoutError) Timeout() bool {
+	return true
+}
+
+// deadlineSettings returns max_execution_time, and timeout_before_checking_execution_speed if set, from the
+// remaining deadline of ctx if bytehouse.DeadlineMaxExecutionTime is enabled, otherwise nil.
+// current is max_execution_time already set for the query, which is kept if it is lower.
+func deadlineSettings(ctx context.Context, current interface{}) map[string]interface{} {
+	if !resolveClientSetting(ctx, bytehouse.DeadlineMaxExecutionTime).(bool) {
+		return nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+
+	seconds := durationSeconds(time.Until(deadline))
+	if current, ok := current.(uint64); ok && current != 0 && current < seconds {
+		seconds = current
+	}
+	result := map[string]interface{}{
+		maxExecutionTimeSetting: seconds,
+	}
+	if speedCheck := resolveClientSetting(ctx, bytehouse.DeadlineExecutionSpeedCheck).(time.Duration); speedCheck > 0 {
+		result[executionSpeedCheckTimeSetting] = durationSeconds(speedCheck)
+	}
+	return result
+}
+
+// durationSeconds returns d in whole seconds, at least 1 since 0 disables the time settings of the server
+func durationSeconds(d time.Duration) uint64 {
+	if d < time.Second {
+		return 1
+	}
+	return uint64(d / time.Second)
+}
+
+// timeoutErrorMapper returns the mapping of the errors of the query of ctx to *TimeoutError
+// if bytehouse.DeadlineMaxExecutionTime is enabled, otherwise nil
+func timeoutErrorMapper(ctx context.Context) func(error) error {
+	if !resolveClientSetting(ctx, bytehouse.DeadlineMaxExecutionTime).(bool) {
+		return nil
+	}
+	return func(err error) error {
+		return toTimeoutError(ctx, err)
+	}
+}
+
+// toTimeoutError returns err as *TimeoutError if it is the TIMEOUT_EXCEEDED exception of the server
+// or ctx exceeded its deadline, otherwise err
+func toTimeoutError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if exception, ok := err.(*response.ExceptionPacket); ok && exception.Code == timeoutExceededCode {
+		return &TimeoutError{Server: true, Err: exception}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return &TimeoutError{Err: fmt.Errorf("%w, %v", context.DeadlineExceeded, err)}
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	bytehouse "github.com/bytehouse-cloud/driver-go"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+func TestDeadlineSettings(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	qc := bytehouse.NewQueryContext(ctx)
+	require.Nil(t, deadlineSettings(qc, nil), "disabled by default")
+
+	require.NoError(t, qc.AddClientSetting(bytehouse.DeadlineMaxExecutionTime, true))
+	require.Nil(t, deadlineSettings(bytehouse.NewQueryContext(context.Background()), nil), "no deadline")
+
+	m := deadlineSettings(qc, nil)
+	seconds := m[maxExecutionTimeSetting].(uint64)
+	require.True(t, seconds > 590 && seconds <= 600, "got %v", seconds)
+	require.NotContains(t, m, executionSpeedCheckTimeSetting)
+
+	require.Equal(t, uint64(30), deadlineSettings(qc, uint64(30))[maxExecutionTimeSetting], "lower setting kept")
+	require.Equal(t, seconds, deadlineSettings(qc, uint64(0))[maxExecutionTimeSetting])
+
+	require.NoError(t, qc.AddClientSetting(bytehouse.DeadlineExecutionSpeedCheck, 15*time.Second))
+	require.Equal(t, uint64(15), deadlineSettings(qc, nil)[executionSpeedCheckTimeSetting])
+
+	short, cancelShort := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelShort()
+	shortQC := bytehouse.NewQueryContext(short)
+	require.NoError(t, shortQC.AddClientSetting(bytehouse.DeadlineMaxExecutionTime, true))
+	require.Equal(t, uint64(1), deadlineSettings(shortQC, nil)[maxExecutionTimeSetting])
+}
+
+func TestToTimeoutError(t *testing.T) {
+	serverTimeout := &response.ExceptionPacket{Code: timeoutExceededCode, Name: "DB::Exception", Message: "Timeout exceeded"}
+	other := &response.ExceptionPacket{Code: 394, Name: "DB::Exception", Message: "Query was cancelled"}
+
+	ctx := context.Background()
+	require.NoError(t, toTimeoutError(ctx, nil))
+	require.Equal(t, other, toTimeoutError(ctx, other))
+
+	err := toTimeoutError(ctx, serverTimeout)
+	var timeoutErr *TimeoutError
+	require.True(t, errors.As(err, &timeoutErr))
+	require.True(t, timeoutErr.Server)
+	require.Equal(t, serverTimeout, timeoutErr.Unwrap())
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	err = toTimeoutError(expired, other)
+	require.True(t, errors.As(err, &timeoutErr))
+	require.False(t, timeoutErr.Server)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.Contains(t, err.Error(), "Query was cancelled")
+
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+	require.Equal(t, other, toTimeoutError(cancelled, other), "only deadlines are timeouts")
+}
+
+func TestTimeoutErrorMapper(t *testing.T) {
+	qc := bytehouse.NewQueryContext(context.Background())
+	require.Nil(t, timeoutErrorMapper(qc))
+
+	require.NoError(t, qc.AddClientSetting(bytehouse.DeadlineMaxExecutionTime, true))
+	mapErr := timeoutErrorMapper(qc)
+	require.NotNil(t, mapErr)
+
+	qr := &QueryResult{err: &response.ExceptionPacket{Code: timeoutExceededCode}, mapErr: mapErr}
+	var timeoutErr *TimeoutError
+	require.True(t, errors.As(qr.Exception(), &timeoutErr))
+	require.True(t, timeoutErr.Server)
+}
@@ -212,6 +212,22 @@ func makeConnConfigs(host string, urlValues url.Values, logf func(s string, i ..
 		opts = append(opts, conn.OptionSendTimeout(settings.DBMS_DEFAULT_SEND_TIMEOUT_SEC))
 	}
 
+	if keepaliveInterval := urlValues.Get(param.KEEPALIVE_INTERVAL); keepaliveInterval != "" {
+		duration, err := parseUint(keepaliveInterval)
+		if err != nil {
+			return nil, fmt.Errorf(ErrParseParamFmt, param.KEEPALIVE_INTERVAL, duration, keepaliveInterval, err)
+		}
+		opts = append(opts, conn.OptionKeepaliveInterval(duration))
+	}
+
+	if keepaliveTimeout := urlValues.Get(param.KEEPALIVE_TIMEOUT); keepaliveTimeout != "" {
+		duration, err := parseUint(keepaliveTimeout)
+		if err != nil {
+			return nil, fmt.Errorf(ErrParseParamFmt, param.KEEPALIVE_TIMEOUT, duration, keepaliveTimeout, err)
+		}
+		opts = append(opts, conn.OptionKeepaliveTimeout(duration))
+	}
+
 	return conn.NewConnConfig(opts...)
 }
 
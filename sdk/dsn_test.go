@@ -47,6 +47,27 @@ func TestParseDSN(t *testing.T) {
 				conn.OptionHostName(":"),
 			},
 		},
+		{
+			name: "Can parse keepalive",
+			args: args{
+				dsn: "tcp://localhost:9000?keepalive_interval=30&keepalive_timeout=5",
+			},
+			want: &Config{
+				databaseName:   "",
+				authentication: conn.NewPasswordAuthentication("default", ""),
+				querySettings:  map[string]interface{}{},
+			},
+			wantOpts: []conn.OptionConfig{
+				conn.OptionHostName("localhost:9000"),
+				conn.OptionKeepaliveInterval(30),
+				conn.OptionKeepaliveTimeout(5),
+			},
+		},
+		{
+			name:    "Cannot parse invalid keepalive interval",
+			args:    args{dsn: "tcp://localhost:9000?keepalive_interval=abc"},
+			wantErr: true,
+		},
 		{
 			name: "Can accept region with no volcano flag false and map accordingly",
 			args: args{
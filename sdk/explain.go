@@ -0,0 +1,312 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+const (
+	explainPlanPrefix     = "EXPLAIN json = 1, indexes = 1 "
+	explainEstimatePrefix = "EXPLAIN ESTIMATE "
+)
+
+// PlanNode is a step of the query plan returned by ExplainPlan
+type PlanNode struct {
+	// Name is the type of the step, e.g. Expression, Filter or ReadFromMergeTree
+	Name        string
+	Description string
+	// EstimatedRows is the estimated number of rows of the table read by a step, in the whole query,
+	// only set if EstimateKnown
+	EstimatedRows uint64
+	// EstimateKnown is false if the step does not read a table, the server does not estimate the rows
+	// read by the query or the table of the step is not found in the estimate
+	EstimateKnown bool
+	// EstimateError is the exception of EXPLAIN ESTIMATE if the server failed to estimate the rows read by the query,
+	// only set on the root step
+	EstimateError error
+	// Indexes are the indexes used by a step reading a MergeTree table
+	Indexes  []*PlanIndex
+	Children []*PlanNode
+}
+
+// PlanIndex is an index used by a step of the query plan, with the parts and granules selected with it
+type PlanIndex struct {
+	Type             string
+	Keys             []string
+	Condition        string
+	InitialParts     uint64
+	SelectedParts    uint64
+	InitialGranules  uint64
+	SelectedGranules uint64
+}
+
+// FullScan tells if n reads a table of which none of the granules are excluded by an index
+func (n *PlanNode) FullScan() bool {
+	if !strings.HasPrefix(n.Name, "ReadFrom") {
+		return false
+	}
+	for _, index := range n.Indexes {
+		if index.SelectedGranules < index.InitialGranules {
+			return false
+		}
+	}
+	return true
+}
+
+// Walk calls fn with n and each of its descendants, parents before children
+func (n *PlanNode) Walk(fn func(node *PlanNode)) {
+	fn(n)
+	for _, child := range n.Children {
+		child.Walk(fn)
+	}
+}
+
+// ExplainPlan returns the query plan of query as a tree of steps, read with EXPLAIN json = 1, indexes = 1.
+// If the server sends the plan in a QueryPlanPacket or an AggregateQueryPlanPacket with the result,
+// it is read from the packet instead, see ParsePlanPacket.
+// The rows read from each table are estimated with EXPLAIN ESTIMATE, if the server fails to estimate them
+// the plan is returned with EstimateError set on its root, see EstimateKnown.
+//
+// Unlike the plans sent by the server, ClientQueryPlan is not sent to request the plan: its payload is a serialized
+// plan fragment which a server sends to another to execute, not a query to explain.
+func (g *Gateway) ExplainPlan(ctx context.Context, query string) (*PlanNode, error) {
+	var lines []string
+	meta, err := g.queryRowsAndMeta(ctx, explainPlanPrefix+query, func(row []interface{}) error {
+		var line string
+		if err := scanRow(row, &line); err != nil {
+			return err
+		}
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	root, err := planOfResult(lines, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	var estimates []*tableEstimate
+	err = g.queryRows(ctx, explainEstimatePrefix+query, func(row []interface{}) error {
+		var (
+			estimate     tableEstimate
+			parts, marks uint64
+		)
+		if err := scanRow(row, &estimate.database, &estimate.table, &parts, &estimate.rows, &marks); err != nil {
+			return err
+		}
+		estimates = append(estimates, &estimate)
+		return nil
+	})
+	if _, ok := err.(*response.ExceptionPacket); ok {
+		// not all servers support EXPLAIN ESTIMATE, steps are left with EstimateKnown false
+		root.EstimateError = err
+		return root, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	setEstimatedRows(root, estimates)
+	return root, nil
+}
+
+// planOfResult returns the plan of the packets of meta if any, otherwise the plan in lines
+func planOfResult(lines []string, meta []response.Packet) (*PlanNode, error) {
+	for _, packet := range meta {
+		switch packet.(type) {
+		case *response.QueryPlanPacket, *response.AggregateQueryPlanPacket:
+			return ParsePlanPacket(packet)
+		}
+	}
+	return parsePlan(strings.Join(lines, "\n"))
+}
+
+// ParsePlanPacket returns the plan of a QueryPlanPacket or an AggregateQueryPlanPacket, of which the lines are
+// either the JSON of EXPLAIN json = 1 or the text of EXPLAIN. Indexes are only read from JSON.
+func ParsePlanPacket(packet response.Packet) (*PlanNode, error) {
+	var plans []string
+	switch packet := packet.(type) {
+	case *response.QueryPlanPacket:
+		plans = packet.Plans
+	case *response.AggregateQueryPlanPacket:
+		plans = packet.Plans
+	default:
+		return nil, errors.ErrorfWithCaller("not a query plan packet: %T", packet)
+	}
+
+	s := strings.TrimSpace(strings.Join(plans, "\n"))
+	switch {
+	case strings.HasPrefix(s, "["):
+		return parsePlan(s)
+	case strings.HasPrefix(s, "{"):
+		return parsePlan("[" + s + "]")
+	}
+	return parseTextPlan(s)
+}
+
+type jsonPlanIndex struct {
+	Type             string   `json:"Type"`
+	Keys             []string `json:"Keys"`
+	Condition        string   `json:"Condition"`
+	InitialParts     uint64   `json:"Initial Parts"`
+	SelectedParts    uint64   `json:"Selected Parts"`
+	InitialGranules  uint64   `json:"Initial Granules"`
+	SelectedGranules uint64   `json:"Selected Granules"`
+}
+
+type jsonPlanNode struct {
+	NodeType    string           `json:"Node Type"`
+	Description string           `json:"Description"`
+	Indexes     []*jsonPlanIndex `json:"Indexes"`
+	Plans       []*jsonPlanNode  `json:"Plans"`
+}
+
+// parsePlan parses the output of EXPLAIN json = 1, which is an array holding the plan
+func parsePlan(s string) (*PlanNode, error) {
+	var plans []struct {
+		Plan *jsonPlanNode `json:"Plan"`
+	}
+	if err := json.Unmarshal([]byte(s), &plans); err != nil {
+		return nil, errors.ErrorfWithCaller("invalid query plan: %v", err)
+	}
+	if len(plans) == 0 || plans[0].Plan == nil {
+		return nil, errors.ErrorfWithCaller("empty query plan")
+	}
+	return toPlanNode(plans[0].Plan), nil
+}
+
+func toPlanNode(j *jsonPlanNode) *PlanNode {
+	n := &PlanNode{
+		Name:        j.NodeType,
+		Description: j.Description,
+	}
+	for _, index := range j.Indexes {
+		n.Indexes = append(n.Indexes, &PlanIndex{
+			Type:             index.Type,
+			Keys:             index.Keys,
+			Condition:        index.Condition,
+			InitialParts:     index.InitialParts,
+			SelectedParts:    index.SelectedParts,
+			InitialGranules:  index.InitialGranules,
+			SelectedGranules: index.SelectedGranules,
+		})
+	}
+	for _, child := range j.Plans {
+		n.Children = append(n.Children, toPlanNode(child))
+	}
+	return n
+}
+
+// textPlanIndent is the indentation of the children of a step in the text of EXPLAIN
+const textPlanIndent = 2
+
+// parseTextPlan parses the output of EXPLAIN, a step per line indented by its depth, such as
+// "ReadFromMergeTree (default.events)". Details of a step, such as "Indexes:" and the lines indented below it,
+// are skipped.
+func parseTextPlan(s string) (*PlanNode, error) {
+	var (
+		root *PlanNode
+		// steps are the last step read at each depth
+		steps []*PlanNode
+		// detailDepth is the depth of the details being skipped, -1 if none
+		detailDepth = -1
+	)
+	for _, line := range strings.Split(s, "\n") {
+		text := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		depth := (len(line) - len(text)) / textPlanIndent
+		if detailDepth >= 0 && depth > detailDepth {
+			continue
+		}
+		detailDepth = -1
+
+		name, description := cutStep(strings.TrimSpace(text))
+		if strings.Contains(name, ":") {
+			detailDepth = depth
+			continue
+		}
+		n := &PlanNode{Name: name, Description: description}
+		switch {
+		case depth == 0 && root == nil:
+			root = n
+		case depth == 0:
+			return nil, errors.ErrorfWithCaller("invalid query plan: more than one root step: %q", line)
+		case depth > len(steps):
+			return nil, errors.ErrorfWithCaller("invalid query plan: step without parent: %q", line)
+		default:
+			parent := steps[depth-1]
+			parent.Children = append(parent.Children, n)
+		}
+		steps = append(steps[:depth], n)
+	}
+	if root == nil {
+		return nil, errors.ErrorfWithCaller("empty query plan")
+	}
+	return root, nil
+}
+
+// cutStep returns the name of a step in the text of EXPLAIN, and its description in parentheses if any
+func cutStep(text string) (name, description string) {
+	i := strings.IndexByte(text, '(')
+	if i < 0 || !strings.HasSuffix(text, ")") {
+		return text, ""
+	}
+	return strings.TrimSpace(text[:i]), text[i+1 : len(text)-1]
+}
+
+// tableEstimate is a row of EXPLAIN ESTIMATE, the rows read from a table by a query
+type tableEstimate struct {
+	database string
+	table    string
+	rows     uint64
+}
+
+// setEstimatedRows sets the estimated rows of each table to the steps reading it, see estimateOf
+func setEstimatedRows(root *PlanNode, estimates []*tableEstimate) {
+	root.Walk(func(n *PlanNode) {
+		if !strings.HasPrefix(n.Name, "ReadFrom") {
+			return
+		}
+		n.EstimatedRows, n.EstimateKnown = estimateOf(n.Description, estimates)
+	})
+}
+
+// estimateOf returns the estimated rows of the table read by a step of the description, which starts with the
+// name of the table, such as "default.events", "`default`.`events`" or "events (alias)".
+// A table named without its database is matched only if no table of another database has the same name.
+// The rows of a table estimated more than once are summed.
+func estimateOf(description string, estimates []*tableEstimate) (uint64, bool) {
+	name := strings.ReplaceAll(description, "`", "")
+	if i := strings.IndexAny(name, " ("); i >= 0 {
+		name = name[:i]
+	}
+	database, table := "", name
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		database, table = name[:i], name[i+1:]
+	}
+
+	var (
+		rows      uint64
+		found     bool
+		databases = make(map[string]bool)
+	)
+	for _, estimate := range estimates {
+		if estimate.table != table || (database != "" && estimate.database != database) {
+			continue
+		}
+		rows += estimate.rows
+		found = true
+		databases[estimate.database] = true
+	}
+	if len(databases) > 1 { // ambiguous without the database
+		return 0, false
+	}
+	return rows, found
+}
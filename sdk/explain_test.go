@@ -0,0 +1,173 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+const samplePlan = `[
+  {
+    "Plan": {
+      "Node Type": "Expression",
+      "Description": "(Projection + Before ORDER BY)",
+      "Plans": [
+        {
+          "Node Type": "Join",
+          "Plans": [
+            {
+              "Node Type": "ReadFromMergeTree",
+              "Description": "default.events",
+              "Indexes": [
+                {
+                  "Type": "PrimaryKey",
+                  "Keys": ["id"],
+                  "Condition": "(id in [10, +Inf))",
+                  "Initial Parts": 4,
+                  "Selected Parts": 2,
+                  "Initial Granules": 100,
+                  "Selected Granules": 20
+                }
+              ]
+            },
+            {
+              "Node Type": "ReadFromMergeTree",
+              "Description": "default.users",
+              "Indexes": [
+                {
+                  "Type": "PrimaryKey",
+                  "Condition": "true",
+                  "Initial Parts": 1,
+                  "Selected Parts": 1,
+                  "Initial Granules": 8,
+                  "Selected Granules": 8
+                }
+              ]
+            }
+          ]
+        }
+      ]
+    }
+  }
+]`
+
+func TestParsePlan(t *testing.T) {
+	root, err := parsePlan(samplePlan)
+	require.NoError(t, err)
+	require.Equal(t, "Expression", root.Name)
+	require.Equal(t, "(Projection + Before ORDER BY)", root.Description)
+	require.Len(t, root.Children, 1)
+	join := root.Children[0]
+	require.Equal(t, "Join", join.Name)
+	require.Len(t, join.Children, 2)
+
+	events, users := join.Children[0], join.Children[1]
+	require.Equal(t, []*PlanIndex{{
+		Type:             "PrimaryKey",
+		Keys:             []string{"id"},
+		Condition:        "(id in [10, +Inf))",
+		InitialParts:     4,
+		SelectedParts:    2,
+		InitialGranules:  100,
+		SelectedGranules: 20,
+	}}, events.Indexes)
+
+	var fullScans []string
+	root.Walk(func(n *PlanNode) {
+		if n.FullScan() {
+			fullScans = append(fullScans, n.Description)
+		}
+	})
+	require.Equal(t, []string{"default.users"}, fullScans)
+	require.True(t, (&PlanNode{Name: "ReadFromMergeTree"}).FullScan(), "no index")
+
+	setEstimatedRows(root, []*tableEstimate{
+		{database: "default", table: "events", rows: 163840},
+		{database: "other", table: "users", rows: 1},
+	})
+	require.True(t, events.EstimateKnown)
+	require.Equal(t, uint64(163840), events.EstimatedRows)
+	require.False(t, users.EstimateKnown, "table of another database")
+	require.False(t, root.EstimateKnown, "not reading a table")
+
+	_, err = parsePlan("[]")
+	require.Error(t, err)
+	_, err = parsePlan("Expression")
+	require.Error(t, err)
+}
+
+func TestEstimateOf(t *testing.T) {
+	estimates := []*tableEstimate{
+		{database: "default", table: "events", rows: 10},
+		{database: "default", table: "events", rows: 5},
+		{database: "default", table: "users", rows: 7},
+		{database: "other", table: "users", rows: 3},
+		{database: "other", table: "sessions", rows: 2},
+	}
+	tests := []struct {
+		description string
+		wantRows    uint64
+		wantKnown   bool
+	}{
+		{description: "default.events", wantRows: 15, wantKnown: true},
+		{description: "`default`.`events`", wantRows: 15, wantKnown: true},
+		{description: "default.events (Projection)", wantRows: 15, wantKnown: true},
+		{description: "sessions", wantRows: 2, wantKnown: true},
+		{description: "other.users", wantRows: 3, wantKnown: true},
+		{description: "users"},
+		{description: "default.sessions"},
+		{description: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			rows, known := estimateOf(tt.description, estimates)
+			require.Equal(t, tt.wantKnown, known)
+			require.Equal(t, tt.wantRows, rows)
+		})
+	}
+}
+
+func TestParsePlanPacket(t *testing.T) {
+	root, err := ParsePlanPacket(&response.QueryPlanPacket{Plans: []string{
+		"Expression ((Projection + Before ORDER BY))",
+		"  Join (JOIN)",
+		"    ReadFromMergeTree (default.events)",
+		"    Indexes:",
+		"      PrimaryKey",
+		"        Condition: true",
+		"    ReadFromRemote (Read from remote replica)",
+	}})
+	require.NoError(t, err)
+	require.Equal(t, &PlanNode{
+		Name:        "Expression",
+		Description: "(Projection + Before ORDER BY)",
+		Children: []*PlanNode{{
+			Name:        "Join",
+			Description: "JOIN",
+			Children: []*PlanNode{
+				{Name: "ReadFromMergeTree", Description: "default.events"},
+				{Name: "ReadFromRemote", Description: "Read from remote replica"},
+			},
+		}},
+	}, root)
+
+	root, err = ParsePlanPacket(&response.AggregateQueryPlanPacket{Plans: []string{samplePlan}})
+	require.NoError(t, err)
+	require.Equal(t, "Expression", root.Name)
+	require.Len(t, root.Children[0].Children[0].Indexes, 1)
+
+	root, err = ParsePlanPacket(&response.QueryPlanPacket{Plans: []string{`{"Plan": {"Node Type": "Expression"}}`}})
+	require.NoError(t, err)
+	require.Equal(t, "Expression", root.Name)
+
+	_, err = ParsePlanPacket(&response.QueryPlanPacket{})
+	require.Error(t, err)
+	_, err = ParsePlanPacket(&response.QueryPlanPacket{Plans: []string{"Expression", "Filter"}})
+	require.Error(t, err, "more than one root")
+	_, err = ParsePlanPacket(&response.QueryPlanPacket{Plans: []string{"Expression", "    Filter"}})
+	require.Error(t, err, "step without parent")
+	_, err = ParsePlanPacket(&response.ExceptionPacket{})
+	require.Error(t, err)
+}
@@ -1,14 +1,25 @@
 package sdk
 
 import (
+	"context"
 	"fmt"
 	"io"
 
+	"github.com/bytehouse-cloud/driver-go/conn"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/settings"
+	"github.com/bytehouse-cloud/driver-go/stream/format"
 	"github.com/bytehouse-cloud/driver-go/utils"
 )
 
+// ExternalData is an external table sent with a query, *ExternalTable or *ExternalTableReader
+type ExternalData interface {
+	// blockStream starts reading the blocks of the table with the query settings, until ctx is done.
+	// yield waits for the end of reading and returns its error.
+	blockStream(ctx context.Context, querySettings map[string]interface{}) (s *conn.ExternalTableStream, yield func() error, err error)
+}
+
 type ExternalTable struct {
 	name        string
 	values      [][]interface{}
@@ -41,6 +52,18 @@ func (e *ExternalTable) ToSingleBlockStream() (<-chan *data.Block, error) {
 	return blockStream, nil
 }
 
+func (e *ExternalTable) blockStream(_ context.Context, _ map[string]interface{}) (*conn.ExternalTableStream, func() error, error) {
+	header, err := data.NewBlock(e.columnNames, e.columnTypes, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocks, err := e.ToSingleBlockStream()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &conn.ExternalTableStream{Name: e.name, Header: header, Blocks: blocks}, func() error { return nil }, nil
+}
+
 // ExternalTableFromReader creates an external table
 // columnTypes are the clickhouse column type for each column in the table, e.g. UInt8, Uint32, etc
 // values are the table values
@@ -61,7 +84,29 @@ type ExternalTableReader struct {
 // It parses the data according to the fileType
 // The type of each column in the file must correspond to the columnTypes specified
 // Column types are the clickhouse column type for each column in the table, e.g. UInt8, Uint32, etc
-// Supported fileType values are CSV, CSVWithNames, JSON, VALUES
+// Supported fileType values are the formats registered with format.RegisterReader, e.g. CSV, CSVWithNames, JSON, VALUES
+// Compressed files are decompressed according to the client setting bytehouse.Compression of the query context
 func NewExternalTableReader(name string, reader io.Reader, columnNames []string, columnTypes []column.CHColumnType, fileType string) *ExternalTableReader {
 	return &ExternalTableReader{name: name, reader: reader, columnNames: columnNames, columnTypes: columnTypes, fileType: fileType}
 }
+
+func (e *ExternalTableReader) blockStream(ctx context.Context, querySettings map[string]interface{}) (*conn.ExternalTableStream, func() error, error) {
+	reader, err := format.BlockStreamFmtReaderFactory(e.fileType, e.reader, querySettings)
+	if err != nil {
+		return nil, nil, fmt.Errorf("external table reader error = %v", err)
+	}
+	sample, err := data.NewBlock(e.columnNames, e.columnTypes, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := data.NewBlock(e.columnNames, e.columnTypes, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	blocks, yield := reader.BlockStreamFmtRead(ctx, sample, settings.DEFAULT_BLOCK_SIZE)
+	return &conn.ExternalTableStream{Name: e.name, Header: header, Blocks: blocks}, func() error {
+		_, err := yield()
+		return err
+	}, nil
+}
@@ -15,11 +15,11 @@ type resultFmtReader struct {
 	err    error
 }
 
-func newResultFmtReader(fmtType string, blockStream <-chan *data.Block) *resultFmtReader {
+func newResultFmtReader(fmtType string, blockStream <-chan *data.Block, settings map[string]interface{}) *resultFmtReader {
 	var fmtReader resultFmtReader
 
 	zBuf := bytepool.NewZBufferDefault()
-	fmtWriter, err := format.BlockStreamFmtWriterFactory(fmtType, zBuf, nil)
+	fmtWriter, err := format.BlockStreamFmtWriterFactory(fmtType, zBuf, settings)
 	if err != nil {
 		fmtReader.err = err
 		return &fmtReader
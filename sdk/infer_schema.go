@@ -0,0 +1,533 @@
+package sdk
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/errors"
+	"github.com/bytehouse-cloud/driver-go/stream/format"
+)
+
+// DefaultSchemaSampleRows is the number of rows sampled by InferSchema if sampleRows is not positive
+const DefaultSchemaSampleRows = 1000
+
+const (
+	inferDateFormat     = "2006-01-02"
+	inferDateTimeFormat = "2006-01-02 15:04:05"
+)
+
+// Schema is the structure of input proposed by InferSchema
+type Schema struct {
+	ColumnNames []string
+	ColumnTypes []column.CHColumnType
+}
+
+// ExternalTableReader returns the external table of name reading reader in fileType with the columns of s
+func (s *Schema) ExternalTableReader(name string, reader io.Reader, fileType string) *ExternalTableReader {
+	return NewExternalTableReader(name, reader, s.ColumnNames, s.ColumnTypes, fileType)
+}
+
+// ColumnsDefinition returns the definition of the columns of s, e.g. `a` Int64, `b` Nullable(String)
+func (s *Schema) ColumnsDefinition() string {
+	defs := make([]string, len(s.ColumnNames))
+	for i, name := range s.ColumnNames {
+		defs[i] = quoteIdentifier(name) + " " + string(s.ColumnTypes[i])
+	}
+	return strings.Join(defs, ", ")
+}
+
+// CreateTableQuery returns the query creating table with the columns of s, e.g.
+// CreateTableQuery("db.events", "CnchMergeTree ORDER BY tuple()") returns
+// CREATE TABLE db.events (`a` Int64, `b` String) ENGINE = CnchMergeTree ORDER BY tuple()
+func (s *Schema) CreateTableQuery(table, engine string) string {
+	return fmt.Sprintf("CREATE TABLE %s (%s) ENGINE = %s", table, s.ColumnsDefinition(), engine)
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(name) + "`"
+}
+
+// InferSchema proposes the columns of r in fmtType from its first sampleRows rows, DefaultSchemaSampleRows if not
+// positive. Supported formats are CSV, CSVWithNames, the TabSeparated formats and JSONEachRow, compressed or not.
+// Columns of CSV and TabSeparated without names are named c1, c2, etc.
+//
+// Values are proposed Int64, Float64, Bool, Date, DateTime, String, Array(...) of values and, for JSON objects,
+// Map(String, ...). Columns with NULL or empty values are Nullable, and columns of values of different types
+// are String, except for Int64 and Float64 which are Float64, and Date and DateTime which are DateTime.
+//
+// As r is consumed, the returned reader reads the whole input, decompressed, including the rows sampled.
+// It is to be read instead of r, e.g. by the external table of Schema.ExternalTableReader.
+func InferSchema(r io.Reader, fmtType string, sampleRows int) (*Schema, io.Reader, error) {
+	if sampleRows <= 0 {
+		sampleRows = DefaultSchemaSampleRows
+	}
+
+	dr, err := format.NewDecompressReader(r, format.CompressionAuto)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sampled bytes.Buffer
+	br := bufio.NewReader(io.TeeReader(dr, &sampled))
+	replay := io.MultiReader(&sampled, dr)
+
+	var s *schemaInference
+	switch name := strings.ToUpper(fmtType); {
+	case name == format.Formats[format.CSV]:
+		s, err = inferCSV(br, false, sampleRows)
+	case name == format.Formats[format.CSVWITHNAMES]:
+		s, err = inferCSV(br, true, sampleRows)
+	case isFormat(name, format.TABSEPARATED), isFormat(name, format.TABSEPARATEDRAW):
+		s, err = inferTSV(br, false, sampleRows)
+	case isFormat(name, format.TABSEPARATEDWITHNAMES):
+		s, err = inferTSV(br, true, sampleRows)
+	case isFormat(name, format.TABSEPARATEDWITHNAMESANDTYPES):
+		schema, err := readTSVNamesAndTypes(br)
+		return schema, replay, err
+	case isFormat(name, format.JSONEACHROW):
+		s, err = inferJSONEachRow(br, sampleRows)
+	default:
+		return nil, nil, errors.ErrorfWithCaller("cannot infer schema of format: %v", fmtType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(s.names) == 0 {
+		return nil, nil, errors.ErrorfWithCaller("cannot infer schema of empty input")
+	}
+	return s.schema(), replay, nil
+}
+
+// isFormat tells if name is the name of fmtType in format.Formats or one of its aliases
+func isFormat(name string, fmtType int) bool {
+	if t, ok := format.FormatAliases[name]; ok {
+		return t == fmtType
+	}
+	return name == format.Formats[fmtType]
+}
+
+// schemaInference is the types inferred of columns by name, in order of appearance
+type schemaInference struct {
+	names []string
+	types []*inferredType
+	idx   map[string]int
+}
+
+func newSchemaInference(names []string) *schemaInference {
+	s := &schemaInference{idx: make(map[string]int)}
+	for _, name := range names {
+		s.column(name)
+	}
+	return s
+}
+
+// column returns the index of the column of name, added if new
+func (s *schemaInference) column(name string) int {
+	if i, ok := s.idx[name]; ok {
+		return i
+	}
+	s.idx[name] = len(s.names)
+	s.names = append(s.names, name)
+	s.types = append(s.types, nil)
+	return len(s.names) - 1
+}
+
+// columnAt returns the index of the i-th column, named c1, c2, etc. if added
+func (s *schemaInference) columnAt(i int) int {
+	for len(s.names) <= i {
+		s.column("c" + strconv.Itoa(len(s.names)+1))
+	}
+	return i
+}
+
+func (s *schemaInference) add(i int, t *inferredType) {
+	s.types[i] = mergeTypes(s.types[i], t)
+}
+
+func (s *schemaInference) schema() *Schema {
+	schema := &Schema{
+		ColumnNames: s.names,
+		ColumnTypes: make([]column.CHColumnType, len(s.types)),
+	}
+	for i, t := range s.types {
+		schema.ColumnTypes[i] = column.CHColumnType(t.String())
+	}
+	return schema
+}
+
+func inferCSV(r io.Reader, withNames bool, sampleRows int) (*schemaInference, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	s := newSchemaInference(nil)
+	if withNames {
+		names, err := cr.Read()
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, errors.ErrorfWithCaller("cannot read csv header: %v", err)
+		}
+		for _, name := range names {
+			s.column(strings.TrimSpace(name))
+		}
+	}
+
+	for row := 0; row < sampleRows; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.ErrorfWithCaller("cannot read csv row %v: %v", row+1, err)
+		}
+		for i, text := range record {
+			s.add(s.columnAt(i), inferText(strings.TrimSpace(text)))
+		}
+	}
+	return s, nil
+}
+
+func inferTSV(r *bufio.Reader, withNames bool, sampleRows int) (*schemaInference, error) {
+	s := newSchemaInference(nil)
+	if withNames {
+		line, err := readTSVLine(r)
+		if err == io.EOF {
+			return s, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range strings.Split(line, "\t") {
+			s.column(name)
+		}
+	}
+
+	for row := 0; row < sampleRows; row++ {
+		line, err := readTSVLine(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, text := range strings.Split(line, "\t") {
+			s.add(s.columnAt(i), inferText(text))
+		}
+	}
+	return s, nil
+}
+
+func readTSVNamesAndTypes(r *bufio.Reader) (*Schema, error) {
+	names, err := readTSVLine(r)
+	if err != nil {
+		return nil, errors.ErrorfWithCaller("cannot read names of columns: %v", err)
+	}
+	types, err := readTSVLine(r)
+	if err != nil {
+		return nil, errors.ErrorfWithCaller("cannot read types of columns: %v", err)
+	}
+
+	schema := &Schema{ColumnNames: strings.Split(names, "\t")}
+	for _, t := range strings.Split(types, "\t") {
+		schema.ColumnTypes = append(schema.ColumnTypes, column.CHColumnType(t))
+	}
+	if len(schema.ColumnNames) != len(schema.ColumnTypes) {
+		return nil, errors.ErrorfWithCaller("got %v names and %v types of columns", len(schema.ColumnNames), len(schema.ColumnTypes))
+	}
+	return schema, nil
+}
+
+// readTSVLine returns the next line of r without line ending, io.EOF if there is none
+func readTSVLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func inferJSONEachRow(r io.Reader, sampleRows int) (*schemaInference, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	s := newSchemaInference(nil)
+	for row := 0; row < sampleRows; row++ {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.ErrorfWithCaller("cannot read json row %v: %v", row+1, err)
+		}
+		if tok != json.Delim('{') {
+			return nil, errors.ErrorfWithCaller("expected json object of row %v, got: %v", row+1, tok)
+		}
+
+		// keys are read as tokens to keep the order of columns
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, errors.ErrorfWithCaller("cannot read json row %v: %v", row+1, err)
+			}
+			var v interface{}
+			if err = dec.Decode(&v); err != nil {
+				return nil, errors.ErrorfWithCaller("cannot read json row %v: %v", row+1, err)
+			}
+			s.add(s.column(key.(string)), inferJSONValue(v))
+		}
+		if _, err = dec.Token(); err != nil {
+			return nil, errors.ErrorfWithCaller("cannot read json row %v: %v", row+1, err)
+		}
+	}
+	return s, nil
+}
+
+type inferredKind int
+
+const (
+	kindNull inferredKind = iota
+	kindInt
+	kindFloat
+	kindBool
+	kindDate
+	kindDateTime
+	kindString
+	kindArray
+	kindMap
+)
+
+// inferredType is the type inferred of values, of which elem is the type of elements of arrays and values of maps,
+// nil if all are empty
+type inferredType struct {
+	kind     inferredKind
+	nullable bool
+	elem     *inferredType
+}
+
+var (
+	nullType     = &inferredType{kind: kindNull}
+	intType      = &inferredType{kind: kindInt}
+	floatType    = &inferredType{kind: kindFloat}
+	boolType     = &inferredType{kind: kindBool}
+	dateType     = &inferredType{kind: kindDate}
+	dateTimeType = &inferredType{kind: kindDateTime}
+	stringType   = &inferredType{kind: kindString}
+)
+
+func (t *inferredType) String() string {
+	var name column.CHColumnType
+	switch t.kind {
+	case kindNull:
+		return fmt.Sprintf("%v(%v)", column.NULLABLE, column.STRING)
+	case kindInt:
+		name = column.INT64
+	case kindFloat:
+		name = column.FLOAT64
+	case kindBool:
+		name = column.BOOL
+	case kindDate:
+		name = column.DATE
+	case kindDateTime:
+		name = column.DATETIME
+	case kindArray:
+		// arrays and maps cannot be nullable
+		return fmt.Sprintf("%v(%v)", column.ARRAY, t.elemString())
+	case kindMap:
+		return fmt.Sprintf("%v(%v, %v)", column.MAP, column.STRING, t.elemString())
+	default:
+		name = column.STRING
+	}
+	if t.nullable {
+		return fmt.Sprintf("%v(%v)", column.NULLABLE, name)
+	}
+	return string(name)
+}
+
+func (t *inferredType) elemString() string {
+	if t.elem == nil {
+		return string(column.STRING)
+	}
+	return t.elem.String()
+}
+
+// mergeTypes returns the type of values of both a and b, a if b is nil or b if a is nil
+func mergeTypes(a, b *inferredType) *inferredType {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.kind == kindNull:
+		return withNullable(b, b.kind != kindNull)
+	case b.kind == kindNull:
+		return withNullable(a, true)
+	}
+
+	nullable := a.nullable || b.nullable
+	switch {
+	case a.kind == b.kind && (a.kind == kindArray || a.kind == kindMap):
+		return &inferredType{kind: a.kind, elem: mergeTypes(a.elem, b.elem)}
+	case a.kind == b.kind:
+		return withNullable(a, nullable)
+	case isKindPair(a, b, kindInt, kindFloat):
+		return withNullable(floatType, nullable)
+	case isKindPair(a, b, kindDate, kindDateTime):
+		return withNullable(dateTimeType, nullable)
+	default:
+		return withNullable(stringType, nullable)
+	}
+}
+
+func isKindPair(a, b *inferredType, k1, k2 inferredKind) bool {
+	return (a.kind == k1 && b.kind == k2) || (a.kind == k2 && b.kind == k1)
+}
+
+func withNullable(t *inferredType, nullable bool) *inferredType {
+	if t.nullable == nullable {
+		return t
+	}
+	copied := *t
+	copied.nullable = nullable
+	return &copied
+}
+
+// inferText returns the type of an unquoted text value of CSV or TabSeparated
+func inferText(text string) *inferredType {
+	switch text {
+	case "", `\N`, column.NULL:
+		return nullType
+	case "true", "false":
+		return boolType
+	}
+	if len(text) >= 2 && text[0] == '[' && text[len(text)-1] == ']' {
+		if t, ok := inferArrayText(text[1 : len(text)-1]); ok {
+			return t
+		}
+	}
+	if isNumberText(text) {
+		if _, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return intType
+		}
+		if _, err := strconv.ParseFloat(text, 64); err == nil {
+			return floatType
+		}
+	}
+	return inferStringText(text)
+}
+
+// inferStringText returns the type of a quoted text value, which is a date, a date time or a string
+func inferStringText(text string) *inferredType {
+	if _, err := time.Parse(inferDateFormat, text); err == nil {
+		return dateType
+	}
+	if _, err := time.Parse(inferDateTimeFormat, text); err == nil {
+		return dateTimeType
+	}
+	return stringType
+}
+
+// isNumberText tells if text starts like a number, excluding texts such as inf and nan
+func isNumberText(text string) bool {
+	if text[0] == '-' || text[0] == '+' {
+		text = text[1:]
+	}
+	return len(text) > 0 && (text[0] >= '0' && text[0] <= '9' || text[0] == '.')
+}
+
+// inferArrayText returns the type of the array of elements of text, e.g. 1, 2 or 'a', 'b'.
+// It fails if the elements cannot be split.
+func inferArrayText(text string) (*inferredType, bool) {
+	elems, ok := splitArrayText(text)
+	if !ok {
+		return nil, false
+	}
+	t := &inferredType{kind: kindArray}
+	for _, elem := range elems {
+		if len(elem) >= 2 && (elem[0] == '\'' || elem[0] == '"') && elem[len(elem)-1] == elem[0] {
+			t.elem = mergeTypes(t.elem, inferStringText(elem[1:len(elem)-1]))
+			continue
+		}
+		t.elem = mergeTypes(t.elem, inferText(elem))
+	}
+	return t, true
+}
+
+// splitArrayText splits text on the commas which are not quoted or nested in brackets, with spaces trimmed
+func splitArrayText(text string) ([]string, bool) {
+	if strings.TrimSpace(text) == "" {
+		return nil, true
+	}
+
+	var (
+		elems []string
+		quote byte
+		depth int
+		start int
+	)
+	for i := 0; i < len(text); i++ {
+		b := text[i]
+		switch {
+		case quote != 0:
+			if b == '\\' {
+				i++
+			} else if b == quote {
+				quote = 0
+			}
+		case b == '\'' || b == '"':
+			quote = b
+		case b == '[' || b == '(' || b == '{':
+			depth++
+		case b == ']' || b == ')' || b == '}':
+			depth--
+		case b == ',' && depth == 0:
+			elems = append(elems, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 || depth != 0 {
+		return nil, false
+	}
+	return append(elems, strings.TrimSpace(text[start:])), true
+}
+
+// inferJSONValue returns the type of v decoded from JSON with numbers as json.Number
+func inferJSONValue(v interface{}) *inferredType {
+	switch v := v.(type) {
+	case nil:
+		return nullType
+	case bool:
+		return boolType
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return intType
+		}
+		return floatType
+	case string:
+		return inferStringText(v)
+	case []interface{}:
+		t := &inferredType{kind: kindArray}
+		for _, elem := range v {
+			t.elem = mergeTypes(t.elem, inferJSONValue(elem))
+		}
+		return t
+	case map[string]interface{}:
+		t := &inferredType{kind: kindMap}
+		for _, value := range v {
+			t.elem = mergeTypes(t.elem, inferJSONValue(value))
+		}
+		return t
+	default:
+		return stringType
+	}
+}
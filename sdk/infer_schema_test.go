@@ -0,0 +1,144 @@
+package sdk
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestInferSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		input      string
+		sampleRows int
+		want       *Schema
+		wantErr    bool
+	}{
+		{
+			name:   "CSVWithNames",
+			format: "CSVWithNames",
+			input: "id, score, day, at, tags, name, note\n" +
+				"1, 0.5, 2022-01-02, 2022-01-02 10:00:00, \"[1, 2]\", \"jack, ma\", \\N\n" +
+				"2, 3, 2022-01-03, 2022-01-04, \"['a', 'b']\", 5, \n",
+			want: &Schema{
+				ColumnNames: []string{"id", "score", "day", "at", "tags", "name", "note"},
+				ColumnTypes: []column.CHColumnType{
+					"Int64", "Float64", "Date", "DateTime", "Array(String)", "String", "Nullable(String)",
+				},
+			},
+		},
+		{
+			name:   "CSV without names",
+			format: "csv",
+			input:  "1,true\n,false\n",
+			want: &Schema{
+				ColumnNames: []string{"c1", "c2"},
+				ColumnTypes: []column.CHColumnType{"Nullable(Int64)", "Bool"},
+			},
+		},
+		{
+			name:       "Rows after sampleRows are not sampled",
+			format:     "CSV",
+			input:      "1\n2\nx\n",
+			sampleRows: 2,
+			want: &Schema{
+				ColumnNames: []string{"c1"},
+				ColumnTypes: []column.CHColumnType{"Int64"},
+			},
+		},
+		{
+			name:   "TSVWithNames",
+			format: "TSVWithNames",
+			input:  "a\tb\n1\t[1, NULL]\n\\N\t[]\n",
+			want: &Schema{
+				ColumnNames: []string{"a", "b"},
+				ColumnTypes: []column.CHColumnType{"Nullable(Int64)", "Array(Nullable(Int64))"},
+			},
+		},
+		{
+			name:   "TabSeparatedWithNamesAndTypes",
+			format: "TabSeparatedWithNamesAndTypes",
+			input:  "a\tb\nUInt8\tLowCardinality(String)\n1\tx\n",
+			want: &Schema{
+				ColumnNames: []string{"a", "b"},
+				ColumnTypes: []column.CHColumnType{"UInt8", "LowCardinality(String)"},
+			},
+		},
+		{
+			name:   "JSONEachRow",
+			format: "JSONEachRow",
+			input: `{"a": 1, "b": "2022-01-02", "c": [1, 2.5], "d": {"k": "v"}}` + "\n" +
+				`{"e": true, "a": 2, "b": "2022-01-02 10:00:00", "c": [], "d": {}, "f": null}` + "\n" +
+				`{"f": "x", "a": null}`,
+			want: &Schema{
+				ColumnNames: []string{"a", "b", "c", "d", "e", "f"},
+				ColumnTypes: []column.CHColumnType{
+					"Nullable(Int64)", "DateTime", "Array(Float64)", "Map(String, String)", "Bool", "Nullable(String)",
+				},
+			},
+		},
+		{
+			name:    "Empty input",
+			format:  "CSV",
+			input:   "",
+			wantErr: true,
+		},
+		{
+			name:    "Unsupported format",
+			format:  "Parquet",
+			input:   "PAR1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, r, err := InferSchema(strings.NewReader(tt.input), tt.format, tt.sampleRows)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, schema)
+
+			replayed, err := ioutil.ReadAll(r)
+			require.NoError(t, err)
+			require.Equal(t, tt.input, string(replayed))
+		})
+	}
+}
+
+func TestInferSchema_Compressed(t *testing.T) {
+	input := "a,b\n" + strings.Repeat("1,x\n", 10000)
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write([]byte(input))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	schema, r, err := InferSchema(&buf, "CSVWithNames", 10)
+	require.NoError(t, err)
+	require.Equal(t, []column.CHColumnType{"Int64", "String"}, schema.ColumnTypes)
+	replayed, err := ioutil.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, input, string(replayed))
+}
+
+func TestSchema_CreateTableQuery(t *testing.T) {
+	schema := &Schema{
+		ColumnNames: []string{"id", "na`me"},
+		ColumnTypes: []column.CHColumnType{"Int64", "Nullable(String)"},
+	}
+	require.Equal(t, "CREATE TABLE db.t (`id` Int64, `na\\`me` Nullable(String)) ENGINE = CnchMergeTree ORDER BY id",
+		schema.CreateTableQuery("db.t", "CnchMergeTree ORDER BY id"))
+
+	ext := schema.ExternalTableReader("ext", strings.NewReader(""), "CSV")
+	require.Equal(t, schema.ColumnNames, ext.columnNames)
+	require.Equal(t, schema.ColumnTypes, ext.columnTypes)
+}
@@ -3,6 +3,8 @@ package sdk
 import (
 	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
 	"github.com/bytehouse-cloud/driver-go/driver/response"
@@ -15,12 +17,21 @@ type getColumnValues func() [][]interface{}
 
 type InsertStmt struct {
 	// Column values to be converted into blocks
-	columnsBuffer      [][]interface{}
+	columnsBuffer [][]interface{}
+	// estimated encoded size of columnsBuffer
+	bufferBytes int
+	// time when the first row of columnsBuffer was added
+	bufferStart        time.Time
 	getEmpty           getColumnValues //TODO: find way to put back into pool after usage
 	columnsInputStream chan [][]interface{}
 	insertProcess      *stream.InsertProcess
 	toBlockProcess     values.BlockProcess
 	closed             bool
+	// mu guards columnsBuffer against the interval flusher
+	mu sync.Mutex
+	// stopFlusher stops the interval flusher, flusherDone is closed after it returns
+	stopFlusher chan struct{}
+	flusherDone chan struct{}
 }
 
 func NewInsertStatement(
@@ -37,15 +48,60 @@ func NewInsertStatement(
 		insertProcess:      insertProcess,
 		columnsInputStream: columnsInputStream,
 	}
-	newStmt.toBlockProcess = values.NewColumnValuesToBlock(columnsInputStream, sample)
+	toBlockOpts := []values.ColumnValuesToBlockOption{values.OptionParallelism(insertProcess.BlockParallelism())}
+	if insertProcess.BlockParallelism() > 1 {
+		// encode and compress in workers as well, sending the block only copies bytes
+		toBlockOpts = append(toBlockOpts, values.OptionEncode(insertProcess.Compress()))
+	}
+	newStmt.toBlockProcess = values.NewColumnValuesToBlock(columnsInputStream, sample, toBlockOpts...)
 	newStmt.columnsBuffer = newStmt.getBuffer()
 
 	blockInputStream := newStmt.toBlockProcess.Start(ctx)
 	insertProcess.Start(ctx, blockInputStream, serverResponseStream)
+	if interval := insertProcess.BlockLimits().Interval; interval > 0 {
+		newStmt.stopFlusher = make(chan struct{})
+		newStmt.flusherDone = make(chan struct{})
+		go newStmt.flushOnInterval(interval)
+	}
 
 	return newStmt
 }
 
+// flushOnInterval flushes the buffered rows once they are older than interval,
+// even if ExecContext is not called again
+func (s *InsertStmt) flushOnInterval(interval time.Duration) {
+	defer close(s.flusherDone)
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.stopFlusher:
+			return
+		case <-timer.C:
+		}
+
+		next := interval
+		s.mu.Lock()
+		if len(s.columnsBuffer[0]) > 0 {
+			if age := time.Since(s.bufferStart); age >= interval {
+				s.flush()
+			} else {
+				next = interval - age
+			}
+		}
+		s.mu.Unlock()
+		timer.Reset(next)
+	}
+}
+
+// flush sends the buffered rows to be built into a block, s.mu has to be held
+func (s *InsertStmt) flush() {
+	s.columnsInputStream <- s.columnsBuffer
+	s.columnsBuffer = s.getBuffer()
+	s.bufferBytes = 0
+}
+
 func (s *InsertStmt) getBuffer() [][]interface{} {
 	colBuf := s.getEmpty()
 	for i := range colBuf {
@@ -68,6 +124,9 @@ func (s *InsertStmt) ExecContext(ctx context.Context, args ...interface{}) (err
 		}
 	}
 
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	args_len, num_cols := len(args), len(s.columnsBuffer)
 	if args_len%num_cols != 0 {
 		return errors.ErrorfWithCaller("number of args: %v must be a multiple of number of columns: %v",
@@ -75,6 +134,13 @@ func (s *InsertStmt) ExecContext(ctx context.Context, args ...interface{}) (err
 		)
 	}
 
+	if len(s.columnsBuffer[0]) == 0 {
+		s.bufferStart = time.Now()
+	}
+	if limits := s.insertProcess.BlockLimits(); limits.Bytes > 0 {
+		s.bufferBytes += values.EstimateRowsEncodedSize(args)
+	}
+
 	// put all args to the column buffer
 	for len(args) > 0 {
 		for i, col := range s.columnsBuffer {
@@ -83,15 +149,12 @@ func (s *InsertStmt) ExecContext(ctx context.Context, args ...interface{}) (err
 		args = args[len(s.columnsBuffer):]
 	}
 
-	// only flush values when size of columns buffer is the same as batch
-	if len(s.columnsBuffer[0]) < s.insertProcess.BatchSize() {
+	// only flush values when columns buffer reaches any of the block limits
+	if !s.insertProcess.BlockLimits().Reached(len(s.columnsBuffer[0]), s.bufferBytes, s.bufferStart) {
 		return nil
 	}
 
-	// flushing
-	s.columnsInputStream <- s.columnsBuffer
-	s.columnsBuffer = s.getBuffer()
-
+	s.flush()
 	return nil
 }
 
@@ -104,6 +167,10 @@ func (s *InsertStmt) Close() error {
 		return errors.ErrorfWithCaller("insert statement already closed")
 	}
 	s.closed = true
+	if s.stopFlusher != nil {
+		close(s.stopFlusher)
+		<-s.flusherDone
+	}
 
 	if len(s.columnsBuffer[0]) > 0 {
 		s.columnsInputStream <- s.columnsBuffer
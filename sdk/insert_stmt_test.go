@@ -0,0 +1,48 @@
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+	"github.com/bytehouse-cloud/driver-go/stream"
+)
+
+func TestInsertStmt_FlushInterval(t *testing.T) {
+	sample, err := data.NewBlock([]string{"a"}, []column.CHColumnType{"Int32"}, 0)
+	require.NoError(t, err)
+	respStream := make(chan response.Packet, 1)
+	sent := make(chan int, 10)
+	sendBlock := func(b *data.Block) error {
+		if b.NumRows == 0 {
+			respStream <- &response.EndOfStreamPacket{}
+		}
+		sent <- b.NumRows
+		return nil
+	}
+
+	stmt := NewInsertStatement(context.Background(), sample, sendBlock, func() {}, respStream,
+		stream.OptionBatchSize(1000),
+		stream.OptionFlushInterval(50*time.Millisecond),
+	)
+	require.NoError(t, stmt.ExecContext(context.Background(), int32(0)))
+	require.NoError(t, stmt.ExecContext(context.Background(), int32(0)))
+
+	// the buffered rows are sent without further calls to ExecContext
+	select {
+	case n := <-sent:
+		require.Equal(t, 2, n)
+	case <-time.After(time.Second):
+		t.Fatal("rows not flushed after interval")
+	}
+
+	require.NoError(t, stmt.ExecContext(context.Background(), int32(0)))
+	require.NoError(t, stmt.Close())
+	require.Equal(t, 1, <-sent)
+	require.Equal(t, 0, <-sent)
+}
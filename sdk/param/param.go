@@ -29,4 +29,6 @@ const (
 	ACCESS_KEY               string = "access_key"
 	SECRET_KEY               string = "secret_key"
 	VOLCANO                  string = "volcano"
+	KEEPALIVE_INTERVAL       string = "keepalive_interval"
+	KEEPALIVE_TIMEOUT        string = "keepalive_timeout"
 )
@@ -0,0 +1,188 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bytehouse-cloud/driver-go"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+// killStatusFinished is the kill_status of a query which stopped
+const killStatusFinished = "finished"
+
+const runningQueriesQuery = "SELECT query_id, user, query, initial_query_id, toUInt8(is_initial_query), " +
+	"toFloat64(elapsed), toUInt8(is_cancelled), toUInt64(read_rows), toUInt64(read_bytes), " +
+	"toUInt64(total_rows_approx), toUInt64(written_rows), toUInt64(written_bytes), toInt64(memory_usage) " +
+	"FROM system.processes"
+
+// KillQueryResult is the result of KillQuery
+type KillQueryResult struct {
+	// Found is true if the query was running
+	Found bool
+	// Stopped is true if the query stopped, which is only known when killed in sync
+	Stopped bool
+	// Status is the kill_status reported by the server, e.g. finished, waiting or cant_cancel
+	Status string
+}
+
+// RunningQuery is a query running on the server, read from system.processes
+type RunningQuery struct {
+	QueryID        string
+	User           string
+	Query          string
+	InitialQueryID string
+	IsInitialQuery bool
+	Elapsed        time.Duration
+	IsCancelled    bool
+	ReadRows       uint64
+	ReadBytes      uint64
+	// TotalRowsApprox is the approximate number of rows to be read, 0 if unknown
+	TotalRowsApprox uint64
+	WrittenRows     uint64
+	WrittenBytes    uint64
+	MemoryUsage     int64
+}
+
+// KillQuery kills the query of queryID, which can be set with bytehouse.QueryContext.SetQueryID.
+// The kill is sent on a separate connection, so that it can stop a query in progress on g or any other connection.
+// If sync, KillQuery waits for the query to stop, otherwise the query may still be stopping when it returns.
+func (g *Gateway) KillQuery(ctx context.Context, queryID string, sync bool) (*KillQueryResult, error) {
+	mode := "ASYNC"
+	if sync {
+		mode = "SYNC"
+	}
+	query := fmt.Sprintf("KILL QUERY WHERE query_id = %s %s", quoteString(queryID), mode)
+
+	result := &KillQueryResult{}
+	err := g.queryOnSeparateConn(ctx, query, func(row []interface{}) error {
+		var killedID, user, killedQuery string
+		if err := scanRow(row, &result.Status, &killedID, &user, &killedQuery); err != nil {
+			return err
+		}
+		result.Found = true
+		result.Stopped = result.Status == killStatusFinished
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// RunningQueries returns the queries running on the server, read from system.processes on a separate connection
+func (g *Gateway) RunningQueries(ctx context.Context) ([]*RunningQuery, error) {
+	return g.runningQueries(ctx, "")
+}
+
+// runningQueries returns the queries running on the server which match the condition where if not empty
+func (g *Gateway) runningQueries(ctx context.Context, where string) ([]*RunningQuery, error) {
+	query := runningQueriesQuery
+	if where != "" {
+		query += " WHERE " + where
+	}
+
+	var result []*RunningQuery
+	err := g.queryOnSeparateConn(ctx, query, func(row []interface{}) error {
+		var (
+			q                      RunningQuery
+			isInitial, isCancelled uint8
+			elapsedSeconds         float64
+		)
+		if err := scanRow(row,
+			&q.QueryID, &q.User, &q.Query, &q.InitialQueryID, &isInitial,
+			&elapsedSeconds, &isCancelled, &q.ReadRows, &q.ReadBytes,
+			&q.TotalRowsApprox, &q.WrittenRows, &q.WrittenBytes, &q.MemoryUsage,
+		); err != nil {
+			return err
+		}
+		q.IsInitialQuery = isInitial != 0
+		q.IsCancelled = isCancelled != 0
+		q.Elapsed = time.Duration(elapsedSeconds * float64(time.Second))
+		result = append(result, &q)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// queryOnSeparateConn sends query on a new connection of the same config as g, which is closed afterwards,
+// and calls readRow with each row of the result.
+// The query settings and query ID of ctx are not applied, since they are meant for the query of g.
+func (g *Gateway) queryOnSeparateConn(ctx context.Context, query string, readRow func(row []interface{}) error) error {
+	if queryContext, ok := ctx.(*bytehouse.QueryContext); ok {
+		ctx = queryContext.Context
+	}
+
+	separate := g.Clone()
+	defer separate.Close()
+
+	return separate.queryRows(ctx, query, readRow)
+}
+
+// queryRows sends query and calls readRow with each row of the result
+func (g *Gateway) queryRows(ctx context.Context, query string, readRow func(row []interface{}) error) error {
+	_, err := g.queryRowsAndMeta(ctx, query, readRow)
+	return err
+}
+
+// queryRowsAndMeta is queryRows, returning the packets of the result other than data, such as ProgressPacket
+func (g *Gateway) queryRowsAndMeta(
+	ctx context.Context, query string, readRow func(row []interface{}) error,
+) ([]response.Packet, error) {
+	qr, err := g.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer qr.Close()
+
+	for {
+		row, ok := qr.NextRow()
+		if !ok {
+			break
+		}
+		if err = readRow(row); err != nil {
+			return nil, err
+		}
+	}
+	return qr.GetAllMeta(), qr.Exception()
+}
+
+// scanRow sets each of dest to the value of row at the same index
+func scanRow(row []interface{}, dest ...interface{}) error {
+	if len(row) != len(dest) {
+		return errors.ErrorfWithCaller("expected %v columns, got %v", len(dest), len(row))
+	}
+	for i, v := range row {
+		var ok bool
+		switch d := dest[i].(type) {
+		case *string:
+			*d, ok = v.(string)
+		case *uint8:
+			*d, ok = v.(uint8)
+		case *uint32:
+			*d, ok = v.(uint32)
+		case *uint64:
+			*d, ok = v.(uint64)
+		case *int64:
+			*d, ok = v.(int64)
+		case *float64:
+			*d, ok = v.(float64)
+		default:
+			return errors.ErrorfWithCaller("unsupported destination type: %T", d)
+		}
+		if !ok {
+			return errors.ErrorfWithCaller("unexpected type of column %v: %T", i, v)
+		}
+	}
+	return nil
+}
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -0,0 +1,32 @@
+package sdk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanRow(t *testing.T) {
+	var (
+		s   string
+		u8  uint8
+		u64 uint64
+		i64 int64
+		f64 float64
+	)
+	require.NoError(t, scanRow([]interface{}{"id", uint8(1), uint64(2), int64(-3), 0.5}, &s, &u8, &u64, &i64, &f64))
+	require.Equal(t, "id", s)
+	require.Equal(t, uint8(1), u8)
+	require.Equal(t, uint64(2), u64)
+	require.Equal(t, int64(-3), i64)
+	require.Equal(t, 0.5, f64)
+
+	require.Error(t, scanRow([]interface{}{"id"}, &s, &u8), "column count mismatch")
+	require.Error(t, scanRow([]interface{}{uint32(1)}, &u64), "type mismatch")
+	require.Error(t, scanRow([]interface{}{1}, new(int)), "unsupported destination")
+}
+
+func TestQuoteString(t *testing.T) {
+	require.Equal(t, `'abc'`, quoteString("abc"))
+	require.Equal(t, `'it\'s \\ here'`, quoteString(`it's \ here`))
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"runtime/debug"
 
+	"github.com/apache/arrow/go/v10/arrow"
+
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
 	"github.com/bytehouse-cloud/driver-go/driver/response"
@@ -19,6 +21,8 @@ type QueryResult struct {
 	err          error
 	resultMeta   []response.Packet
 	rowsInserted int
+	// mapErr maps err returned by Exception if not nil
+	mapErr func(error) error
 }
 
 func NewInsertQueryResult(responses <-chan response.Packet) *QueryResult {
@@ -143,6 +147,9 @@ func (q *QueryResult) GetAllLogs() []*response.LogPacket {
 }
 
 func (q *QueryResult) Exception() error {
+	if q.mapErr != nil {
+		return q.mapErr(q.err)
+	}
 	return q.err
 }
 
@@ -168,6 +175,31 @@ func (q *QueryResult) NextRowAsString() ([]interface{}, bool) {
 	return q.getNextRowFromBuffer(), true
 }
 
+// NextArrowRecord returns the rows of the next block of the result as an Arrow record, which must be released
+// by the caller. Blocks are converted column by column, which is much cheaper than reading rows with NextRow.
+// See data.ArrowSchema for the mapping of types.
+func (q *QueryResult) NextArrowRecord() (arrow.Record, bool) {
+	if q.offset < len(q.values) {
+		// rows of the block read by Columns or NextRow which are not consumed yet
+		record := data.BlockToArrowRecord(q.block)
+		defer record.Release()
+		rest := record.NewSlice(int64(q.offset), record.NumRows())
+		q.offset = len(q.values)
+		return rest, true
+	}
+
+	for d := range q.dataStream {
+		if d.Block.NumRows == 0 {
+			_ = d.Close()
+			continue
+		}
+		record := data.BlockToArrowRecord(d.Block)
+		_ = d.Close()
+		return record, true
+	}
+	return nil, false
+}
+
 func (q *QueryResult) getNextRowFromBuffer() []interface{} {
 	row := q.values[q.offset]
 	q.offset++
@@ -217,7 +249,14 @@ func (q *QueryResult) prepareValues(block *data.Block) {
 }
 
 func (q *QueryResult) ExportToReader(fmtType string) io.Reader {
-	return newResultFmtReader(fmtType, extractBlockStream(q.dataStream))
+	return q.ExportToReaderWithSettings(fmtType, nil)
+}
+
+// ExportToReaderWithSettings is ExportToReader with format settings,
+// such as format_csv_delimiter or output_format_parquet_row_group_size.
+// The output is compressed with the method of format.CompressionSetting if set, such as gzip or zstd
+func (q *QueryResult) ExportToReaderWithSettings(fmtType string, settings map[string]interface{}) io.Reader {
+	return newResultFmtReader(fmtType, extractBlockStream(q.dataStream), settings)
 }
 
 func (q *QueryResult) Close() error {
@@ -1,10 +1,12 @@
 package sdk
 
 import (
+	"bytes"
 	"io/ioutil"
 	"testing"
 	"time"
 
+	"github.com/apache/arrow/go/v10/parquet/file"
 	"github.com/stretchr/testify/require"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
@@ -41,6 +43,67 @@ func TestRows(t *testing.T) {
 				require.NoError(t, qr.Close())
 			},
 		},
+		{
+			name: "Can export data in Parquet with settings",
+			test: func(t *testing.T) {
+				ch := make(chan response.Packet, 1)
+				b, _ := data.NewBlock([]string{"dog"}, []column.CHColumnType{column.UINT32}, 3)
+				ch <- &response.DataPacket{
+					Table: "cool_table",
+					Block: b,
+				}
+				close(ch)
+				qr := NewQueryResult(ch, func() {})
+
+				reader := qr.ExportToReaderWithSettings("Parquet", map[string]interface{}{
+					"output_format_parquet_row_group_size": 2,
+				})
+				bs, err := ioutil.ReadAll(reader)
+				require.Nil(t, err)
+
+				parquetReader, err := file.NewParquetReader(bytes.NewReader(bs))
+				require.NoError(t, err)
+				require.Equal(t, int64(3), parquetReader.NumRows())
+				require.Equal(t, 2, parquetReader.NumRowGroups())
+				require.NoError(t, qr.Exception())
+				require.NoError(t, qr.Close())
+			},
+		},
+		{
+			name: "Can read blocks as Arrow records",
+			test: func(t *testing.T) {
+				ch := make(chan response.Packet, 3)
+				for _, texts := range [][]string{{"1", "2"}, {}, {"3"}} {
+					b, _ := data.NewBlock([]string{"dog"}, []column.CHColumnType{column.UINT32}, len(texts))
+					_, err := b.Columns[0].Data.ReadFromTexts(texts)
+					require.NoError(t, err)
+					ch <- &response.DataPacket{
+						Table: "cool_table",
+						Block: b,
+					}
+				}
+				close(ch)
+				qr := NewQueryResult(ch, func() {})
+
+				row, ok := qr.NextRow()
+				require.True(t, ok)
+				require.Equal(t, []interface{}{uint32(1)}, row)
+
+				var got []string
+				for {
+					record, ok := qr.NextArrowRecord()
+					if !ok {
+						break
+					}
+					require.Equal(t, "dog", record.ColumnName(0))
+					got = append(got, record.Column(0).String())
+					record.Release()
+				}
+				require.Equal(t, []string{"[2]", "[3]"}, got)
+				require.NoError(t, qr.Exception())
+				require.NoError(t, qr.Close())
+			},
+		},
 		{
 			name: "Can create insert query result",
 			test: func(t *testing.T) {
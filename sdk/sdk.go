@@ -4,16 +4,16 @@ import (
 	"bytes"
 	"context"
 	"errors"
-	"fmt"
 	"io"
 	"log"
 	"runtime/debug"
+	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/bytehouse-cloud/driver-go"
 	"github.com/bytehouse-cloud/driver-go/conn"
-	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/settings"
 	"github.com/bytehouse-cloud/driver-go/driver/response"
 	"github.com/bytehouse-cloud/driver-go/stream"
@@ -39,6 +39,8 @@ type Conn interface {
 	// Can be used for insert with files such as csv or json
 	// DataPacket will be read from the reader until io.EOF is returned as an error from reader.Read()
 	InsertFromReader(ctx context.Context, query string, reader io.Reader) (int, error)
+	// InsertFromReaderWithOptions is InsertFromReader with opts such as stream.OptionProgress and stream.OptionDryRun
+	InsertFromReaderWithOptions(ctx context.Context, query string, reader io.Reader, opts ...stream.InsertOption) (int, error)
 }
 
 type Stmt interface {
@@ -51,6 +53,8 @@ type Stmt interface {
 
 type Gateway struct {
 	Conn *conn.GatewayConn
+	// submitted holds the queries started by Submit, by query ID
+	submitted sync.Map
 }
 
 func Open(ctx context.Context, dsn string) (*Gateway, error) {
@@ -145,6 +149,10 @@ func (g *Gateway) PrepareInsert(ctx context.Context, query string, batchSize int
 
 	return NewInsertStatement(ctx, sample, g.Conn.SendClientData, g.Conn.Cancel, respStream,
 		stream.OptionBatchSize(batchSize),
+		stream.OptionBatchBytes(resolveBatchBytes(ctx)),
+		stream.OptionFlushInterval(resolveFlushInterval(ctx)),
+		stream.OptionBlockParallelism(resolveInsertBlockParallelism(ctx)),
+		stream.OptionCompress(g.Conn.Compress()),
 		stream.OptionAddCallBackResp(appendMeta),
 	), nil
 }
@@ -198,30 +206,10 @@ func (g *Gateway) QueryContext(ctx context.Context, query string) (*QueryResult,
 }
 
 func (g *Gateway) QueryContextWithExternalTableReader(ctx context.Context, query string, externalTable *ExternalTableReader) (*QueryResult, error) {
-	extTableReader, err := format.BlockStreamFmtReaderFactory(externalTable.fileType, externalTable.reader, g.Conn.GetAllSettings())
-	if err != nil {
-		return nil, fmt.Errorf("external table reader error = %v", err)
-	}
-
-	newBlock, err := data.NewBlock(externalTable.columnNames, externalTable.columnTypes, 0)
-	if err != nil {
-		return nil, err
-	}
-	extTablesStream, yield := extTableReader.BlockStreamFmtRead(ctx, newBlock, settings.DEFAULT_BLOCK_SIZE)
-
-	var eg errgroup.Group
-	eg.Go(func() error {
-		return g.sendQueryWithExternalTableStream(ctx, query, extTablesStream, externalTable.name)
-	})
-	eg.Go(func() error {
-		_, err := yield()
-		return err
-	})
-	if err := eg.Wait(); err != nil {
-		return nil, err
+	if externalTable == nil {
+		return g.QueryContextWithExternalTables(ctx, query)
 	}
-
-	return g.streamResult(ctx)
+	return g.QueryContextWithExternalTables(ctx, query, externalTable)
 }
 
 func (g *Gateway) Query(query string) (*QueryResult, error) {
@@ -245,7 +233,13 @@ func (g *Gateway) SendInsertQuery(ctx context.Context, query string) error {
 }
 
 func (g *Gateway) InsertFromReader(ctx context.Context, query string, file io.Reader) (int, error) {
-	qr, err := g.InsertWithDataFormatAuto(ctx, query, file)
+	return g.InsertFromReaderWithOptions(ctx, query, file)
+}
+
+// InsertFromReaderWithOptions is InsertFromReader with opts such as stream.OptionProgress and stream.OptionDryRun
+// applied to the insert
+func (g *Gateway) InsertFromReaderWithOptions(ctx context.Context, query string, file io.Reader, opts ...stream.InsertOption) (int, error) {
+	qr, err := g.InsertWithDataFormatAuto(ctx, query, file, opts...)
 	if err != nil {
 		return 0, err
 	}
@@ -255,7 +249,7 @@ func (g *Gateway) InsertFromReader(ctx context.Context, query string, file io.Re
 }
 
 // InsertWithDataFormatAuto handles insert Query with data reader
-func (g *Gateway) InsertWithDataFormatAuto(ctx context.Context, query string, dataReader io.Reader) (*QueryResult, error) {
+func (g *Gateway) InsertWithDataFormatAuto(ctx context.Context, query string, dataReader io.Reader, opts ...stream.InsertOption) (*QueryResult, error) {
 	iq, err := utils.ParseInsertQuery(query)
 	if err != nil {
 		return nil, err
@@ -269,15 +263,16 @@ func (g *Gateway) InsertWithDataFormatAuto(ctx context.Context, query string, da
 		}
 	}
 
-	return g.InsertWithData(ctx, query, dataReader, iq.DataFmt, blockSize)
+	return g.InsertWithData(ctx, query, dataReader, iq.DataFmt, blockSize, opts...)
 }
 
-func (g *Gateway) InsertWithData(ctx context.Context, query string, dataReader io.Reader, dataFmt string, blockSize int) (*QueryResult, error) {
+// InsertWithData inserts dataReader in dataFmt, opts are applied after the options resolved from ctx
+func (g *Gateway) InsertWithData(ctx context.Context, query string, dataReader io.Reader, dataFmt string, blockSize int, opts ...stream.InsertOption) (*QueryResult, error) {
 	var settings map[string]interface{}
 	if bytehouseCtx, ok := ctx.(*bytehouse.QueryContext); ok {
 		settings = bytehouseCtx.GetQuerySettings()
 	}
-	blockStreamReader, err := format.BlockStreamFmtReaderFactory(dataFmt, dataReader, settings)
+	blockStreamReader, err := format.BlockStreamFmtReaderFactory(dataFmt, dataReader, withCompressionSetting(ctx, settings))
 	if err != nil {
 		return nil, err
 	}
@@ -287,16 +282,29 @@ func (g *Gateway) InsertWithData(ctx context.Context, query string, dataReader i
 	}
 	respStreamForResult := make(chan response.Packet, 1)
 	qr := NewInsertQueryResult(respStreamForResult)
+	qr.mapErr = timeoutErrorMapper(ctx)
 
 	defer close(respStreamForResult)
+	insertOpts := append([]stream.InsertOption{
+		stream.OptionBatchSize(blockSize),
+		stream.OptionBatchBytes(resolveBatchBytes(ctx)),
+		stream.OptionFlushInterval(resolveFlushInterval(ctx)),
+		stream.OptionBlockParallelism(resolveInsertBlockParallelism(ctx)),
+		stream.OptionAddLogf(g.Conn.Log),
+		stream.OptionRestartInsert(func() (<-chan response.Packet, error) {
+			if err := g.sendQuery(ctx, query); err != nil {
+				return nil, err
+			}
+			return g.Conn.GetResponseStream(ctx), nil
+		}),
+	}, opts...)
 	rowsInserted, err := stream.HandleInsertFromFmtStream(ctx,
 		g.Conn.GetResponseStream(ctx), blockStreamReader,
 		g.Conn.SendClientData, g.Conn.Cancel,
 		func(resp response.Packet) {
 			respStreamForResult <- resp
 		},
-		stream.OptionBatchSize(blockSize),
-		stream.OptionAddLogf(g.Conn.Log),
+		insertOpts...,
 	)
 	qr.rowsInserted = rowsInserted
 
@@ -342,8 +350,13 @@ func (g *Gateway) applySettingsFromCtx(ctx context.Context) func() {
 		g.applyConnConfigs(queryContext.GetPersistentConnConfigs())
 		revertConnConfigs := g.applyConnConfigsTemporarily(queryContext.GetTemporaryConnConfigs())
 		revertQuerySettings := g.applySettingsTemporarily(queryContext.GetQuerySettings())
+		revertDeadlineSettings := func() {}
+		if m := deadlineSettings(ctx, g.Conn.GetAllSettings()[maxExecutionTimeSetting]); m != nil {
+			revertDeadlineSettings = g.applySettingsTemporarily(m)
+		}
 		return func() {
 			revertConnConfigs()
+			revertDeadlineSettings()
 			revertQuerySettings()
 		}
 	}
@@ -353,40 +366,83 @@ func (g *Gateway) applySettingsFromCtx(ctx context.Context) func() {
 func (g *Gateway) streamResult(ctx context.Context) (*QueryResult, error) {
 	responseStream := g.Conn.GetResponseStream(ctx)
 	finish := g.listenCtxDone(ctx)
-	return NewQueryResult(responseStream, finish), nil
+	qr := NewQueryResult(responseStream, finish)
+	qr.mapErr = timeoutErrorMapper(ctx)
+	return qr, nil
 }
 
 func (g *Gateway) sendQuery(ctx context.Context, query string) error {
-	return g.sendQueryWithExternalTable(ctx, query, nil)
+	return g.sendQueryWithExternalTables(ctx, query, nil)
 }
 
 func (g *Gateway) QueryContextWithExternalTable(ctx context.Context, query string, externalTable *ExternalTable) (*QueryResult, error) {
-	if err := g.sendQueryWithExternalTable(ctx, query, externalTable); err != nil {
+	if externalTable == nil {
+		return g.QueryContextWithExternalTables(ctx, query)
+	}
+	return g.QueryContextWithExternalTables(ctx, query, externalTable)
+}
+
+// QueryContextWithExternalTables sends a query with external tables, each an *ExternalTable or *ExternalTableReader.
+// The names of the external tables in the query have to correspond to those of the externalTables you are sending.
+func (g *Gateway) QueryContextWithExternalTables(ctx context.Context, query string, externalTables ...ExternalData) (*QueryResult, error) {
+	if err := g.sendQueryWithExternalTables(ctx, query, externalTables); err != nil {
 		return nil, err
 	}
 
 	return g.streamResult(ctx)
 }
 
-func (g *Gateway) sendQueryWithExternalTable(ctx context.Context, query string, externalTable *ExternalTable) error {
-	if externalTable == nil {
-		return g.sendQueryWithExternalTableStream(ctx, query, nil, "")
+// sendQueryWithExternalTables sends query with the blocks of externalTables, which are read concurrently
+// and sent one table after another
+func (g *Gateway) sendQueryWithExternalTables(ctx context.Context, query string, externalTables []ExternalData) error {
+	if len(externalTables) == 0 {
+		return g.sendQueryWithExternalTableStreams(ctx, query)
+	}
+
+	// readers are cancelled and drained to stop reading if the query cannot be sent
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		eg            errgroup.Group
+		streams       = make([]*conn.ExternalTableStream, 0, len(externalTables))
+		querySettings = withCompressionSetting(ctx, g.Conn.GetAllSettings())
+	)
+	stopReading := func() {
+		cancel()
+		for _, s := range streams {
+			for range s.Blocks {
+			}
+		}
+	}
+
+	for _, externalTable := range externalTables {
+		s, yield, err := externalTable.blockStream(readCtx, querySettings)
+		if err != nil {
+			stopReading()
+			_ = eg.Wait()
+			return err
+		}
+		streams = append(streams, s)
+		eg.Go(yield)
 	}
 
-	extTablesStream, err := externalTable.ToSingleBlockStream()
+	err := g.sendQueryWithExternalTableStreams(ctx, query, streams...)
 	if err != nil {
-		return err
+		stopReading()
 	}
-	return g.sendQueryWithExternalTableStream(ctx, query, extTablesStream, externalTable.name)
+	if yieldErr := eg.Wait(); err == nil {
+		err = yieldErr
+	}
+	return err
 }
 
-func (g *Gateway) sendQueryWithExternalTableStream(ctx context.Context, query string, extTableStream <-chan *data.Block, extTableName string) error {
+func (g *Gateway) sendQueryWithExternalTableStreams(ctx context.Context, query string, extTables ...*conn.ExternalTableStream) error {
 	var queryID string
 	if queryContext, ok := ctx.(*bytehouse.QueryContext); ok {
 		queryID = queryContext.GetQueryID()
 	}
 	defer g.applySettingsFromCtx(ctx)()
-	return g.Conn.SendQueryFull(query, queryID, extTableStream, extTableName)
+	return g.Conn.SendQueryWithExternalTables(query, queryID, extTables...)
 }
 
 // Ping exposed for SDK directly
@@ -412,43 +468,48 @@ func (g *Gateway) applyConnConfigsTemporarily(m map[string]interface{}) func() {
 }
 
 func (g *Gateway) Clone() *Gateway {
-	return &Gateway{g.Conn.Clone()}
+	return &Gateway{Conn: g.Conn.Clone()}
 }
 
 func resolveBatchSize(ctx context.Context) int {
-	qc, ok := ctx.(*bytehouse.QueryContext)
-	if !ok {
-		return bytehouse.Default[bytehouse.InsertBlockSize].(int)
-	}
-	size, ok := qc.GetClientSettings()[bytehouse.InsertBlockSize]
-	if !ok {
-		return bytehouse.Default[bytehouse.InsertBlockSize].(int)
-	}
-	return size.(int)
+	return resolveClientSetting(ctx, bytehouse.InsertBlockSize).(int)
+}
+
+func resolveBatchBytes(ctx context.Context) int {
+	return resolveClientSetting(ctx, bytehouse.InsertBlockBytes).(int)
+}
+
+func resolveFlushInterval(ctx context.Context) time.Duration {
+	return resolveClientSetting(ctx, bytehouse.InsertFlushInterval).(time.Duration)
 }
 
 func resolveConnCount(ctx context.Context) int {
-	// todo: a little repetitive from function above, do dynamic resolution base on type if needed
-	qc, ok := ctx.(*bytehouse.QueryContext)
-	if !ok {
-		return bytehouse.Default[bytehouse.InsertConnectionCount].(int)
-	}
-	count, ok := qc.GetClientSettings()[bytehouse.InsertConnectionCount]
-	if !ok {
-		return bytehouse.Default[bytehouse.InsertConnectionCount].(int)
-	}
-	return count.(int)
+	return resolveClientSetting(ctx, bytehouse.InsertConnectionCount).(int)
 }
 
 func resolveInsertBlockParallelism(ctx context.Context) int {
-	// todo: a little repetitive from function above, do dynamic resolution base on type if needed
+	return resolveClientSetting(ctx, bytehouse.InsertBlockParallelism).(int)
+}
+
+// withCompressionSetting returns a copy of the format settings with the compression of input resolved from ctx
+func withCompressionSetting(ctx context.Context, settings map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(settings)+1)
+	for k, v := range settings {
+		result[k] = v
+	}
+	result[format.CompressionSetting] = resolveClientSetting(ctx, bytehouse.Compression)
+	return result
+}
+
+// resolveClientSetting returns the client setting of name from ctx if set, otherwise the default value
+func resolveClientSetting(ctx context.Context, name string) interface{} {
 	qc, ok := ctx.(*bytehouse.QueryContext)
 	if !ok {
-		return bytehouse.Default[bytehouse.InsertBlockParallelism].(int)
+		return bytehouse.Default[name]
 	}
-	parallelism, ok := qc.GetClientSettings()[bytehouse.InsertBlockParallelism]
+	v, ok := qc.GetClientSettings()[name]
 	if !ok {
-		return bytehouse.Default[bytehouse.InsertBlockParallelism].(int)
+		return bytehouse.Default[name]
 	}
-	return parallelism.(int)
+	return v
 }
@@ -0,0 +1,297 @@
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/bytehouse-cloud/driver-go"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+// WaitPollInterval is the interval at which Wait polls the status of a query submitted by another Gateway
+const WaitPollInterval = time.Second
+
+const loggedQueryStatusQuery = "SELECT toString(type), toFloat64(query_duration_ms) / 1000, toUInt64(read_rows), " +
+	"toUInt64(read_bytes), toUInt64(written_rows), toUInt64(written_bytes), toUInt32(exception_code), exception " +
+	"FROM system.query_log WHERE query_id = %s AND type != 'QueryStart' ORDER BY event_time DESC LIMIT 1"
+
+const queryLogFinishType = "QueryFinish"
+
+// QueryState is the state of a query followed with Status
+type QueryState string
+
+const (
+	// QueryStateUnknown is the state of a query which is neither running nor logged in system.query_log yet,
+	// which is flushed periodically by the server
+	QueryStateUnknown  QueryState = "unknown"
+	QueryStateRunning  QueryState = "running"
+	QueryStateFinished QueryState = "finished"
+	QueryStateFailed   QueryState = "failed"
+)
+
+// QueryStatus is the status of a query submitted with Submit
+type QueryStatus struct {
+	QueryID string
+	State   QueryState
+	Elapsed time.Duration
+	// ReadRows, ReadBytes and TotalRowsApprox are the progress of the query
+	ReadRows        uint64
+	ReadBytes       uint64
+	TotalRowsApprox uint64
+	WrittenRows     uint64
+	WrittenBytes    uint64
+	// Exception is the exception of the query if State is QueryStateFailed
+	Exception *response.ExceptionPacket
+}
+
+// Done tells if the query finished or failed
+func (s *QueryStatus) Done() bool {
+	return s.State == QueryStateFinished || s.State == QueryStateFailed
+}
+
+// QueryLogFlushTimeout is how long the end of a submitted query is looked for in system.query_log after the
+// connection of the query ended, after which the end known by the connection is final
+const QueryLogFlushTimeout = 10 * time.Second
+
+// SubmittedQueryExpiry is how long a Gateway keeps the end of a query it submitted after the query ended,
+// if its final status is not returned by Status or Wait before
+const SubmittedQueryExpiry = time.Hour
+
+// submittedQuery is a query submitted by a Gateway, of which the result is read by the Gateway until done
+type submittedQuery struct {
+	done chan struct{}
+	// status is the status of the query known by its connection, set before done is closed
+	status *QueryStatus
+	// lost is true if the connection failed before the end of the query, set before done is closed
+	lost bool
+	// ended is when done is closed
+	ended time.Time
+}
+
+// Submit starts query and returns its ID without waiting for the query to end.
+// The query ID is that of ctx, set with bytehouse.QueryContext.SetQueryID, otherwise a random UUID.
+// The query runs on a separate connection held by g in the background, of which the result is discarded,
+// with the settings of ctx but not its deadline or cancellation. The query still depends on that connection:
+// the server may stop it if the connection drops or the process exits before the query ends.
+// Use Status or Wait to follow the query and KillQuery to stop it.
+func (g *Gateway) Submit(ctx context.Context, query string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	queryCtx := bytehouse.NewQueryContext(ctx).WithContext(context.Background())
+	queryID := queryCtx.GetQueryID()
+	if queryID == "" {
+		queryID = uuid.NewString()
+		queryCtx.SetQueryID(queryID)
+	}
+
+	separate := g.Clone()
+	start := time.Now()
+	if err := separate.sendQuery(queryCtx, query); err != nil {
+		_ = separate.Close()
+		return "", err
+	}
+
+	sq := &submittedQuery{done: make(chan struct{})}
+	g.submitted.Store(queryID, sq)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+					r,
+					string(debug.Stack()))
+			}
+		}()
+		defer time.AfterFunc(SubmittedQueryExpiry, func() {
+			g.forgetSubmitted(queryID, sq)
+		})
+		defer close(sq.done)
+		defer separate.Close()
+
+		qr, _ := separate.streamResult(queryCtx)
+		_ = qr.Close()
+		sq.status = submittedQueryStatus(queryID, qr, time.Since(start))
+		_, serverEnded := qr.Exception().(*response.ExceptionPacket)
+		sq.lost = qr.Exception() != nil && !serverEnded
+		sq.ended = time.Now()
+	}()
+	return queryID, nil
+}
+
+// Status returns the status of the query of queryID, of which the progress is read from system.processes while it
+// is running, and the end from system.query_log, which has the final counters such as WrittenRows.
+// The end of a query submitted by g is also known by its connection: it is returned until the query is logged,
+// or after QueryLogFlushTimeout if it is not, except if the connection failed, in which case the state is unknown
+// until then. The query is forgotten by g once its final status is returned.
+func (g *Gateway) Status(ctx context.Context, queryID string) (*QueryStatus, error) {
+	status, _, err := g.status(ctx, queryID)
+	return status, err
+}
+
+// status returns the status of the query of queryID and whether it is final
+func (g *Gateway) status(ctx context.Context, queryID string) (*QueryStatus, bool, error) {
+	sq, submitted := g.loadSubmitted(queryID)
+	if submitted {
+		select {
+		case <-sq.done:
+		default:
+			submitted = false // running, followed on the server
+		}
+	}
+
+	status, err := g.serverStatus(ctx, queryID)
+	if err != nil {
+		return nil, false, err
+	}
+	if !submitted {
+		return status, status.Done(), nil
+	}
+
+	status, final := sq.statusGiven(status, time.Now())
+	if final {
+		g.forgetSubmitted(queryID, sq)
+	}
+	return status, final, nil
+}
+
+// serverStatus returns the status of the query of queryID read from system.processes and system.query_log
+func (g *Gateway) serverStatus(ctx context.Context, queryID string) (*QueryStatus, error) {
+	running, err := g.runningQueries(ctx, "query_id = "+quoteString(queryID))
+	if err != nil {
+		return nil, err
+	}
+	if len(running) > 0 {
+		q := running[0]
+		return &QueryStatus{
+			QueryID:         queryID,
+			State:           QueryStateRunning,
+			Elapsed:         q.Elapsed,
+			ReadRows:        q.ReadRows,
+			ReadBytes:       q.ReadBytes,
+			TotalRowsApprox: q.TotalRowsApprox,
+			WrittenRows:     q.WrittenRows,
+			WrittenBytes:    q.WrittenBytes,
+		}, nil
+	}
+
+	status := &QueryStatus{QueryID: queryID, State: QueryStateUnknown}
+	err = g.queryOnSeparateConn(ctx, fmt.Sprintf(loggedQueryStatusQuery, quoteString(queryID)), func(row []interface{}) error {
+		var (
+			logType, exception string
+			durationSeconds    float64
+			exceptionCode      uint32
+		)
+		if err := scanRow(row,
+			&logType, &durationSeconds, &status.ReadRows, &status.ReadBytes,
+			&status.WrittenRows, &status.WrittenBytes, &exceptionCode, &exception,
+		); err != nil {
+			return err
+		}
+		status.Elapsed = time.Duration(durationSeconds * float64(time.Second))
+		status.State = QueryStateFinished
+		if logType != queryLogFinishType {
+			status.State = QueryStateFailed
+			status.Exception = &response.ExceptionPacket{
+				Code:    exceptionCode,
+				Name:    "DB::Exception",
+				Message: exception,
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// Wait waits for the query of queryID to end and returns its final status, of which Exception is set if it failed.
+// The status is polled every WaitPollInterval, once the connection of the query ended if it is submitted by g.
+func (g *Gateway) Wait(ctx context.Context, queryID string) (*QueryStatus, error) {
+	if sq, ok := g.loadSubmitted(queryID); ok {
+		select {
+		case <-sq.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ticker := time.NewTicker(WaitPollInterval)
+	defer ticker.Stop()
+	for {
+		status, final, err := g.status(ctx, queryID)
+		if err != nil {
+			return nil, err
+		}
+		if final {
+			return status, nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (g *Gateway) loadSubmitted(queryID string) (*submittedQuery, bool) {
+	v, ok := g.submitted.Load(queryID)
+	if !ok {
+		return nil, false
+	}
+	return v.(*submittedQuery), true
+}
+
+// forgetSubmitted removes sq from the queries submitted by g, unless replaced by another query of the same ID
+func (g *Gateway) forgetSubmitted(queryID string, sq *submittedQuery) {
+	if current, ok := g.loadSubmitted(queryID); ok && current == sq {
+		g.submitted.Delete(queryID)
+	}
+}
+
+// statusGiven returns the status of the ended query sq given its status on the server and whether it is final.
+// The status on the server is preferred, as the connection knows neither the written rows nor if the query still
+// runs after the connection failed.
+func (sq *submittedQuery) statusGiven(server *QueryStatus, now time.Time) (*QueryStatus, bool) {
+	if server.State != QueryStateUnknown {
+		return server, server.Done()
+	}
+	flushed := now.Sub(sq.ended) >= QueryLogFlushTimeout
+	if sq.lost && !flushed {
+		return server, false
+	}
+	status := *sq.status
+	return &status, flushed
+}
+
+// submittedQueryStatus returns the final status of the submitted query of qr, which is read until the end
+func submittedQueryStatus(queryID string, qr *QueryResult, elapsed time.Duration) *QueryStatus {
+	status := &QueryStatus{
+		QueryID: queryID,
+		State:   QueryStateFinished,
+		Elapsed: elapsed,
+	}
+	for _, meta := range qr.GetAllMeta() {
+		if progress, ok := meta.(*response.ProgressPacket); ok {
+			status.ReadRows += progress.Rows
+			status.ReadBytes += progress.Bytes
+			status.TotalRowsApprox += progress.TotalRows
+		}
+	}
+
+	switch err := qr.Exception().(type) {
+	case nil:
+	case *response.ExceptionPacket:
+		status.State = QueryStateFailed
+		status.Exception = err
+	default:
+		status.State = QueryStateFailed
+		status.Exception = &response.ExceptionPacket{Message: err.Error()}
+	}
+	return status
+}
@@ -0,0 +1,134 @@
+package sdk
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+func TestSubmittedQueryStatus(t *testing.T) {
+	newResult := func(packets ...response.Packet) *QueryResult {
+		ch := make(chan response.Packet, len(packets))
+		for _, p := range packets {
+			ch <- p
+		}
+		close(ch)
+		qr := NewQueryResult(ch, func() {})
+		require.NoError(t, qr.Close())
+		return qr
+	}
+
+	status := submittedQueryStatus("q1", newResult(
+		&response.ProgressPacket{Rows: 10, Bytes: 100, TotalRows: 30},
+		&response.ProgressPacket{Rows: 20, Bytes: 200},
+		&response.EndOfStreamPacket{},
+	), time.Second)
+	require.Equal(t, &QueryStatus{
+		QueryID:         "q1",
+		State:           QueryStateFinished,
+		Elapsed:         time.Second,
+		ReadRows:        30,
+		ReadBytes:       300,
+		TotalRowsApprox: 30,
+	}, status)
+	require.True(t, status.Done())
+
+	exception := &response.ExceptionPacket{Code: 60, Name: "DB::Exception", Message: "Table default.t doesn't exist"}
+	status = submittedQueryStatus("q2", newResult(exception), time.Second)
+	require.Equal(t, QueryStateFailed, status.State)
+	require.Equal(t, exception, status.Exception)
+}
+
+func TestGateway_WaitSubmitted(t *testing.T) {
+	g := &Gateway{}
+	sq := &submittedQuery{done: make(chan struct{})}
+	g.submitted.Store("q1", sq)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.Wait(ctx, "q1")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// a query submitted again with the same ID is kept
+	g.forgetSubmitted("q1", &submittedQuery{})
+	_, ok := g.loadSubmitted("q1")
+	require.True(t, ok)
+	g.forgetSubmitted("q1", sq)
+	_, ok = g.loadSubmitted("q1")
+	require.False(t, ok)
+}
+
+func TestSubmittedQuery_StatusGiven(t *testing.T) {
+	ended := time.Now()
+	finished := &QueryStatus{QueryID: "q1", State: QueryStateFinished, ReadRows: 10}
+	lost := &QueryStatus{QueryID: "q1", State: QueryStateFailed, Exception: &response.ExceptionPacket{Message: "EOF"}}
+	unknown := &QueryStatus{QueryID: "q1", State: QueryStateUnknown}
+	logged := &QueryStatus{QueryID: "q1", State: QueryStateFinished, ReadRows: 10, WrittenRows: 10}
+	running := &QueryStatus{QueryID: "q1", State: QueryStateRunning, ReadRows: 5}
+
+	tests := []struct {
+		name      string
+		sq        *submittedQuery
+		server    *QueryStatus
+		now       time.Time
+		want      *QueryStatus
+		wantFinal bool
+	}{
+		{
+			name:      "Should prefer the logged status",
+			sq:        &submittedQuery{status: finished, ended: ended},
+			server:    logged,
+			now:       ended,
+			want:      logged,
+			wantFinal: true,
+		},
+		{
+			name:   "Should return the status of the connection until logged",
+			sq:     &submittedQuery{status: finished, ended: ended},
+			server: unknown,
+			now:    ended,
+			want:   finished,
+		},
+		{
+			name:      "Should return the status of the connection if not logged in time",
+			sq:        &submittedQuery{status: finished, ended: ended},
+			server:    unknown,
+			now:       ended.Add(QueryLogFlushTimeout),
+			want:      finished,
+			wantFinal: true,
+		},
+		{
+			name:   "Should follow a query still running after its connection failed",
+			sq:     &submittedQuery{status: lost, lost: true, ended: ended},
+			server: running,
+			now:    ended,
+			want:   running,
+		},
+		{
+			name:   "Should not take the failure of the connection as the end of the query",
+			sq:     &submittedQuery{status: lost, lost: true, ended: ended},
+			server: unknown,
+			now:    ended,
+			want:   unknown,
+		},
+		{
+			name:      "Should return the failure of the connection if the query is not logged in time",
+			sq:        &submittedQuery{status: lost, lost: true, ended: ended},
+			server:    unknown,
+			now:       ended.Add(QueryLogFlushTimeout),
+			want:      lost,
+			wantFinal: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, final := tt.sq.statusGiven(tt.server, tt.now)
+			require.Equal(t, tt.want, status)
+			require.Equal(t, tt.wantFinal, final)
+		})
+	}
+}
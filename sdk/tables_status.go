@@ -0,0 +1,57 @@
+package sdk
+
+import (
+	"context"
+	"time"
+
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+// TableName is the name of a table of which the status is requested with TablesStatus,
+// of which Database is the current database of the connection if empty
+type TableName = response.TableName
+
+// TableStatus is the status of a table on the server of the connection
+type TableStatus struct {
+	TableName
+	// Found is false if the table does not exist on the server
+	Found        bool
+	IsReplicated bool
+	// Delay is the replication delay of the table if IsReplicated
+	Delay time.Duration
+}
+
+// Ready tells if the table exists and is at most maxDelay behind the other replicas if replicated
+func (s *TableStatus) Ready(maxDelay time.Duration) bool {
+	return s.Found && (!s.IsReplicated || s.Delay <= maxDelay)
+}
+
+// TablesStatus returns the status of each of tables on the server of the connection, in the same order,
+// such as the replication delay used to route reads away from lagging replicas
+func (g *Gateway) TablesStatus(ctx context.Context, tables []TableName) ([]*TableStatus, error) {
+	p, err := g.Conn.TablesStatus(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
+	return tablesStatus(tables, p), nil
+}
+
+// tablesStatus returns the status of each of tables from p, which only holds the tables found
+func tablesStatus(tables []TableName, p *response.TablesStatusPacket) []*TableStatus {
+	found := make(map[TableName]*response.TableStatus, len(p.Statuses))
+	for _, s := range p.Statuses {
+		found[s.TableName] = s
+	}
+
+	result := make([]*TableStatus, len(tables))
+	for i, t := range tables {
+		status := &TableStatus{TableName: t}
+		if s, ok := found[t]; ok {
+			status.Found = true
+			status.IsReplicated = s.IsReplicated
+			status.Delay = time.Duration(s.AbsoluteDelay) * time.Second
+		}
+		result[i] = status
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+)
+
+func TestTablesStatus(t *testing.T) {
+	local := TableName{Database: "db", Table: "local"}
+	replicated := TableName{Database: "db", Table: "replicated"}
+	missing := TableName{Database: "db", Table: "missing"}
+
+	statuses := tablesStatus([]TableName{missing, replicated, local}, &response.TablesStatusPacket{
+		Statuses: []*response.TableStatus{
+			{TableName: local},
+			{TableName: replicated, IsReplicated: true, AbsoluteDelay: 30},
+		},
+	})
+	require.Equal(t, []*TableStatus{
+		{TableName: missing},
+		{TableName: replicated, Found: true, IsReplicated: true, Delay: 30 * time.Second},
+		{TableName: local, Found: true},
+	}, statuses)
+
+	require.False(t, statuses[0].Ready(time.Hour))
+	require.True(t, statuses[1].Ready(time.Minute))
+	require.False(t, statuses[1].Ready(10*time.Second))
+	require.True(t, statuses[2].Ready(0))
+}
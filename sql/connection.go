@@ -63,7 +63,7 @@ func (c *CHConn) Rollback() error {
 // if the connection has been used before. If the driver returns ErrBadConn
 // the connection is discarded.
 func (c *CHConn) ResetSession(ctx context.Context) error {
-	if c.Gateway.Conn.InQueryingState() {
+	if c.Gateway.Conn.InQueryingState() || c.Gateway.Conn.Broken() {
 		return driver.ErrBadConn
 	}
 
@@ -74,6 +74,13 @@ func (c *CHConn) ResetSession(ctx context.Context) error {
 	return nil
 }
 
+// IsValid implements Validator interface
+// The connection is discarded instead of being returned to the pool if it is broken,
+// which is found by the keepalive of idle connections.
+func (c *CHConn) IsValid() bool {
+	return !c.Gateway.Conn.Broken()
+}
+
 // Ping implements Pinger interface
 // If CHConn.Ping returns ErrBadConn, DB.Ping and DB.PingContext will remove
 // the CHConn from pool.
@@ -0,0 +1,77 @@
+package stream
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// Checkpoint is the input of a load from formatted input of which the insert was acknowledged by the server,
+// used to resume the load
+type Checkpoint struct {
+	// Offset is the number of bytes of input inserted
+	Offset int64 `json:"offset"`
+	// Rows is the number of rows of input inserted, including rows skipped
+	Rows int `json:"rows"`
+	// Done is true once the server acknowledged the end of the last insert of the load
+	Done bool `json:"done"`
+}
+
+// CheckpointStore saves the checkpoint of a load and loads it on restart
+type CheckpointStore interface {
+	// Load returns the saved checkpoint, ok is false if there is none
+	Load() (checkpoint Checkpoint, ok bool, err error)
+	Save(checkpoint Checkpoint) error
+}
+
+// FileCheckpointStore keeps the checkpoint as JSON in a local file
+type FileCheckpointStore struct {
+	path string
+}
+
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+func (s *FileCheckpointStore) Load() (Checkpoint, bool, error) {
+	var checkpoint Checkpoint
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return checkpoint, false, nil
+		}
+		return checkpoint, false, err
+	}
+	if err := json.Unmarshal(b, &checkpoint); err != nil {
+		return checkpoint, false, err
+	}
+	return checkpoint, true, nil
+}
+
+// Save writes the checkpoint to a temporary file renamed over the path,
+// so that a crash leaves either the previous or the new checkpoint
+func (s *FileCheckpointStore) Save(checkpoint Checkpoint) error {
+	b, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
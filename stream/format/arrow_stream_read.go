@@ -0,0 +1,102 @@
+package format
+
+import (
+	"context"
+	"io"
+	"log"
+	"runtime/debug"
+
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+)
+
+// ArrowStreamBlockStreamFmtReader reads record batches in the Arrow IPC streaming format into blocks,
+// columns of the table are matched to columns of the records by name.
+// Records are converted to blocks column by column without going through text, see data.ArrowRecordToBlock.
+type ArrowStreamBlockStreamFmtReader struct {
+	r io.Reader
+
+	totalRowsRead int
+	exception     error
+	done          chan struct{}
+}
+
+func NewArrowStreamBlockStreamFmtReader(r io.Reader) *ArrowStreamBlockStreamFmtReader {
+	return &ArrowStreamBlockStreamFmtReader{r: r}
+}
+
+// BlockStreamFmtRead reads each record into blocks of at most blockSize rows,
+// records are not split if blockSize is not positive
+func (a *ArrowStreamBlockStreamFmtReader) BlockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	stream := make(chan *data.Block, 1)
+	a.done = make(chan struct{}, 1)
+	go a.blockStreamFmtRead(ctx, sample, blockSize, stream)
+	return stream, a.yield
+}
+
+func (a *ArrowStreamBlockStreamFmtReader) blockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int, blockStream chan<- *data.Block,
+) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		a.done <- struct{}{}
+	}()
+	defer close(blockStream)
+	a.totalRowsRead, a.exception = a.readBlocks(ctx, sample, blockSize, blockStream)
+}
+
+func (a *ArrowStreamBlockStreamFmtReader) yield() (int, error) {
+	<-a.done
+	return a.totalRowsRead, a.exception
+}
+
+func (a *ArrowStreamBlockStreamFmtReader) readBlocks(
+	ctx context.Context, sample *data.Block, blockSize int, blockStream chan<- *data.Block,
+) (int, error) {
+	reader, err := ipc.NewReader(a.r)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Release()
+
+	var totalRowsRead int
+	for reader.Next() {
+		record := reader.Record()
+		numRows := record.NumRows()
+		step := numRows
+		if blockSize > 0 {
+			step = int64(blockSize)
+		}
+
+		for start := int64(0); start < numRows; start += step {
+			end := start + step
+			if end > numRows {
+				end = numRows
+			}
+			slice := record.NewSlice(start, end)
+			b, err := data.ArrowRecordToBlock(slice, sample)
+			slice.Release()
+			if err != nil {
+				return totalRowsRead, err
+			}
+
+			select {
+			case blockStream <- b:
+				totalRowsRead += b.NumRows
+			case <-ctx.Done():
+				b.Close()
+				return totalRowsRead, ctx.Err()
+			}
+		}
+	}
+	return totalRowsRead, reader.Err()
+}
@@ -0,0 +1,101 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestArrowStreamBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
+	input := writeBlocks(t, "ArrowStream",
+		[]string{"a", "b", "c"},
+		[]column.CHColumnType{"Int32", "Nullable(String)", "Nullable(Array(Int32))"},
+		[][]string{{"1", "2", "3"}, {"x", "NULL", "z"}, {"[1, 2]", "NULL", "[]"}},
+		3, nil,
+	)
+
+	tests := []struct {
+		name    string
+		names   []string
+		types   []column.CHColumnType
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:  "Should match columns by name",
+			names: []string{"c", "a"},
+			types: []column.CHColumnType{"Array(Int64)", "Int32"},
+			want:  [][]string{{"[1, 2]", "1"}, {"[]", "2"}, {"[]", "3"}},
+		},
+		{
+			name:  "Should read nulls as default of columns which are not nullable",
+			names: []string{"b"},
+			types: []column.CHColumnType{"String"},
+			want:  [][]string{{"x"}, {""}, {"z"}},
+		},
+		{
+			name:    "Should fail if a column is not in the stream",
+			names:   []string{"a", "d"},
+			types:   []column.CHColumnType{"Int32", "Int32"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readRowStrings(t, "ArrowStream", string(input), tt.names, tt.types)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestArrowStreamBlockStreamFmtReader_SplitsRecords(t *testing.T) {
+	input := writeBlocks(t, "ArrowStream", []string{"a"}, []column.CHColumnType{"UInt8"},
+		[][]string{{"1", "2", "3", "4", "5"}}, 5, nil)
+
+	sample, err := data.NewBlock([]string{"a"}, []column.CHColumnType{"UInt8"}, 0)
+	require.NoError(t, err)
+	blockStream, yield := NewArrowStreamBlockStreamFmtReader(bytes.NewReader(input)).
+		BlockStreamFmtRead(context.Background(), sample, 2)
+	var blockRows []int
+	for b := range blockStream {
+		blockRows = append(blockRows, b.NumRows)
+		b.Close()
+	}
+	n, err := yield()
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	require.Equal(t, []int{2, 2, 1}, blockRows)
+}
+
+func TestArrowStreamBlockStreamFmtReader_InvalidInput(t *testing.T) {
+	_, err := readRowStrings(t, "ArrowStream", "a,b\n1,2\n", []string{"a"}, []column.CHColumnType{"Int32"})
+	require.Error(t, err)
+}
+
+func TestArrowStreamBlockStreamFmtReader_Canceled(t *testing.T) {
+	input := writeBlocks(t, "ArrowStream", []string{"a"}, []column.CHColumnType{"UInt8"},
+		[][]string{{"1", "2", "3", "4", "5"}}, 1, nil)
+
+	sample, err := data.NewBlock([]string{"a"}, []column.CHColumnType{"UInt8"}, 0)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	blockStream, yield := NewArrowStreamBlockStreamFmtReader(bytes.NewReader(input)).BlockStreamFmtRead(ctx, sample, 1)
+	b := <-blockStream
+	b.Close()
+	cancel()
+	for b := range blockStream {
+		b.Close()
+	}
+	_, err = yield()
+	require.ErrorIs(t, err, context.Canceled)
+}
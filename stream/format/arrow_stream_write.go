@@ -0,0 +1,87 @@
+package format
+
+import (
+	"io"
+	"log"
+	"runtime/debug"
+
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+)
+
+// ArrowStreamBlockStreamFmtWriter writes blocks in the Arrow IPC streaming format, one record batch per block.
+// The schema is taken from the first block, see data.ArrowSchema for the mapping of types.
+type ArrowStreamBlockStreamFmtWriter struct {
+	w io.Writer
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewArrowStreamBlockStreamFmtWriter(w io.Writer) *ArrowStreamBlockStreamFmtWriter {
+	return &ArrowStreamBlockStreamFmtWriter{w: w}
+}
+
+func (a *ArrowStreamBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	a.done = make(chan struct{}, 1)
+	go a.blockStreamFmtWrite(blockStream)
+}
+
+func (a *ArrowStreamBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		a.done <- struct{}{}
+	}()
+	a.totalRowsWrite, a.exception = a.writeBlocks(blockStream)
+}
+
+func (a *ArrowStreamBlockStreamFmtWriter) Yield() (int, error) {
+	<-a.done
+	return a.totalRowsWrite, a.exception
+}
+
+// writeBlocks writes the schema and a record batch for each block with rows.
+// Nothing is written if blockStream has no blocks as there is no schema.
+func (a *ArrowStreamBlockStreamFmtWriter) writeBlocks(blockStream <-chan *data.Block) (int, error) {
+	defer func() {
+		for b := range blockStream {
+			b.Close()
+		}
+	}()
+
+	var (
+		totalRowsWrite int
+		writer         *ipc.Writer
+	)
+	for b := range blockStream {
+		if writer == nil {
+			writer = ipc.NewWriter(a.w, ipc.WithSchema(data.ArrowSchema(b.Columns)))
+		}
+
+		if b.NumRows > 0 {
+			record := data.BlockToArrowRecord(b)
+			err := writer.Write(record)
+			record.Release()
+			if err != nil {
+				b.Close()
+				return totalRowsWrite, err
+			}
+		}
+		totalRowsWrite += b.NumRows
+		b.Close()
+	}
+
+	if writer == nil {
+		return totalRowsWrite, nil
+	}
+	// closing writes the end of stream marker, w is not closed
+	return totalRowsWrite, writer.Close()
+}
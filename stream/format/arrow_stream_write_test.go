@@ -0,0 +1,85 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow/ipc"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestArrowStreamBlockStreamFmtWriter_RoundTrip(t *testing.T) {
+	names := []string{"i", "u", "s", "n", "lcn", "d", "dt", "dec", "arr", "m", "tup", "e"}
+	types := []column.CHColumnType{
+		"Int32", "UInt64", "String", "Nullable(Float64)", "LowCardinality(Nullable(String))", "Date", "DateTime('UTC')",
+		"Decimal(10, 2)", "Array(Array(String))", "Map(String, Nullable(Int8))", "Tuple(Int16, Date32)",
+		"Enum8('a' = 1, 'b' = 2)",
+	}
+	texts := [][]string{
+		{"-1", "2", "3"},
+		{"18446744073709551615", "0", "7"},
+		{"NULL", "a,'b'", ""},
+		{"1.5", "NULL", "-2"},
+		{"x", "NULL", "x"},
+		{"2022-01-02", "1970-01-01", "2100-12-31"},
+		{"2022-01-02 03:04:05", "1970-01-01 00:00:00", "2038-01-19 03:14:07"},
+		{"12.34", "-0.01", "0"},
+		{"[['a'], []]", "[]", "[['b', 'c']]"},
+		{"{'k': 1, 'l': NULL}", "{}", "{'m': -1}"},
+		{"(1, '2022-01-02')", "(-2, '1970-01-01')", "(3, '1969-12-31')"},
+		{"a", "b", "a"},
+	}
+
+	for _, rowsPerBlock := range []int{1, 2, 3} {
+		output := writeBlocks(t, "ArrowStream", names, types, texts, rowsPerBlock, nil)
+
+		reader, err := ipc.NewReader(bytes.NewReader(output))
+		require.NoError(t, err)
+		var records int
+		for reader.Next() {
+			records++
+		}
+		require.NoError(t, reader.Err())
+		reader.Release()
+		require.Equal(t, (3+rowsPerBlock-1)/rowsPerBlock, records)
+
+		rows, err := readRowStrings(t, "ArrowStream", string(output), names, types)
+		require.NoError(t, err)
+		want, err := data.NewBlock(names, types, 3)
+		require.NoError(t, err)
+		_, _, err = want.ReadFromColumnTexts(texts)
+		require.NoError(t, err)
+		require.Len(t, rows, 3)
+		for i, row := range rows {
+			for j, col := range want.Columns {
+				require.Equal(t, col.Data.GetString(i), row[j], "row %v column %v", i, names[j])
+			}
+		}
+		want.Close()
+	}
+}
+
+func TestArrowStreamBlockStreamFmtWriter_NoRows(t *testing.T) {
+	blockStream := make(chan *data.Block, 1)
+	b, err := data.NewBlock([]string{"a", "b"}, []column.CHColumnType{"Int32", "Nullable(String)"}, 0)
+	require.NoError(t, err)
+	blockStream <- b
+	close(blockStream)
+
+	var buf bytes.Buffer
+	w := NewArrowStreamBlockStreamFmtWriter(&buf)
+	w.BlockStreamFmtWrite(blockStream)
+	n, err := w.Yield()
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	reader, err := ipc.NewReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	defer reader.Release()
+	require.Equal(t, []string{"a", "b"}, []string{reader.Schema().Field(0).Name, reader.Schema().Field(1).Name})
+	require.False(t, reader.Next())
+	require.NoError(t, reader.Err())
+}
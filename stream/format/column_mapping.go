@@ -0,0 +1,165 @@
+package format
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+const (
+	// withNamesUseHeaderSetting matches the columns of the header of CSVWithNames to the table columns by name
+	withNamesUseHeaderSetting = "input_format_with_names_use_header"
+	// skipUnknownFieldsSetting set to false fails rows with columns which are not in the table, instead of skipping them
+	skipUnknownFieldsSetting = "input_format_skip_unknown_fields"
+)
+
+// ColumnDefaultsSetter is implemented by readers of formats in which columns may be omitted, such as JSON, JSONEachRow
+// and CSVWithNames. Omitted columns are filled with the defaults of the table columns instead of those of their
+// types, if the defaults are literals. Omitting a column of which the default is another expression, such as now(),
+// fails the row, as the expression is only evaluated by the server for columns left out of the INSERT query.
+type ColumnDefaultsSetter interface {
+	// SetColumnDefaults sets the default expressions of the table columns by name,
+	// before reading starts, such as those of response.TableColumnsPacket
+	SetColumnDefaults(exprs map[string]string)
+}
+
+// columnDefaults are the defaults of table columns by name
+type columnDefaults struct {
+	// texts are the texts of the defaults which are literals
+	texts map[string]string
+	// exprs are the defaults which are not literals
+	exprs map[string]string
+}
+
+// newColumnDefaults returns the texts of the default expressions which are literals, and the other expressions
+func newColumnDefaults(exprs map[string]string) columnDefaults {
+	defaults := columnDefaults{texts: make(map[string]string), exprs: make(map[string]string)}
+	for name, expr := range exprs {
+		if text, ok := literalText(expr); ok {
+			defaults.texts[name] = text
+		} else {
+			defaults.exprs[name] = expr
+		}
+	}
+	return defaults
+}
+
+// text returns the text of the default of col, or typeDefault of col if the table has none.
+// It fails if the default of col is not a literal, which cannot be evaluated by the reader.
+func (d columnDefaults) text(col *column.CHColumn, typeDefault func(col *column.CHColumn) string) (string, error) {
+	if text, ok := d.texts[col.Name]; ok {
+		return text, nil
+	}
+	if expr, ok := d.exprs[col.Name]; ok {
+		return "", errors.ErrorfWithCaller(
+			"column %v is omitted and its default is not a literal: %v, "+
+				"list the columns of the input in the INSERT query to have it evaluated by the server", col.Name, expr,
+		)
+	}
+	return typeDefault(col), nil
+}
+
+// literalText returns the text of expr if it is a number, a string or NULL, e.g. 1, -0.5, 'x' or NULL
+func literalText(expr string) (string, bool) {
+	expr = strings.TrimSpace(expr)
+	if strings.EqualFold(expr, column.NULL) {
+		return column.NULL, true
+	}
+	if _, err := strconv.ParseFloat(expr, 64); err == nil {
+		return expr, true
+	}
+	if len(expr) < 2 || expr[0] != '\'' || expr[len(expr)-1] != '\'' {
+		return "", false
+	}
+
+	var sb strings.Builder
+	quoted := expr[1 : len(expr)-1]
+	for i := 0; i < len(quoted); i++ {
+		b := quoted[i]
+		switch {
+		case b == '\\' && i+1 < len(quoted):
+			i++
+			b = unescapeLiteralByte(quoted[i])
+		case b == '\'':
+			return "", false // the string ends before expr, e.g. 'a' || 'b'
+		}
+		sb.WriteByte(b)
+	}
+	return sb.String(), true
+}
+
+func unescapeLiteralByte(b byte) byte {
+	switch b {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case '0':
+		return 0
+	}
+	return b
+}
+
+// headerMapping maps the columns of input named by a header to the table columns
+type headerMapping struct {
+	// inputCols are the table columns of the input columns, String columns for unknown ones
+	inputCols []*column.CHColumn
+	// inputIdx are the indexes of the table columns in input, -1 if omitted
+	inputIdx []int
+}
+
+// newHeaderMapping returns the mapping of names of the header to cols, nil if they are cols in order.
+// Names of unknown columns fail unless skipUnknown.
+func newHeaderMapping(names []string, cols []*column.CHColumn, skipUnknown bool) (*headerMapping, error) {
+	if sameColumnNames(names, cols) {
+		return nil, nil
+	}
+
+	colIdxByName := make(map[string]int, len(cols))
+	for i, col := range cols {
+		colIdxByName[col.Name] = i
+	}
+	m := &headerMapping{
+		inputCols: make([]*column.CHColumn, len(names)),
+		inputIdx:  make([]int, len(cols)),
+	}
+	for i := range m.inputIdx {
+		m.inputIdx[i] = -1
+	}
+	for i, name := range names {
+		colIdx, ok := colIdxByName[name]
+		if !ok {
+			if !skipUnknown {
+				return nil, errors.ErrorfWithCaller("unknown column in header: %q", name)
+			}
+			m.inputCols[i] = &column.CHColumn{
+				Name: name,
+				Type: column.STRING,
+				Data: column.MustMakeColumnData(column.STRING, 0),
+			}
+			continue
+		}
+		if m.inputIdx[colIdx] >= 0 {
+			return nil, errors.ErrorfWithCaller("duplicate column in header: %q", name)
+		}
+		m.inputIdx[colIdx] = i
+		m.inputCols[i] = cols[colIdx]
+	}
+	return m, nil
+}
+
+func sameColumnNames(names []string, cols []*column.CHColumn) bool {
+	if len(names) != len(cols) {
+		return false
+	}
+	for i, col := range cols {
+		if names[i] != col.Name {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,33 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiteralText(t *testing.T) {
+	tests := []struct {
+		expr   string
+		want   string
+		wantOk bool
+	}{
+		{expr: "1", want: "1", wantOk: true},
+		{expr: " -0.5 ", want: "-0.5", wantOk: true},
+		{expr: "null", want: "NULL", wantOk: true},
+		{expr: "'x'", want: "x", wantOk: true},
+		{expr: `'it\'s\n'`, want: "it's\n", wantOk: true},
+		{expr: "''", want: "", wantOk: true},
+		{expr: "'a' || 'b'"},
+		{expr: "now()"},
+		{expr: "a + 1"},
+		{expr: "'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, ok := literalText(tt.expr)
+			require.Equal(t, tt.wantOk, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
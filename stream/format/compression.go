@@ -0,0 +1,186 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+// CompressionSetting is the setting of the compression of input and output of formats,
+// one of the Compression methods, case-insensitive
+const CompressionSetting = "compression"
+
+const (
+	// CompressionAuto detects the compression of input by its magic bytes, output is not compressed
+	CompressionAuto  = "auto"
+	CompressionNone  = "none"
+	CompressionGzip  = "gzip"
+	CompressionZstd  = "zstd"
+	CompressionLZ4   = "lz4"
+	CompressionBzip2 = "bz2"
+	CompressionXz    = "xz"
+)
+
+// compressionAliases maps file extensions and other names of compression methods to their method
+var compressionAliases = map[string]string{
+	"":      CompressionAuto,
+	"gz":    CompressionGzip,
+	"zst":   CompressionZstd,
+	"bzip2": CompressionBzip2,
+}
+
+// compressionMagics are the first bytes of the compression methods which can be detected
+var compressionMagics = []struct {
+	method string
+	magic  []byte
+}{
+	{method: CompressionGzip, magic: []byte{0x1f, 0x8b, 0x08}}, // with the deflate method
+	{method: CompressionZstd, magic: []byte{0x28, 0xb5, 0x2f, 0xfd}},
+	{method: CompressionLZ4, magic: []byte{0x04, 0x22, 0x4d, 0x18}},
+	{method: CompressionXz, magic: []byte{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}},
+}
+
+// bzip2BlockMagic follows the header "BZh" and the block size of bzip2 streams, which are checked separately
+var bzip2BlockMagic = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59}
+
+// compressionMagicLen is the number of bytes read ahead to detect compression
+const compressionMagicLen = 10
+
+// resolveCompression returns the compression method in settings, CompressionAuto if not set
+func resolveCompression(settings map[string]interface{}) (string, error) {
+	v, ok := settings[CompressionSetting]
+	if !ok {
+		return CompressionAuto, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", errors.ErrorfWithCaller("expected type: string for %v, got: %T", CompressionSetting, v)
+	}
+
+	method := strings.ToLower(s)
+	if alias, ok := compressionAliases[method]; ok {
+		method = alias
+	}
+	switch method {
+	case CompressionAuto, CompressionNone, CompressionGzip, CompressionZstd, CompressionLZ4, CompressionBzip2, CompressionXz:
+		return method, nil
+	}
+	return "", errors.ErrorfWithCaller("unknown %v: %v, expected one of auto, none, gzip, zstd, lz4, bz2, xz", CompressionSetting, s)
+}
+
+// NewDecompressReader returns the decompressed content of r, compressed with method.
+// If method is CompressionAuto, the compression is detected from the first bytes of r,
+// r is returned as is if they are not the magic bytes of a compression method.
+func NewDecompressReader(r io.Reader, method string) (io.Reader, error) {
+	if method == CompressionAuto {
+		br := bufio.NewReader(r)
+		method = detectCompression(br)
+		r = br
+	}
+
+	switch method {
+	case CompressionAuto, CompressionNone:
+		return r, nil
+	case CompressionGzip:
+		return gzip.NewReader(r)
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &zstdReader{decoder: decoder}, nil
+	case CompressionLZ4:
+		return lz4.NewReader(r), nil
+	case CompressionBzip2:
+		return bzip2.NewReader(r), nil
+	case CompressionXz:
+		return xz.NewReader(r)
+	default:
+		return nil, errors.ErrorfWithCaller("unknown compression: %v", method)
+	}
+}
+
+// detectCompression returns the compression method of which br starts with the magic bytes, CompressionNone if none
+func detectCompression(br *bufio.Reader) string {
+	// Peek returns fewer bytes with an error if the input is shorter
+	head, _ := br.Peek(compressionMagicLen)
+	for _, m := range compressionMagics {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.method
+		}
+	}
+	if len(head) == compressionMagicLen && string(head[:3]) == "BZh" && head[3] >= '1' && head[3] <= '9' &&
+		bytes.Equal(head[4:], bzip2BlockMagic) {
+		return CompressionBzip2
+	}
+	return CompressionNone
+}
+
+// zstdReader releases the decoder once the input ends or fails, as nothing closes readers of input
+type zstdReader struct {
+	decoder *zstd.Decoder
+}
+
+func (z *zstdReader) Read(p []byte) (int, error) {
+	if z.decoder == nil {
+		return 0, io.EOF
+	}
+	n, err := z.decoder.Read(p)
+	if err != nil {
+		z.decoder.Close()
+		z.decoder = nil
+	}
+	return n, err
+}
+
+// NewCompressWriter returns a writer compressing to w with method, which has to be closed to write the end of
+// the compressed stream. It does not close w. Nothing is compressed with CompressionAuto and CompressionNone.
+func NewCompressWriter(w io.Writer, method string) (io.WriteCloser, error) {
+	switch method {
+	case CompressionAuto, CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	case CompressionLZ4:
+		return lz4.NewWriter(w), nil
+	case CompressionXz:
+		return xz.NewWriter(w)
+	case CompressionBzip2:
+		return nil, errors.ErrorfWithCaller("%v compression is only supported for input", method)
+	default:
+		return nil, errors.ErrorfWithCaller("unknown compression: %v", method)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// compressedBlockStreamFmtWriter closes the compressed output of BlockStreamFmtWriter once all blocks are written
+type compressedBlockStreamFmtWriter struct {
+	BlockStreamFmtWriter
+	w io.WriteCloser
+}
+
+func (c *compressedBlockStreamFmtWriter) Yield() (int, error) {
+	n, err := c.BlockStreamFmtWriter.Yield()
+	if closeErr := c.w.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
@@ -0,0 +1,128 @@
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// bzip2CSV is "a,b\n1,x\n2,y\n" compressed with bzip2 -9
+var bzip2CSV = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0xbc, 0xc7, 0x28, 0x45, 0x00, 0x00, 0x04, 0x59,
+	0x80, 0x00, 0x10, 0x00, 0x04, 0x30, 0x00, 0x30, 0x00, 0x00, 0x60, 0x20, 0x00, 0x31, 0x0c, 0x08, 0x23, 0x41,
+	0x9a, 0x8e, 0x04, 0x22, 0x17, 0x8b, 0xb9, 0x22, 0x9c, 0x28, 0x48, 0x5e, 0x63, 0x94, 0x22, 0x80,
+}
+
+func TestCompression_RoundTrip(t *testing.T) {
+	names := []string{"a", "b"}
+	types := []column.CHColumnType{"Int32", "String"}
+	texts := [][]string{{"1", "2", "3"}, {"x", "y", "z"}}
+	want := [][]string{{"1", "x"}, {"2", "y"}, {"3", "z"}}
+
+	for _, method := range []string{"gzip", "ZSTD", "lz4", "xz", "gz", "zst"} {
+		t.Run(method, func(t *testing.T) {
+			settings := map[string]interface{}{CompressionSetting: method}
+			output := writeBlocks(t, "CSV", names, types, texts, 2, settings)
+			require.False(t, strings.HasPrefix(string(output), "1,"))
+
+			// detected by magic bytes
+			rows, err := readRowStrings(t, "CSV", string(output), names, types)
+			require.NoError(t, err)
+			require.Equal(t, want, rows)
+
+			rows, err = readRowStringsWithSettings(t, "CSV", output, names, types, settings)
+			require.NoError(t, err)
+			require.Equal(t, want, rows)
+		})
+	}
+}
+
+func TestCompression_Bzip2Input(t *testing.T) {
+	want := [][]string{{"1", "x"}, {"2", "y"}}
+	names := []string{"a", "b"}
+	types := []column.CHColumnType{"Int32", "String"}
+
+	rows, err := readRowStrings(t, "CSVWithNames", string(bzip2CSV), names, types)
+	require.NoError(t, err)
+	require.Equal(t, want, rows)
+
+	_, err = BlockStreamFmtWriterFactory("CSV", io.Discard, map[string]interface{}{CompressionSetting: "bz2"})
+	require.Error(t, err)
+}
+
+func TestCompression_NotDetected(t *testing.T) {
+	names := []string{"a"}
+	types := []column.CHColumnType{"String"}
+
+	// text which starts like the header of bzip2
+	rows, err := readRowStrings(t, "CSV", "BZh9 is not compressed\n", names, types)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"BZh9 is not compressed"}}, rows)
+
+	// plain input with compression none
+	rows, err = readRowStringsWithSettings(t, "CSV", []byte("x\n"), names, types, map[string]interface{}{CompressionSetting: "none"})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"x"}}, rows)
+
+	// binary formats are not detected
+	native := writeBlocks(t, "Native", names, types, [][]string{{"x"}}, 1, map[string]interface{}{CompressionSetting: "gzip"})
+	_, err = readRowStrings(t, "Native", string(native), names, types)
+	require.Error(t, err)
+	rows, err = readRowStringsWithSettings(t, "Native", native, names, types, map[string]interface{}{CompressionSetting: "gzip"})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"x"}}, rows)
+}
+
+func TestDetectCompression(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  string
+	}{
+		{name: "gzip", input: []byte{0x1f, 0x8b, 0x08, 0x00}, want: CompressionGzip},
+		{name: "zstd", input: []byte{0x28, 0xb5, 0x2f, 0xfd, 0x00}, want: CompressionZstd},
+		{name: "lz4", input: []byte{0x04, 0x22, 0x4d, 0x18}, want: CompressionLZ4},
+		{name: "bzip2", input: bzip2CSV, want: CompressionBzip2},
+		{name: "xz", input: []byte{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00, 0x00}, want: CompressionXz},
+		{name: "short bzip2 header", input: []byte("BZh9"), want: CompressionNone},
+		{name: "text", input: []byte("a,b\n"), want: CompressionNone},
+		{name: "empty", want: CompressionNone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, detectCompression(bufio.NewReader(bytes.NewReader(tt.input))))
+		})
+	}
+}
+
+func TestResolveCompression(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     string
+		wantErr  bool
+	}{
+		{name: "Should default to auto", want: CompressionAuto},
+		{name: "Should be case-insensitive", settings: map[string]interface{}{CompressionSetting: "GZip"}, want: CompressionGzip},
+		{name: "Should resolve aliases", settings: map[string]interface{}{CompressionSetting: "bzip2"}, want: CompressionBzip2},
+		{name: "Should reject unknown methods", settings: map[string]interface{}{CompressionSetting: "rar"}, wantErr: true},
+		{name: "Should reject other types", settings: map[string]interface{}{CompressionSetting: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveCompression(tt.settings)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -3,7 +3,9 @@ package format
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
@@ -21,20 +23,36 @@ type CSVBlockStreamFmtReader struct {
 	zReader   *bytepool.ZReader
 	delimiter byte
 	withNames bool
+	settings  *csvSettings
+	helper.RowRecorder
+
+	// field holds the unquoted field being read, which is trimmed or replaced before written to the frame
+	field bytes.Buffer
+	// rowEnded is set when the current row ends before its last column
+	rowEnded bool
+
+	// mapping maps the columns of the header to the table columns if they differ, see csvSettings.useHeader
+	mapping *headerMapping
+	// inputRow holds the texts of a row in the order of the header, if mapped
+	inputRow *bytepool.FrameBuffer
+	defaults columnDefaults
 }
 
 func NewCSVBlockStreamFmtReader(
 	input io.Reader, withNames bool, settings map[string]interface{},
 ) (*CSVBlockStreamFmtReader, error) {
-	delim, err := resolveCSVDelim(settings)
+	s, err := resolveCSVSettings(settings)
 	if err != nil {
 		return nil, err
 	}
 
+	zReader := bytepool.NewZReaderDefault(&input)
 	return &CSVBlockStreamFmtReader{
-		zReader:   bytepool.NewZReaderDefault(&input),
-		delimiter: delim,
-		withNames: withNames,
+		zReader:     zReader,
+		delimiter:   s.delimiter,
+		withNames:   withNames,
+		settings:    s,
+		RowRecorder: helper.NewRowRecorder(zReader),
 	}, nil
 }
 
@@ -44,18 +62,57 @@ func (c *CSVBlockStreamFmtReader) BlockStreamFmtRead(
 	return helper.TableToBlockStream(ctx, sample, blockSize, c)
 }
 
+func (c *CSVBlockStreamFmtReader) BlockStreamFmtReadWithOptions(
+	ctx context.Context, sample *data.Block, opts helper.ReadOptions,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStreamWithOptions(ctx, sample, opts, c)
+}
+
 func (c *CSVBlockStreamFmtReader) ReadFirstRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
-	if c.withNames {
-		if err := helper.DiscardUntilByteEscaped(c.zReader, '\n'); err != nil {
-			return err
-		}
+	if err := c.readHead(cols); err != nil {
+		return err
 	}
 
-	return helper.ReadRow(fb, cols, c)
+	return c.readRow(fb, cols)
 }
 
 func (c *CSVBlockStreamFmtReader) ReadRowCont(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
-	return helper.ReadRow(fb, cols, c)
+	return c.readRow(fb, cols)
+}
+
+// SetColumnDefaults sets the defaults of columns omitted in the header or in rows
+func (c *CSVBlockStreamFmtReader) SetColumnDefaults(exprs map[string]string) {
+	c.defaults = newColumnDefaults(exprs)
+}
+
+// readRow reads a row into the texts of cols, reordered from the columns of the header if mapped
+func (c *CSVBlockStreamFmtReader) readRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	c.StartRow()
+	if c.mapping == nil {
+		return helper.ReadRow(fb, cols, c)
+	}
+
+	if c.inputRow == nil {
+		c.inputRow = bytepool.NewFrameBuffer()
+	}
+	c.inputRow.TruncateElem(0)
+	if err := helper.ReadRow(c.inputRow, c.mapping.inputCols, c); err != nil {
+		return err
+	}
+	texts := c.inputRow.StringsBuffer.Export()
+	for i, col := range cols {
+		fb.NewElem()
+		if j := c.mapping.inputIdx[i]; j >= 0 {
+			fb.WriteString(texts[j])
+			continue
+		}
+		text, err := c.defaults.text(col, csvDefaultText)
+		if err != nil {
+			return err
+		}
+		fb.WriteString(text)
+	}
+	return nil
 }
 
 func (c *CSVBlockStreamFmtReader) ReadFirstColumnTexts(
@@ -70,47 +127,195 @@ func (c *CSVBlockStreamFmtReader) ReadColumnTextsCont(
 	return helper.ReadColumnTextsCont(fb, numRows, cols, c)
 }
 
-func (c *CSVBlockStreamFmtReader) ReadElem(fb *bytepool.FrameBuffer, cols []*column.CHColumn, idx int) error {
-	if idx > 0 {
-		if err := helper.AssertNextByteEqual(c.zReader, c.delimiter); err != nil {
+func (c *CSVBlockStreamFmtReader) InputPosition() (int64, int) {
+	return c.zReader.Position(), c.zReader.Line()
+}
+
+// ResumeAt discards the header if any and the input until offset
+func (c *CSVBlockStreamFmtReader) ResumeAt(offset int64, cols []*column.CHColumn) error {
+	if err := c.readHead(cols); err != nil {
+		return err
+	}
+	return helper.DiscardUntilPosition(c.zReader, offset)
+}
+
+// readHead discards the lines to skip, and the header if any unless its columns are mapped to cols
+func (c *CSVBlockStreamFmtReader) readHead(cols []*column.CHColumn) error {
+	for i := 0; i < c.settings.skipFirstLines; i++ {
+		if err := helper.DiscardLine(c.zReader); err != nil {
 			return err
 		}
 	}
-	isSingleCol := len(cols) == 1
-	isLast := (len(cols) - 1) == idx
-	return c.readElem(fb, cols[idx], isLast, isSingleCol)
+	if !c.withNames {
+		return nil
+	}
+	if !c.settings.useHeader {
+		return helper.DiscardUntilByteEscaped(c.zReader, '\n')
+	}
+
+	var header strings.Builder
+	n, err := helper.ReadStringUntilByte(&header, c.zReader, '\n')
+	if err != nil && (err != io.EOF || n == 0) {
+		return err
+	}
+	c.mapping, err = newHeaderMapping(c.splitHeader(header.String()), cols, c.settings.skipUnknown)
+	return err
 }
 
-func (c *CSVBlockStreamFmtReader) readElem(
-	fb *bytepool.FrameBuffer, col *column.CHColumn, last bool, singleCol bool,
-) error {
+// splitHeader returns the names of columns in the header, which may be quoted
+func (c *CSVBlockStreamFmtReader) splitHeader(header string) []string {
 	var (
-		b   byte
-		err error
+		names []string
+		name  strings.Builder
+		quote byte
 	)
+	header = strings.TrimSuffix(header, "\r")
+	for i := 0; i < len(header); i++ {
+		b := header[i]
+		switch {
+		case quote != 0:
+			if b != quote {
+				name.WriteByte(b)
+				continue
+			}
+			if i+1 < len(header) && header[i+1] == quote { // escaped quote
+				name.WriteByte(b)
+				i++
+				continue
+			}
+			quote = 0
+		case b == c.delimiter:
+			names = append(names, strings.TrimSpace(name.String()))
+			name.Reset()
+		case c.settings.isQuote(b) && strings.TrimSpace(name.String()) == "":
+			quote = b
+			name.Reset()
+		default:
+			name.WriteByte(b)
+		}
+	}
+	return append(names, strings.TrimSpace(name.String()))
+}
 
-	if !singleCol && last {
-		b, err = helper.ReadNextNonSpaceExceptNewLineByte(c.zReader)
-		if err != nil {
-			if err == io.EOF {
-				return nil
+// KeepSpaces keeps spaces of texts, as the reader trims unquoted fields itself, quoted fields are kept as is
+func (c *CSVBlockStreamFmtReader) KeepSpaces() bool {
+	return true
+}
+
+// SkipRow discards the rest of current line, unless it has been read until newline already
+func (c *CSVBlockStreamFmtReader) SkipRow() error {
+	if c.zReader.AtLineStart() {
+		return nil
+	}
+	return helper.DiscardLine(c.zReader)
+}
+
+func (c *CSVBlockStreamFmtReader) ReadElem(fb *bytepool.FrameBuffer, cols []*column.CHColumn, idx int) error {
+	if idx == 0 {
+		c.rowEnded = false
+		if c.settings.commentChar != 0 {
+			if err := c.discardCommentLines(); err != nil {
+				return err
 			}
+		}
+	} else if err := c.readDelimiter(); err != nil {
+		return err
+	}
+	if c.rowEnded { // missing columns are filled with defaults
+		text, err := c.defaults.text(cols[idx], csvDefaultText)
+		if err != nil {
 			return err
 		}
-	} else {
-		b, err = helper.ReadNextNonSpaceByte(c.zReader)
+		_, err = fb.WriteString(text)
+		return err
+	}
+
+	isSingleCol := len(cols) == 1
+	isLast := (len(cols) - 1) == idx
+	if err := c.readElem(fb, cols[idx], isLast, isSingleCol); err != nil {
+		return err
+	}
+	if isLast && c.settings.variableColumns {
+		return c.discardExtraColumns()
+	}
+	return nil
+}
+
+// readDelimiter reads the delimiter before the next field,
+// or the end of row if the number of columns may vary
+func (c *CSVBlockStreamFmtReader) readDelimiter() error {
+	if !c.settings.variableColumns {
+		return helper.AssertNextByteEqualSameLine(c.zReader, c.delimiter)
+	}
+	if c.rowEnded {
+		return nil
+	}
+
+	b, err := helper.ReadNextNonSpaceExceptNewLineByte(c.zReader)
+	switch {
+	case err == io.EOF, err == nil && b == '\n':
+		c.rowEnded = true
+		return nil
+	case err != nil:
+		return err
+	case b != c.delimiter:
+		return fmt.Errorf("expect byte: %q, but got: %q", c.delimiter, b)
+	}
+	return nil
+}
+
+// discardExtraColumns discards the rest of the row after the last column
+func (c *CSVBlockStreamFmtReader) discardExtraColumns() error {
+	b, err := helper.ReadNextNonSpaceExceptNewLineByte(c.zReader)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if b != c.delimiter {
+		c.zReader.UnreadCurrentBuffer(1)
+		return nil
+	}
+	if _, err := helper.DiscardUntilUnnested(c.zReader, "\n"); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// discardCommentLines discards blank lines and the lines starting with the comment char before the next row
+func (c *CSVBlockStreamFmtReader) discardCommentLines() error {
+	for {
+		b, err := c.zReader.ReadByte()
 		if err != nil {
 			return err
 		}
+		switch b {
+		case '\n', '\r':
+			continue
+		case c.settings.commentChar:
+			if err := helper.DiscardLine(c.zReader); err != nil {
+				return err
+			}
+			continue
+		}
+		c.zReader.UnreadCurrentBuffer(1)
+		return nil
 	}
+}
 
-	var quoted bool
-	switch b {
-	case '"', '\'', '`':
-		quoted = true
+func (c *CSVBlockStreamFmtReader) readElem(
+	fb *bytepool.FrameBuffer, col *column.CHColumn, last bool, singleCol bool,
+) error {
+	b, err := c.readFirstByte(!singleCol && last)
+	if err != nil {
+		if err == io.EOF && !singleCol && last {
+			return c.writeUnquoted(fb, col)
+		}
+		return err
 	}
 
-	if quoted {
+	if c.settings.isQuote(b) {
 		return c.readElemUntilQuote(fb, b, col)
 	}
 
@@ -118,6 +323,23 @@ func (c *CSVBlockStreamFmtReader) readElem(
 	return c.readElemWithoutQuote(fb, col, last)
 }
 
+// readFirstByte reads the first byte of a field, after whitespaces if they are trimmed.
+// Newlines are skipped unless the field is sameLine.
+func (c *CSVBlockStreamFmtReader) readFirstByte(sameLine bool) (byte, error) {
+	if c.settings.trimWhitespaces {
+		if sameLine {
+			return helper.ReadNextNonSpaceExceptNewLineByte(c.zReader)
+		}
+		return helper.ReadNextNonSpaceByte(c.zReader)
+	}
+	for {
+		b, err := c.zReader.ReadByte()
+		if err != nil || sameLine || (b != '\n' && b != '\r') {
+			return b, err
+		}
+	}
+}
+
 // readElemUntilQuote notFirstRow from underlying reader until quote is found, return the string notFirstRow excluding quote
 func (c *CSVBlockStreamFmtReader) readElemUntilQuote(fb *bytepool.FrameBuffer, quote byte, col *column.CHColumn) error {
 	switch col.Data.(type) {
@@ -188,15 +410,28 @@ func (c *CSVBlockStreamFmtReader) readStringQuoteIndexed(
 func (c *CSVBlockStreamFmtReader) readElemWithoutQuote(
 	fb *bytepool.FrameBuffer, col *column.CHColumn, last bool,
 ) (err error) {
-	if last {
-		return c.readLastElemWithoutQuote(fb)
+	c.field.Reset()
+	switch {
+	case isNestedColumn(col.Data):
+		err = helper.ReadCHElemTillStop(&c.field, c.zReader, col.Data, c.delimiter)
+	case c.settings.variableColumns:
+		err = c.readUnquotedTillRowEnd()
+	case last:
+		err = c.readLastElemWithoutQuote()
+	default:
+		_, err = helper.ReadStringUntilByte(&c.field, c.zReader, c.delimiter)
+		if err == nil {
+			c.zReader.UnreadCurrentBuffer(1)
+		}
 	}
-
-	return helper.ReadCHElemTillStop(fb, c.zReader, col.Data, c.delimiter)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return c.writeUnquoted(fb, col)
 }
 
-func (c *CSVBlockStreamFmtReader) readLastElemWithoutQuote(fb *bytepool.FrameBuffer) error {
-	_, err := helper.ReadStringUntilByte(fb, c.zReader, '\n')
+func (c *CSVBlockStreamFmtReader) readLastElemWithoutQuote() error {
+	_, err := helper.ReadStringUntilByte(&c.field, c.zReader, '\n')
 	switch err {
 	case nil:
 		//c.zReader.UnreadCurrentBuffer(1)
@@ -208,6 +443,64 @@ func (c *CSVBlockStreamFmtReader) readLastElemWithoutQuote(fb *bytepool.FrameBuf
 	}
 }
 
+// readUnquotedTillRowEnd reads until(excluding) the delimiter, newline or EOF
+func (c *CSVBlockStreamFmtReader) readUnquotedTillRowEnd() error {
+	for {
+		buf, err := c.zReader.ReadNextBuffer()
+		if err != nil {
+			return err
+		}
+		i := bytes.IndexAny(buf, string([]byte{c.delimiter, '\n'}))
+		if i < 0 {
+			c.field.Write(buf)
+			continue
+		}
+		c.field.Write(buf[:i])
+		c.zReader.UnreadCurrentBuffer(len(buf) - i)
+		return nil
+	}
+}
+
+// writeUnquoted writes the unquoted field read, without the carriage return before newline and trimmed whitespaces,
+// as NULL or the default value if it is the representation of NULL
+func (c *CSVBlockStreamFmtReader) writeUnquoted(fb *bytepool.FrameBuffer, col *column.CHColumn) error {
+	defer c.field.Reset()
+	field := bytes.TrimSuffix(c.field.Bytes(), []byte{'\r'})
+	if c.settings.trimWhitespaces {
+		field = bytes.TrimRight(field, " \t")
+	}
+
+	if c.settings.hasNullRepr && string(field) == c.settings.nullRepr {
+		text, err := c.defaults.text(col, csvDefaultText)
+		if err != nil {
+			return err
+		}
+		_, err = fb.WriteString(text)
+		return err
+	}
+	_, err := fb.Write(field)
+	return err
+}
+
+// csvDefaultText returns the text of the default value of col, NULL if nullable
+func csvDefaultText(col *column.CHColumn) string {
+	if _, ok := col.Data.(*column.NullableColumnData); ok {
+		return column.NULL
+	}
+	return col.Data.ZeroString()
+}
+
+// isNestedColumn tells if data holds arrays or maps, of which unquoted fields may contain delimiters in brackets
+func isNestedColumn(data column.CHColumnData) bool {
+	switch data := data.(type) {
+	case *column.ArrayColumnData, *column.MapColumnData:
+		return true
+	case *column.NullableColumnData:
+		return isNestedColumn(data.GetInnerColumnData())
+	}
+	return false
+}
+
 // readStringUntilQuote reads with CSV escape protocol: if quote appears consecutively, it's escaped.
 func (c *CSVBlockStreamFmtReader) readStringUntilQuote(fb *bytepool.FrameBuffer, quote byte) error {
 	buf, err := c.zReader.ReadNextBuffer()
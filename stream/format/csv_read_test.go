@@ -5,12 +5,16 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
 )
 
 func TestCSVBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
@@ -733,3 +737,284 @@ func makeArrayBlock() *data.Block {
 	}
 	return block
 }
+
+func TestCSVBlockStreamFmtReader_RowErrors(t *testing.T) {
+	input := bytes.NewReader([]byte("1,a\nx,b\n3,c\n4.5,d\n5,e\n"))
+	r, err := NewCSVBlockStreamFmtReader(input, false, nil)
+	require.NoError(t, err)
+
+	values, rowErrs, progress := readSkippingRowErrors(t, r)
+	require.Equal(t, []interface{}{int32(1), int32(3), int32(5)}, values)
+	require.Equal(t, []int{2, 4}, rowErrorLines(rowErrs))
+	require.Equal(t, "a", rowErrs[0].Column)
+	require.Equal(t, "x", rowErrs[0].Value)
+	require.Equal(t, []string{"x", "b"}, rowErrs[0].Texts)
+	require.Equal(t, 5, progress.RowsRead())
+	require.Equal(t, 2, progress.RowsSkipped())
+	require.Equal(t, int64(input.Size()), progress.BytesRead())
+}
+
+// readSkippingRowErrors reads r into blocks of 2 rows of (a Int32, b String),
+// returns values of a and the row errors sorted by row
+func readSkippingRowErrors(t *testing.T, r BlockStreamFmtReader) ([]interface{}, []*helper.RowError, *helper.ReadProgress) {
+	sample, err := data.NewBlock([]string{"a", "b"}, []column.CHColumnType{"Int32", "String"}, 0)
+	require.NoError(t, err)
+
+	var (
+		mu       sync.Mutex
+		rowErrs  []*helper.RowError
+		progress helper.ReadProgress
+	)
+	blockStream, yield := BlockStreamFmtReadWithOptions(context.Background(), r, sample, helper.ReadOptions{
+		Limits: helper.BlockLimits{Rows: 2},
+		OnRowError: func(rowErr *helper.RowError) error {
+			mu.Lock()
+			defer mu.Unlock()
+			rowErrs = append(rowErrs, rowErr)
+			return nil
+		},
+		Progress: &progress,
+	})
+
+	var values []interface{}
+	for b := range blockStream {
+		for i := 0; i < b.NumRows; i++ {
+			values = append(values, b.Columns[0].Data.GetValue(i))
+		}
+	}
+	numRows, err := yield()
+	require.NoError(t, err)
+	require.Equal(t, len(values), numRows)
+
+	sort.Slice(rowErrs, func(i, j int) bool {
+		return rowErrs[i].Row < rowErrs[j].Row
+	})
+	return values, rowErrs, &progress
+}
+
+func rowErrorLines(rowErrs []*helper.RowError) []int {
+	lines := make([]int, len(rowErrs))
+	for i, rowErr := range rowErrs {
+		lines[i] = rowErr.Line
+	}
+	return lines
+}
+
+func TestBlockStreamFmtReader_Resume(t *testing.T) {
+	tests := []struct {
+		format string
+		input  string
+	}{
+		{format: "CSVWithNames", input: "a,b\n1,a\n2,b\n3,c\n4,d\n5,e\n"},
+		{format: "TSVWithNamesAndTypes", input: "a\tb\nInt32\tString\n1\ta\n2\tb\n3\tc\n4\td\n5\te\n"},
+		{format: "JSONEachRow", input: "{\"a\": 1, \"b\": \"a\"}\n{\"b\": \"b\", \"a\": 2}\n{\"a\": 3}\n{\"a\": 4}\n{\"a\": 5}"},
+		{format: "VALUES", input: "(1, 'a'), (2, 'b'),\n(3, 'c'), (4, 'd'), (5, 'e')"},
+		{format: "JSON", input: `{"meta": [{"name": "a"}, {"name": "b"}], "data": [
+			{"a": 1, "b": "a"}, {"a": 2, "b": "b"}, {"a": 3, "b": "c"}, {"a": 4, "b": "d"}, {"a": 5, "b": "e"}
+		]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			read := func(resume *helper.InputMark) ([]interface{}, []helper.InputMark) {
+				r, err := BlockStreamFmtReaderFactory(tt.format, strings.NewReader(tt.input), nil)
+				require.NoError(t, err)
+				sample, err := data.NewBlock([]string{"a", "b"}, []column.CHColumnType{"Int32", "String"}, 0)
+				require.NoError(t, err)
+
+				var ends []helper.InputMark
+				blockStream, yield := BlockStreamFmtReadWithOptions(context.Background(), r, sample, helper.ReadOptions{
+					Limits:  helper.BlockLimits{Rows: 2},
+					Resume:  resume,
+					OnBlock: func(end helper.InputMark) { ends = append(ends, end) },
+				})
+				var values []interface{}
+				for b := range blockStream {
+					for i := 0; i < b.NumRows; i++ {
+						values = append(values, b.Columns[0].Data.GetValue(i))
+					}
+				}
+				_, err = yield()
+				require.NoError(t, err)
+				return values, ends
+			}
+
+			values, ends := read(nil)
+			require.Equal(t, []interface{}{int32(1), int32(2), int32(3), int32(4), int32(5)}, values)
+			require.Len(t, ends, 3)
+			for _, end := range ends {
+				resumed, _ := read(&end)
+				require.Equal(t, len(values)-end.Rows, len(resumed))
+				for i, v := range resumed {
+					require.Equal(t, values[end.Rows+i], v)
+				}
+			}
+		})
+	}
+}
+
+func TestCSVBlockStreamFmtReader_Settings(t *testing.T) {
+	names := []string{"a", "b", "c"}
+	types := []column.CHColumnType{"Int32", "String", "Nullable(String)"}
+	tests := []struct {
+		name     string
+		input    string
+		settings map[string]interface{}
+		want     [][]string
+		wantErr  bool
+	}{
+		{
+			name:  "Trim whitespaces of unquoted fields and carriage returns by default",
+			input: "1,  x  ,\t\"  y  \"\r\n2,z ,w\r\n",
+			want:  [][]string{{"1", "x", "  y  "}, {"2", "z", "w"}},
+		},
+		{
+			name:     "Keep whitespaces if not trimmed",
+			input:    "1,  x  ,y\n2, z,w \n",
+			settings: map[string]interface{}{csvTrimWhitespacesSetting: false},
+			want:     [][]string{{"1", "  x  ", "y"}, {"2", " z", "w "}},
+		},
+		{
+			name:     "Only the quote set quotes fields",
+			input:    "1,'x,|a,|\n2,|b||c|,d'\n",
+			settings: map[string]interface{}{csvQuoteSetting: "|"},
+			want:     [][]string{{"1", "'x", "a,"}, {"2", "b|c", "d'"}},
+		},
+		{
+			name:     "Skip first lines before header",
+			input:    "title\nexported today\na,b,c\n1,x,y\n",
+			settings: map[string]interface{}{csvSkipFirstLinesSetting: uint64(2)},
+			want:     [][]string{{"1", "x", "y"}},
+		},
+		{
+			name:     "Read NULL representation of unquoted fields",
+			input:    "1,\\N,\\N\n2,\"\\N\",x\n",
+			settings: map[string]interface{}{csvNullRepresentationSetting: "\\N"},
+			want:     [][]string{{"1", "", "ᴺᵁᴸᴸ"}, {"2", "\\N", "x"}},
+		},
+		{
+			name:     "Read empty NULL representation",
+			input:    "1,x,\n2,,\"\"\n",
+			settings: map[string]interface{}{csvNullRepresentationSetting: ""},
+			want:     [][]string{{"1", "x", "ᴺᵁᴸᴸ"}, {"2", "", ""}},
+		},
+		{
+			name:     "Skip comment lines",
+			input:    "# exported\n1,x,y\n#2,x,y\n\n3,z,w\n",
+			settings: map[string]interface{}{csvCommentCharSetting: "#"},
+			want:     [][]string{{"1", "x", "y"}, {"3", "z", "w"}},
+		},
+		{
+			name:     "Fill missing columns and discard extra columns",
+			input:    "1\n2,x\n3,\"y\",z,extra,\"more\"\n4,\"w\",v\r\n5,u",
+			settings: map[string]interface{}{csvVariableColumnsSetting: 1},
+			want:     [][]string{{"1", "", "ᴺᵁᴸᴸ"}, {"2", "x", "ᴺᵁᴸᴸ"}, {"3", "y", "z"}, {"4", "w", "v"}, {"5", "u", "ᴺᵁᴸᴸ"}},
+		},
+		{
+			name:    "Error on missing columns by default",
+			input:   "1,x\n",
+			wantErr: true,
+		},
+		{
+			name:     "Error on quote of more than 1 byte",
+			input:    "1,x,y\n",
+			settings: map[string]interface{}{csvQuoteSetting: "''"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format := "CSV"
+			if tt.settings[csvSkipFirstLinesSetting] != nil {
+				format = "CSVWithNames"
+			}
+			if tt.wantErr {
+				r, err := NewCSVBlockStreamFmtReader(strings.NewReader(tt.input), false, tt.settings)
+				if err == nil {
+					sample, sampleErr := data.NewBlock(names, types, 0)
+					require.NoError(t, sampleErr)
+					blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+					for range blockStream {
+					}
+					_, err = yield()
+				}
+				require.Error(t, err)
+				return
+			}
+			rows, err := readRowStringsWithSettings(t, format, []byte(tt.input), names, types, tt.settings)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestCSVBlockStreamFmtReader_HeaderMapping(t *testing.T) {
+	names := []string{"a", "b", "c"}
+	types := []column.CHColumnType{"Int32", "String", "Nullable(String)"}
+	useHeader := map[string]interface{}{withNamesUseHeaderSetting: true}
+	tests := []struct {
+		name     string
+		input    string
+		settings map[string]interface{}
+		want     [][]string
+		wantErr  string
+	}{
+		{
+			name:     "Columns are reordered",
+			input:    "c,\"a\",b\nz,1,x\n,2,y\n",
+			settings: useHeader,
+			want:     [][]string{{"1", "x", "z"}, {"2", "y", ""}},
+		},
+		{
+			name:     "Unknown columns are skipped and omitted columns are filled with defaults",
+			input:    "b,unknown\nx,\"ignored, quoted\"\ny,2\n",
+			settings: useHeader,
+			want:     [][]string{{"0", "x", "ᴺᵁᴸᴸ"}, {"0", "y", "ᴺᵁᴸᴸ"}},
+		},
+		{
+			name:     "Unknown columns fail if not skipped",
+			input:    "b,unknown\nx,1\n",
+			settings: map[string]interface{}{withNamesUseHeaderSetting: true, skipUnknownFieldsSetting: false},
+			wantErr:  `unknown column in header: "unknown"`,
+		},
+		{
+			name:     "Duplicate columns fail",
+			input:    "a,a\n1,2\n",
+			settings: useHeader,
+			wantErr:  `duplicate column in header: "a"`,
+		},
+		{
+			name:  "Columns are read by position if the header is not used",
+			input: "c,a,b\n1,x,y\n",
+			want:  [][]string{{"1", "x", "y"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readRowStringsWithSettings(t, "CSVWithNames", []byte(tt.input), names, types, tt.settings)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestCSVBlockStreamFmtReader_SetColumnDefaults(t *testing.T) {
+	r, err := NewCSVBlockStreamFmtReader(strings.NewReader("b\nx\n"), true, map[string]interface{}{withNamesUseHeaderSetting: 1})
+	require.NoError(t, err)
+	r.SetColumnDefaults(map[string]string{"a": "-1", "c": "'it\\'s'"})
+	sample, err := data.NewBlock([]string{"a", "b", "c"}, []column.CHColumnType{"Int32", "String", "Nullable(String)"}, 0)
+	require.NoError(t, err)
+
+	blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+	var values [][]interface{}
+	for b := range blockStream {
+		values = append(values, []interface{}{b.Columns[0].Data.GetValue(0), b.Columns[1].Data.GetValue(0), b.Columns[2].Data.GetString(0)})
+	}
+	_, err = yield()
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{{int32(-1), "x", "it's"}}, values)
+}
@@ -0,0 +1,192 @@
+package format
+
+import (
+	"strconv"
+
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+const (
+	csvQuoteSetting              = "format_csv_quote"
+	csvNullRepresentationSetting = "format_csv_null_representation"
+	csvSkipFirstLinesSetting     = "input_format_csv_skip_first_lines"
+	csvTrimWhitespacesSetting    = "input_format_csv_trim_whitespaces"
+	csvCommentCharSetting        = "input_format_csv_comment_char"
+	csvVariableColumnsSetting    = "input_format_csv_allow_variable_number_of_columns"
+	csvCRLFEndOfLineSetting      = "output_format_csv_crlf_end_of_line"
+)
+
+const defaultCSVQuote byte = '"'
+
+// csvSettings are the settings of the CSV dialect, shared by CSVBlockStreamFmtReader and CSVBlockStreamFmtWriter
+type csvSettings struct {
+	delimiter byte
+	// quote is the only quote of fields if not 0, else any of ", ' and ` quotes fields of input
+	quote byte
+	// nullRepr is written for NULL and read as NULL from unquoted fields if hasNullRepr
+	nullRepr    string
+	hasNullRepr bool
+	// skipFirstLines is the number of lines of input discarded before the header or data
+	skipFirstLines int
+	// trimWhitespaces trims spaces and tabs around unquoted fields of input
+	trimWhitespaces bool
+	// commentChar starts lines of input which are discarded if not 0
+	commentChar byte
+	// variableColumns fills missing columns of input rows with default values and discards the extra ones
+	variableColumns bool
+	crlf            bool
+	// useHeader matches the columns of the header to the table columns by name, of which unknown ones are skipped
+	// if skipUnknown, otherwise fail
+	useHeader   bool
+	skipUnknown bool
+}
+
+func resolveCSVSettings(settings map[string]interface{}) (*csvSettings, error) {
+	var (
+		s   = &csvSettings{trimWhitespaces: true, skipUnknown: true}
+		err error
+	)
+	if s.delimiter, err = resolveCSVDelim(settings); err != nil {
+		return nil, err
+	}
+	if s.quote, err = resolveCSVByte(settings, csvQuoteSetting); err != nil {
+		return nil, err
+	}
+	if s.commentChar, err = resolveCSVByte(settings, csvCommentCharSetting); err != nil {
+		return nil, err
+	}
+	if s.skipFirstLines, err = resolveCSVInt(settings, csvSkipFirstLinesSetting); err != nil {
+		return nil, err
+	}
+	if err = resolveBoolSetting(settings, csvTrimWhitespacesSetting, &s.trimWhitespaces); err != nil {
+		return nil, err
+	}
+	if err = resolveBoolSetting(settings, csvVariableColumnsSetting, &s.variableColumns); err != nil {
+		return nil, err
+	}
+	if err = resolveBoolSetting(settings, csvCRLFEndOfLineSetting, &s.crlf); err != nil {
+		return nil, err
+	}
+	if err = resolveBoolSetting(settings, withNamesUseHeaderSetting, &s.useHeader); err != nil {
+		return nil, err
+	}
+	if err = resolveBoolSetting(settings, skipUnknownFieldsSetting, &s.skipUnknown); err != nil {
+		return nil, err
+	}
+
+	if nullRepr, ok := settings[csvNullRepresentationSetting]; ok {
+		if s.nullRepr, ok = nullRepr.(string); !ok {
+			return nil, errors.ErrorfWithCaller("expected type: string for %v, got: %T", csvNullRepresentationSetting, nullRepr)
+		}
+		s.hasNullRepr = true
+	}
+
+	if (s.quote != 0 && s.quote == s.delimiter) || (s.commentChar != 0 && s.commentChar == s.delimiter) {
+		return nil, errors.ErrorfWithCaller("%v and %v must differ from %v", csvQuoteSetting, csvCommentCharSetting, csvDelimiterSetting)
+	}
+	return s, nil
+}
+
+// isQuote tells if b starts a quoted field of input
+func (s *csvSettings) isQuote(b byte) bool {
+	if s.quote != 0 {
+		return b == s.quote
+	}
+	switch b {
+	case '"', '\'', '`':
+		return true
+	}
+	return false
+}
+
+// outputQuote returns the quote of fields of output
+func (s *csvSettings) outputQuote() byte {
+	if s.quote != 0 {
+		return s.quote
+	}
+	return defaultCSVQuote
+}
+
+func (s *csvSettings) lineEnd() string {
+	if s.crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// resolveCSVByte returns the single byte setting of key, 0 if not set or empty
+func resolveCSVByte(settings map[string]interface{}, key string) (byte, error) {
+	v, ok := settings[key]
+	if !ok {
+		return 0, nil
+	}
+	switch v := v.(type) {
+	case byte:
+		return v, nil
+	case string:
+		if len(v) == 0 {
+			return 0, nil
+		}
+		if len(v) > 1 {
+			return 0, errors.ErrorfWithCaller("%v should only be 1 byte, got %q", key, v)
+		}
+		return v[0], nil
+	default:
+		return 0, errors.ErrorfWithCaller("expected type: byte/string for %v, got: %T", key, v)
+	}
+}
+
+func resolveCSVInt(settings map[string]interface{}, key string) (int, error) {
+	v, ok := settings[key]
+	if !ok {
+		return 0, nil
+	}
+
+	var n int64
+	switch v := v.(type) {
+	case int:
+		n = int64(v)
+	case int64:
+		n = v
+	case uint64:
+		n = int64(v)
+	case string:
+		var err error
+		if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return 0, errors.ErrorfWithCaller("invalid %v: %v", key, err)
+		}
+	default:
+		return 0, errors.ErrorfWithCaller("expected type: int/int64/uint64/string for %v, got: %T", key, v)
+	}
+	if n < 0 {
+		return 0, errors.ErrorfWithCaller("%v must not be negative, got: %v", key, n)
+	}
+	return int(n), nil
+}
+
+// resolveBoolSetting sets b to the boolean setting of key if set, which may also be given as 0 or 1 like ClickHouse settings
+func resolveBoolSetting(settings map[string]interface{}, key string, b *bool) error {
+	v, ok := settings[key]
+	if !ok {
+		return nil
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = v
+	case int:
+		*b = v != 0
+	case int64:
+		*b = v != 0
+	case uint64:
+		*b = v != 0
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return errors.ErrorfWithCaller("invalid %v: %v", key, err)
+		}
+		*b = parsed
+	default:
+		return errors.ErrorfWithCaller("expected type: bool/int/int64/uint64/string for %v, got: %T", key, v)
+	}
+	return nil
+}
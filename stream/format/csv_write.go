@@ -17,6 +17,7 @@ import (
 type CSVBlockStreamFmtWriter struct {
 	zWriter        *bytepool.ZWriter
 	delimiterBytes byte
+	settings       *csvSettings
 
 	totalRowsWrite int
 	exception      error
@@ -25,13 +26,14 @@ type CSVBlockStreamFmtWriter struct {
 }
 
 func NewCSVBlockStreamFmtWriter(w io.Writer, withNames bool, settings map[string]interface{}) (*CSVBlockStreamFmtWriter, error) {
-	delim, err := resolveCSVDelim(settings)
+	s, err := resolveCSVSettings(settings)
 	if err != nil {
 		return nil, err
 	}
 	newWriter := &CSVBlockStreamFmtWriter{
 		zWriter:        bytepool.NewZWriterDefault(w),
-		delimiterBytes: delim,
+		delimiterBytes: s.delimiter,
+		settings:       s,
 		withNames:      withNames,
 	}
 	return newWriter, nil
@@ -78,7 +80,7 @@ func (c *CSVBlockStreamFmtWriter) WriteFirstFrame(frame [][]string, cols []*colu
 				return 0, err
 			}
 		}
-		if err := c.zWriter.WriteByte('\n'); err != nil {
+		if err := c.zWriter.WriteString(c.settings.lineEnd()); err != nil {
 			return 0, nil
 		}
 	}
@@ -95,7 +97,7 @@ func (c *CSVBlockStreamFmtWriter) WriteFirstRow(record []string, cols []*column.
 }
 
 func (c *CSVBlockStreamFmtWriter) WriteRowCont(record []string, cols []*column.CHColumn) error {
-	if err := c.zWriter.WriteByte('\n'); err != nil {
+	if err := c.zWriter.WriteString(c.settings.lineEnd()); err != nil {
 		return err
 	}
 	return c.writeRow(record, cols)
@@ -118,40 +120,47 @@ func (c *CSVBlockStreamFmtWriter) writeRow(record []string, cols []*column.CHCol
 }
 
 func (c *CSVBlockStreamFmtWriter) writeColumn(s string, col *column.CHColumn) error {
+	quote := c.settings.outputQuote()
 	switch col.Data.(type) {
 	case *column.StringColumnData, *column.FixedStringColumnData:
-		if err := c.zWriter.WriteByte('"'); err != nil {
+		if err := c.zWriter.WriteByte(quote); err != nil {
 			return err
 		}
-		if err := c.writeColumnWithCheck(s); err != nil {
+		if err := c.writeColumnWithCheck(s, quote); err != nil {
 			return err
 		}
-		if err := c.zWriter.WriteByte('"'); err != nil {
+		if err := c.zWriter.WriteByte(quote); err != nil {
 			return err
 		}
 		return nil
 	case *column.DateColumnData, *column.DateTimeColumnData, *column.DateTime64ColumnData:
-		if err := c.zWriter.WriteByte('"'); err != nil {
+		if err := c.zWriter.WriteByte(quote); err != nil {
 			return err
 		}
 		if err := c.writeString(s); err != nil {
 			return err
 		}
-		if err := c.zWriter.WriteByte('"'); err != nil {
+		if err := c.zWriter.WriteByte(quote); err != nil {
 			return err
 		}
 		return nil
+	case *column.NullableColumnData:
+		if c.settings.hasNullRepr && s == column.NULLDisplay {
+			return c.writeString(c.settings.nullRepr)
+		}
+		return c.writeString(s)
 	default:
 		return c.writeString(s)
 	}
 }
 
-func (c *CSVBlockStreamFmtWriter) writeColumnWithCheck(s string) error {
-	for i := strings.IndexByte(s, '"'); i >= 0; i = strings.IndexByte(s, '"') {
-		if err := c.writeString(s[:i]); err != nil {
+// writeColumnWithCheck writes s escaping quote by doubling it
+func (c *CSVBlockStreamFmtWriter) writeColumnWithCheck(s string, quote byte) error {
+	for i := strings.IndexByte(s, quote); i >= 0; i = strings.IndexByte(s, quote) {
+		if err := c.writeString(s[:i+1]); err != nil {
 			return err
 		}
-		if _, err := c.zWriter.Write([]byte("\"\"")); err != nil {
+		if err := c.zWriter.WriteByte(quote); err != nil {
 			return err
 		}
 		s = s[i+1:]
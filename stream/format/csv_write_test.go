@@ -270,3 +270,50 @@ func makeSampleBlock() *data.Block {
 	}
 	return b
 }
+
+func TestCSVBlockStreamFmtWriter_Settings(t *testing.T) {
+	names := []string{"a", "b", "c"}
+	types := []column.CHColumnType{"Int32", "String", "Nullable(Int32)"}
+	texts := [][]string{{"1", "2"}, {"x'y", "z"}, {"3", "NULL"}}
+	tests := []struct {
+		name     string
+		format   string
+		settings map[string]interface{}
+		want     string
+	}{
+		{
+			name:   "Default dialect",
+			format: "CSVWithNames",
+			want:   "a,b,c\n1,\"x'y\",3\n2,\"z\",ᴺᵁᴸᴸ",
+		},
+		{
+			name:     "Quote",
+			format:   "CSV",
+			settings: map[string]interface{}{csvQuoteSetting: "'"},
+			want:     "1,'x''y',3\n2,'z',ᴺᵁᴸᴸ",
+		},
+		{
+			name:     "NULL representation",
+			format:   "CSV",
+			settings: map[string]interface{}{csvNullRepresentationSetting: "\\N"},
+			want:     "1,\"x'y\",3\n2,\"z\",\\N",
+		},
+		{
+			name:     "CRLF end of line",
+			format:   "CSVWithNames",
+			settings: map[string]interface{}{csvCRLFEndOfLineSetting: true, csvNullRepresentationSetting: ""},
+			want:     "a,b,c\r\n1,\"x'y\",3\r\n2,\"z\",",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := writeBlocks(t, tt.format, names, types, texts, 1, tt.settings)
+			require.Equal(t, tt.want, string(got))
+
+			// the output is read back with the same settings
+			rows, err := readRowStringsWithSettings(t, tt.format, got, names, types, tt.settings)
+			require.NoError(t, err)
+			require.Equal(t, [][]string{{"1", "x'y", "3"}, {"2", "z", "ᴺᵁᴸᴸ"}}, rows)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
 	"github.com/bytehouse-cloud/driver-go/errors"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
 )
 
 const (
@@ -16,6 +17,23 @@ const (
 	VALUES
 	JSON
 	TOML
+	TABSEPARATED
+	TABSEPARATEDWITHNAMES
+	TABSEPARATEDWITHNAMESANDTYPES
+	TABSEPARATEDRAW
+	JSONEACHROW
+	JSONCOMPACTEACHROW
+	JSONCOMPACTEACHROWWITHNAMES
+	JSONCOMPACTEACHROWWITHNAMESANDTYPES
+	PARQUET
+	ARROWSTREAM
+	NATIVE
+	ROWBINARY
+	ROWBINARYWITHNAMES
+	ROWBINARYWITHNAMESANDTYPES
+	VERTICAL
+	MARKDOWN
+	PRETTYCOMPACT
 )
 
 var Formats = map[int]string{
@@ -25,6 +43,47 @@ var Formats = map[int]string{
 	JSON:         "JSON",
 	TOML:         "TOML",
 	PRETTY:       "PRETTY",
+
+	PRETTYCOMPACT: "PRETTYCOMPACT",
+	VERTICAL:      "VERTICAL",
+	MARKDOWN:      "MARKDOWN",
+
+	TABSEPARATED:                  "TABSEPARATED",
+	TABSEPARATEDWITHNAMES:         "TABSEPARATEDWITHNAMES",
+	TABSEPARATEDWITHNAMESANDTYPES: "TABSEPARATEDWITHNAMESANDTYPES",
+	TABSEPARATEDRAW:               "TABSEPARATEDRAW",
+
+	JSONEACHROW:                         "JSONEACHROW",
+	JSONCOMPACTEACHROW:                  "JSONCOMPACTEACHROW",
+	JSONCOMPACTEACHROWWITHNAMES:         "JSONCOMPACTEACHROWWITHNAMES",
+	JSONCOMPACTEACHROWWITHNAMESANDTYPES: "JSONCOMPACTEACHROWWITHNAMESANDTYPES",
+
+	PARQUET:     "PARQUET",
+	ARROWSTREAM: "ARROWSTREAM",
+	NATIVE:      "NATIVE",
+
+	ROWBINARY:                  "ROWBINARY",
+	ROWBINARYWITHNAMES:         "ROWBINARYWITHNAMES",
+	ROWBINARYWITHNAMESANDTYPES: "ROWBINARYWITHNAMESANDTYPES",
+}
+
+// FormatAliases maps short names of formats to their type in Formats
+var FormatAliases = map[string]int{
+	"TSV":                  TABSEPARATED,
+	"TSVWITHNAMES":         TABSEPARATEDWITHNAMES,
+	"TSVWITHNAMESANDTYPES": TABSEPARATEDWITHNAMESANDTYPES,
+	"TSVRAW":               TABSEPARATEDRAW,
+	"JSONLINES":            JSONEACHROW,
+	"NDJSON":               JSONEACHROW,
+}
+
+// formatName returns the upper case name of fmtType in Formats, resolving aliases
+func formatName(fmtType string) string {
+	name := strings.ToUpper(fmtType)
+	if t, ok := FormatAliases[name]; ok {
+		return Formats[t]
+	}
+	return name
 }
 
 type BlockStreamFmtReader interface {
@@ -37,20 +96,37 @@ type BlockStreamFmtReader interface {
 	) (blockStream <-chan *data.Block, yield func() (int, error))
 }
 
+// BlockStreamFmtOptionsReader is implemented by readers which support helper.ReadOptions,
+// such as bounding blocks by estimated size and time or skipping rows with errors
+type BlockStreamFmtOptionsReader interface {
+	BlockStreamFmtReadWithOptions(
+		ctx context.Context, sample *data.Block, opts helper.ReadOptions,
+	) (blockStream <-chan *data.Block, yield func() (int, error))
+}
+
+// BlockStreamFmtReadWithOptions starts reading blocks from r with opts.
+// Readers which do not implement BlockStreamFmtOptionsReader only use opts.Limits.Rows
+func BlockStreamFmtReadWithOptions(
+	ctx context.Context, r BlockStreamFmtReader, sample *data.Block, opts helper.ReadOptions,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	if or, ok := r.(BlockStreamFmtOptionsReader); ok {
+		return or.BlockStreamFmtReadWithOptions(ctx, sample, opts)
+	}
+	return r.BlockStreamFmtRead(ctx, sample, opts.Limits.Rows)
+}
+
+// BlockStreamFmtReaderFactory returns the reader of fmtType registered with RegisterReader from r, which is
+// decompressed according to the CompressionSetting of settings
 func BlockStreamFmtReaderFactory(fmtType string, r io.Reader, settings map[string]interface{}) (BlockStreamFmtReader, error) {
-	switch strings.ToUpper(fmtType) {
-	case Formats[CSVWITHNAMES]:
-		return NewCSVBlockStreamFmtReader(r, true, settings)
-	case Formats[CSV]:
-		return NewCSVBlockStreamFmtReader(r, false, settings)
-	case Formats[VALUES]:
-		return NewValuesBlockStreamReader(r), nil
-	case Formats[JSON]:
-		return NewJSONBlockStreamFmtReader(r), nil
-
-	default:
+	ctor, ok := lookupReader(formatName(fmtType))
+	if !ok {
 		return nil, errors.ErrorfWithCaller("unrecognised input format: [%s]\n", fmtType)
 	}
+	r, err := decompressInput(fmtType, r, settings)
+	if err != nil {
+		return nil, err
+	}
+	return ctor(r, settings)
 }
 
 // BlockStreamFmtWriter writes data of block to it's respective format of it's concrete type
@@ -62,19 +138,48 @@ type BlockStreamFmtWriter interface {
 	Yield() (int, error)
 }
 
+// BlockStreamFmtWriterFactory returns the writer of fmtType registered with RegisterWriter to w, compressed with the CompressionSetting of settings
 func BlockStreamFmtWriterFactory(fmtType string, w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error) {
-	switch strings.ToUpper(fmtType) {
-	case Formats[PRETTY]:
-		return NewPrettyBlockStreamFmtWriter(w), nil
-	case Formats[CSVWITHNAMES]:
-		return NewCSVBlockStreamFmtWriter(w, true, settings)
-	case Formats[CSV]:
-		return NewCSVBlockStreamFmtWriter(w, false, settings)
-	case Formats[VALUES]:
-		return NewValuesBlockStreamFmtWriter(w), nil
-	case Formats[JSON]:
-		return NewJSONBlockStreamFmtWriter(w), nil
-	default:
+	method, err := resolveCompression(settings)
+	if err != nil {
+		return nil, err
+	}
+	if method == CompressionAuto || method == CompressionNone {
+		return newBlockStreamFmtWriter(fmtType, w, settings)
+	}
+
+	cw, err := NewCompressWriter(w, method)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := newBlockStreamFmtWriter(fmtType, cw, settings)
+	if err != nil {
+		return nil, err
+	}
+	return &compressedBlockStreamFmtWriter{BlockStreamFmtWriter: fw, w: cw}, nil
+}
+
+func newBlockStreamFmtWriter(fmtType string, w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error) {
+	ctor, ok := lookupWriter(formatName(fmtType))
+	if !ok {
 		return nil, errors.ErrorfWithCaller("unrecognised input format: [%s]\n", fmtType)
 	}
+	return ctor(w, settings)
+}
+
+// decompressInput returns the decompressed r. Compression is not detected for binary formats
+// which may start with the magic bytes of a compression method, or need random access to r.
+func decompressInput(fmtType string, r io.Reader, settings map[string]interface{}) (io.Reader, error) {
+	method, err := resolveCompression(settings)
+	if err != nil {
+		return nil, err
+	}
+	if method == CompressionAuto {
+		switch formatName(fmtType) {
+		case Formats[PARQUET], Formats[NATIVE],
+			Formats[ROWBINARY], Formats[ROWBINARYWITHNAMES], Formats[ROWBINARYWITHNAMESANDTYPES]:
+			return r, nil
+		}
+	}
+	return NewDecompressReader(r, method)
 }
@@ -0,0 +1,33 @@
+package helper
+
+import "time"
+
+// BlockLimits bounds the blocks built from a stream of rows.
+// A block is cut as soon as any of the set limits is reached,
+// Bytes and Interval are ignored when zero.
+type BlockLimits struct {
+	// Rows is the maximum number of rows in a block
+	Rows int
+	// Bytes is the maximum estimated encoded size of a block
+	Bytes int
+	// Interval is the maximum time spent accumulating a block.
+	// Reached only checks it when a row arrives, readers blocked on input cannot flush in between.
+	Interval time.Duration
+}
+
+// Reached returns true if a block of given rows and estimated bytes,
+// accumulated since start, should be flushed.
+func (l BlockLimits) Reached(rows, bytes int, start time.Time) bool {
+	if rows >= l.Rows {
+		return true
+	}
+	if l.Bytes > 0 && bytes >= l.Bytes {
+		return true
+	}
+	return l.Interval > 0 && time.Since(start) >= l.Interval
+}
+
+// RowsOnly returns true if only the number of rows is bounded
+func (l BlockLimits) RowsOnly() bool {
+	return l.Bytes <= 0 && l.Interval <= 0
+}
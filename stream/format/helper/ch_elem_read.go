@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
@@ -424,9 +425,85 @@ func AssertNextByteEqual(z *bytepool.ZReader, expect byte) error {
 	return nil
 }
 
+// AssertNextByteEqualSameLine reads the next non space byte from z without going past a newline
+// throws error if byte is not same as expect
+func AssertNextByteEqualSameLine(z *bytepool.ZReader, expect byte) error {
+	next, err := ReadNextNonSpaceExceptNewLineByte(z)
+	if err != nil {
+		return err
+	}
+	if next != expect {
+		return fmt.Errorf("expect byte: %q, but got: %q", expect, next)
+	}
+	return nil
+}
+
+// DiscardLine discards bytes from z until including the next newline, or EOF
+func DiscardLine(z *bytepool.ZReader) error {
+	_, err := ReadStringUntilByte(discardWriter{}, z, '\n')
+	return err
+}
+
+// DiscardUntilUnnested discards bytes from z until including the first of stops
+// which is not quoted or nested in brackets, and returns it
+func DiscardUntilUnnested(z *bytepool.ZReader, stops string) (byte, error) {
+	var (
+		depth int
+		quote byte
+	)
+	for {
+		b, err := z.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if quote != 0 {
+			switch b {
+			case backSlash:
+				if _, err := z.ReadByte(); err != nil {
+					return 0, err
+				}
+			case quote:
+				quote = 0
+			}
+			continue
+		}
+
+		if depth == 0 && strings.IndexByte(stops, b) >= 0 {
+			return b, nil
+		}
+		switch b {
+		case '"', '\'', '`':
+			quote = b
+		case '{', '[', '(':
+			depth++
+		case '}', ']', ')':
+			if depth > 0 {
+				depth--
+			}
+		}
+	}
+}
+
 func FlushZReader(z *bytepool.ZReader) {
 	var err error
 	for err == nil {
 		_, err = z.ReadNextBuffer()
 	}
 }
+
+// DiscardUntilPosition discards bytes of z until its position is offset
+func DiscardUntilPosition(z *bytepool.ZReader, offset int64) error {
+	for z.Position() < offset {
+		if _, err := z.ReadNextBuffer(); err != nil {
+			if err == io.EOF {
+				return fmt.Errorf("input ends at %v, before offset %v", z.Position(), offset)
+			}
+			return err
+		}
+		if over := z.Position() - offset; over > 0 {
+			z.UnreadCurrentBuffer(int(over))
+		}
+	}
+	return nil
+}
@@ -24,6 +24,10 @@ type ColumnTextsResult struct {
 	fb       *bytepool.FrameBuffer
 	colTexts [][]string
 	pool     *ColumnTextsPool
+	// positions of each row, only set if row errors are handled
+	positions []RowPosition
+	// end is the input consumed up to the last row, Offset is 0 if the reader is not a PositionReader
+	end InputMark
 }
 
 func (c *ColumnTextsPool) NewColumnTextsResult(fb *bytepool.FrameBuffer) *ColumnTextsResult {
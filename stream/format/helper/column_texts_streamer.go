@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"runtime/debug"
+	"time"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
@@ -19,11 +20,28 @@ type TableReader interface {
 
 type ReadColumnTexts func(fb *bytepool.FrameBuffer, rows int, cols []*column.CHColumn) (int, error)
 
+// RowPosition is the position of a row in the input
+type RowPosition struct {
+	// Row is the 0-based index of the row in the input
+	Row int
+	// Line is the line the row ends on, 0 if unknown
+	Line int
+}
+
 type ColumnTextsStreamer struct {
-	cols      []*column.CHColumn
-	blockSize int
-	tReader   TableReader
-	ctPool    *ColumnTextsPool
+	cols    []*column.CHColumn
+	opts    ReadOptions
+	tReader TableReader
+	ctPool  *ColumnTextsPool
+
+	// position is nil if tReader is not a PositionReader
+	position PositionReader
+	// rowReader is nil if tReader is not a RowReader
+	rowReader RowReader
+	// size estimates the encoded size of the rows read, nil if bytes are not limited
+	size *textSizeEstimator
+	// rowIndex is the index of next row in the input, including rows skipped
+	rowIndex int
 
 	rowRead  int
 	err      error
@@ -32,12 +50,27 @@ type ColumnTextsStreamer struct {
 }
 
 func NewColumnTextsStreamer(sample *data.Block, blockSize int, tReader TableReader) *ColumnTextsStreamer {
-	return &ColumnTextsStreamer{
+	return NewColumnTextsStreamerWithOptions(sample, ReadOptions{Limits: BlockLimits{Rows: blockSize}}, tReader)
+}
+
+func NewColumnTextsStreamerWithOptions(sample *data.Block, opts ReadOptions, tReader TableReader) *ColumnTextsStreamer {
+	position, _ := tReader.(PositionReader)
+	rowReader, _ := tReader.(RowReader)
+	streamer := &ColumnTextsStreamer{
 		cols:      sample.Columns,
-		blockSize: blockSize,
+		opts:      opts,
 		tReader:   tReader,
-		ctPool:    NewColumnTextsPool(sample.NumColumns, blockSize),
+		ctPool:    NewColumnTextsPool(sample.NumColumns, opts.Limits.Rows),
+		position:  position,
+		rowReader: rowReader,
+	}
+	if opts.Limits.Bytes > 0 {
+		streamer.size = newTextSizeEstimator(sample.Columns)
 	}
+	if raw, ok := tReader.(RawRowReader); ok && opts.OnRowError != nil {
+		raw.RecordRows()
+	}
+	return streamer
 }
 
 func (c *ColumnTextsStreamer) Start(ctx context.Context) <-chan *ColumnTextsResult {
@@ -63,11 +96,15 @@ func (c *ColumnTextsStreamer) Start(ctx context.Context) <-chan *ColumnTextsResu
 				if err == io.EOF {
 					return
 				}
-				c.err = fmt.Errorf("error reading row at index %v: %s", c.rowRead, err)
+				c.err = fmt.Errorf("error reading row at index %v: %w", c.rowRead, err)
 			}
 		}()
 
-		c.rowRead, err = c.readFirst(ctx, outputStream)
+		if c.opts.Resume != nil {
+			c.rowRead, err = c.resume(ctx, outputStream)
+		} else {
+			c.rowRead, err = c.readFirst(ctx, outputStream)
+		}
 		if err != nil {
 			return
 		}
@@ -115,6 +152,19 @@ func (c *ColumnTextsStreamer) readFirst(ctx context.Context, des chan<- *ColumnT
 	return c.read(ctx, des, c.tReader.ReadFirstColumnTexts)
 }
 
+// resume discards the input before opts.Resume and reads the rows after it
+func (c *ColumnTextsStreamer) resume(ctx context.Context, des chan<- *ColumnTextsResult) (int, error) {
+	resumer, ok := c.tReader.(Resumer)
+	if !ok {
+		return 0, fmt.Errorf("input format does not support resuming")
+	}
+	if err := resumer.ResumeAt(c.opts.Resume.Offset, c.cols); err != nil {
+		return 0, fmt.Errorf("resume at offset %v: %w", c.opts.Resume.Offset, err)
+	}
+	c.rowIndex = c.opts.Resume.Rows
+	return c.readCont(ctx, des)
+}
+
 func (c *ColumnTextsStreamer) readCont(ctx context.Context, des chan<- *ColumnTextsResult) (int, error) {
 	return c.read(ctx, des, c.tReader.ReadColumnTextsCont)
 }
@@ -125,7 +175,8 @@ func (c *ColumnTextsStreamer) read(ctx context.Context, des chan<- *ColumnTextsR
 	}
 
 	fb := bytepool.NewFrameBuffer()
-	n, err := readColumnTexts(fb, c.blockSize, c.cols)
+	n, positions, err := c.readLimited(fb, readColumnTexts)
+	c.updateProgress(n)
 
 	if err != nil {
 		if err != io.EOF {
@@ -136,10 +187,154 @@ func (c *ColumnTextsStreamer) read(ctx context.Context, des chan<- *ColumnTextsR
 		}
 	}
 
+	result := c.ctPool.NewColumnTextsResult(fb)
+	result.positions = positions
+	result.end = InputMark{Rows: c.rowIndex}
+	if c.position != nil {
+		result.end.Offset, _ = c.position.InputPosition()
+	}
 	select {
-	case des <- c.ctPool.NewColumnTextsResult(fb):
+	case des <- result:
 		return n, nil
 	case <-ctx.Done():
 		return n, context.Canceled
 	}
 }
+
+// rowsPerLimitCheck is the number of rows read between checks of the byte and interval limits
+const rowsPerLimitCheck = 64
+
+// readLimited reads rows into fb until any of the block limits is reached.
+// If only rows are bounded and row errors are not handled, the whole block is read in a single call,
+// otherwise rows are read in batches with the limits checked in between.
+// Positions of the rows read are returned if row errors are handled.
+func (c *ColumnTextsStreamer) readLimited(fb *bytepool.FrameBuffer, readColumnTexts ReadColumnTexts) (int, []RowPosition, error) {
+	if c.opts.Limits.RowsOnly() && c.opts.OnRowError == nil {
+		n, err := readColumnTexts(fb, c.opts.Limits.Rows, c.cols)
+		c.rowIndex += n
+		return n, nil, err
+	}
+
+	var (
+		start     = time.Now()
+		totalRead int
+		positions []RowPosition
+	)
+	if c.size != nil {
+		c.size.reset()
+	}
+	for {
+		batch := c.opts.Limits.Rows - totalRead
+		if !c.opts.Limits.RowsOnly() && batch > rowsPerLimitCheck {
+			batch = rowsPerLimitCheck
+		}
+
+		var n int
+		var err error
+		n, positions, err = c.readBatch(fb, batch, readColumnTexts, positions)
+		readColumnTexts = c.tReader.ReadColumnTextsCont
+		totalRead += n
+
+		if err != nil && err != io.EOF && c.opts.OnRowError != nil {
+			if err := c.handleRowError(err); err != nil {
+				return totalRead, positions, err
+			}
+			err = nil
+		}
+		if err != nil {
+			return totalRead, positions, err
+		}
+		if totalRead > 0 && c.limitsReached(fb, totalRead, start) {
+			return totalRead, positions, nil
+		}
+	}
+}
+
+// readBatch reads up to numRows rows into fb. If row errors are handled, the position of each row is appended
+// to positions, which needs the rows after the first to be read one at a time, by the RowReader if tReader is one.
+func (c *ColumnTextsStreamer) readBatch(
+	fb *bytepool.FrameBuffer, numRows int, readColumnTexts ReadColumnTexts, positions []RowPosition,
+) (int, []RowPosition, error) {
+	if c.opts.OnRowError == nil {
+		n, err := readColumnTexts(fb, numRows, c.cols)
+		c.rowIndex += n
+		return n, positions, err
+	}
+
+	var totalRead int
+	for totalRead < numRows {
+		var err error
+		if totalRead == 0 || c.rowReader == nil {
+			var n int
+			n, err = readColumnTexts(fb, 1, c.cols)
+			readColumnTexts = c.tReader.ReadColumnTextsCont
+			if n == 0 && err == nil {
+				err = io.EOF
+			}
+		} else {
+			// same as ReadColumnTextsCont, without a call per row through tReader
+			fb.NewRow()
+			if err = c.rowReader.ReadRowCont(fb, c.cols); err != nil {
+				fb.DiscardCurrentRow()
+			}
+		}
+		if err != nil {
+			return totalRead, positions, err
+		}
+		positions = append(positions, c.rowPosition())
+		c.rowIndex++
+		totalRead++
+	}
+	return totalRead, positions, nil
+}
+
+func (c *ColumnTextsStreamer) limitsReached(fb *bytepool.FrameBuffer, rows int, start time.Time) bool {
+	var size int
+	if c.size != nil {
+		size = c.size.count(fb)
+	}
+	return c.opts.Limits.Reached(rows, size, start)
+}
+
+// handleRowError skips the row which could not be parsed if tReader supports it, and reports it
+// with its input if tReader is a RawRowReader
+func (c *ColumnTextsStreamer) handleRowError(err error) error {
+	pos := c.rowPosition()
+	c.rowIndex++
+	rowErr := &RowError{Row: pos.Row, Line: pos.Line, Err: err}
+
+	skipper, ok := c.tReader.(RowSkipper)
+	if !ok {
+		return rowErr
+	}
+	skipErr := skipper.SkipRow()
+	if raw, ok := c.tReader.(RawRowReader); ok {
+		rowErr.Raw = raw.RawRow()
+	}
+	if err := c.opts.OnRowError(rowErr); err != nil {
+		return err
+	}
+	if c.opts.Progress != nil {
+		c.opts.Progress.addRowsSkipped(1)
+	}
+	return skipErr
+}
+
+func (c *ColumnTextsStreamer) rowPosition() RowPosition {
+	pos := RowPosition{Row: c.rowIndex}
+	if c.position != nil {
+		_, pos.Line = c.position.InputPosition()
+	}
+	return pos
+}
+
+func (c *ColumnTextsStreamer) updateProgress(rowsRead int) {
+	if c.opts.Progress == nil {
+		return
+	}
+	c.opts.Progress.addRowsRead(rowsRead)
+	if c.position != nil {
+		offset, _ := c.position.InputPosition()
+		c.opts.Progress.setBytesRead(offset)
+	}
+}
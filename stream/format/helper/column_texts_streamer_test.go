@@ -9,6 +9,7 @@ import (
 	"runtime/debug"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -236,6 +237,45 @@ func TestColumnTextsStreamer_Error_13th_elem(t *testing.T) {
 	}
 }
 
+func TestColumnTextsStreamer_Bytes_Limit(t *testing.T) {
+	b := getSampleBlock()
+	// limits are checked every rowsPerLimitCheck rows
+	streamer := NewColumnTextsStreamerWithOptions(
+		b, ReadOptions{Limits: BlockLimits{Rows: 1000, Bytes: 16}}, newTestTableReader(2*rowsPerLimitCheck+10, -1),
+	)
+	outStream := streamer.Start(context.Background())
+	go streamer.Finish()
+
+	var blockRows []int
+	for res := range outStream {
+		blockRows = append(blockRows, len(res.Get()[0]))
+	}
+	assert.Equal(t, []int{rowsPerLimitCheck, rowsPerLimitCheck, 10}, blockRows)
+}
+
+func TestTextSizeEstimator(t *testing.T) {
+	cols := getSampleBlock().Columns
+	e := newTextSizeEstimator(cols)
+	fb := bytepool.NewFrameBuffer()
+	_, err := newTestTableReader(2, -1).ReadFirstColumnTexts(fb, 2, cols)
+	assert.NoError(t, err)
+
+	// UInt32 is 4 bytes, String is the length of the text and 1 byte of its uvarint length
+	assert.Equal(t, 2*(4+1+len("1String")), e.count(fb))
+	e.reset()
+	assert.Equal(t, 2*(4+1+len("1String")), e.count(fb))
+}
+
+func TestBlockLimits_Reached(t *testing.T) {
+	now := time.Now()
+	assert.True(t, BlockLimits{Rows: 2}.Reached(2, 0, now))
+	assert.False(t, BlockLimits{Rows: 2}.Reached(1, 1<<20, now.Add(-time.Hour)))
+	assert.True(t, BlockLimits{Rows: 2, Bytes: 10}.Reached(1, 10, now))
+	assert.False(t, BlockLimits{Rows: 2, Bytes: 10}.Reached(1, 9, now))
+	assert.True(t, BlockLimits{Rows: 2, Interval: time.Second}.Reached(1, 0, now.Add(-time.Second)))
+	assert.False(t, BlockLimits{Rows: 2, Interval: time.Second}.Reached(1, 0, now))
+}
+
 func getSampleBlock() *data.Block {
 	colNames := []string{"col_1", "col_2"}
 	colTypes := []column.CHColumnType{
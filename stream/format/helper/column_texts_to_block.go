@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"log"
 	"runtime/debug"
+	"sort"
 	"strings"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
 )
 
 type RecycleColumnTexts func(columnTexts [][]string)
@@ -19,17 +21,50 @@ var RecycleColumnTextsNoOp RecycleColumnTexts = func(columnTexts [][]string) {}
 type ColumnTextsToBlock struct {
 	sample        *data.Block
 	ctStream      <-chan *ColumnTextsResult
+	opts          ReadOptions
 	rowsProcessed int
-	parallelism   int //TODO implement parallelism if it becomes bottleneck
-	errGroup      *errgroup.Group
-	err           error
-	done          chan struct{}
+	rowsSkipped   int
+	parallelism   int
+	// keepSpaces is set if texts are not trimmed, see SpaceKeeper
+	keepSpaces bool
+	// textsReader reads texts into columns if set, see ColumnTextsReader
+	textsReader ColumnTextsReader
+	errGroup    *errgroup.Group
+	err         error
+	done        chan struct{}
+}
+
+// textsJob is a unit of work for a worker, result is sent to the future in order of submission
+type textsJob struct {
+	result *ColumnTextsResult
+	future chan<- *textsBlockResult
+}
+
+type textsBlockResult struct {
+	// block is nil if all rows are skipped
+	block       *data.Block
+	end         InputMark
+	rowsRead    int
+	rowsSkipped int
+	err         error
 }
 
 func NewColumnTextsToBlock(ctStream <-chan *ColumnTextsResult, sample *data.Block) *ColumnTextsToBlock {
+	return NewColumnTextsToBlockWithOptions(ctStream, sample, ReadOptions{})
+}
+
+// NewColumnTextsToBlockWithOptions uses OnRowError and Progress of opts
+// to skip rows with texts which cannot be read into the sample column types,
+// and Parallelism for the number of workers reading texts into blocks
+func NewColumnTextsToBlockWithOptions(ctStream <-chan *ColumnTextsResult, sample *data.Block, opts ReadOptions) *ColumnTextsToBlock {
 	process := &ColumnTextsToBlock{
-		sample:   sample,
-		ctStream: ctStream,
+		sample:      sample,
+		ctStream:    ctStream,
+		opts:        opts,
+		parallelism: opts.Parallelism,
+	}
+	if process.parallelism < 1 {
+		process.parallelism = 1
 	}
 
 	return process
@@ -40,6 +75,10 @@ func (a *ColumnTextsToBlock) Start(ctx context.Context) <-chan *data.Block {
 	a.errGroup, ctx = errgroup.WithContext(ctx)
 	a.done = make(chan struct{})
 
+	// futures is bounded by parallelism and consumed in order, which keeps the blocks ordered
+	futures := make(chan chan *textsBlockResult, a.parallelism)
+	jobs := make(chan *textsJob, a.parallelism)
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -50,37 +89,18 @@ func (a *ColumnTextsToBlock) Start(ctx context.Context) <-chan *data.Block {
 		}()
 		defer close(outputStream)
 		defer close(a.done)
-		a.parallelism = 1
+
+		a.errGroup.Go(func() error {
+			return a.dispatch(ctx, jobs, futures)
+		})
 		for i := 0; i < a.parallelism; i++ {
 			a.errGroup.Go(func() error {
-				for {
-					select {
-					case <-ctx.Done():
-						return ctx.Err()
-					case result, ok := <-a.ctStream:
-						if !ok {
-							return nil
-						}
-
-						columnTexts := result.Get()
-						colTextsTrimSpace(columnTexts)
-						numRows := len(columnTexts[0])
-						newBlock := a.sample.StructureCopy(numRows)
-						rowsRead, colsRead, err := newBlock.ReadFromColumnTexts(columnTexts)
-
-						a.rowsProcessed += rowsRead
-						if err != nil {
-							return fmt.Errorf(
-								"reading into block error. row_idx: %v, col_idx: %v, name: %v, type: %v, given: %v, err: %s",
-								rowsRead, colsRead, newBlock.Columns[colsRead].Name, newBlock.Columns[colsRead].Type, columnTexts[colsRead][rowsRead], err,
-							)
-						}
-						outputStream <- newBlock
-						result.Close()
-					}
-				}
+				return a.work(ctx, jobs)
 			})
 		}
+		a.errGroup.Go(func() error {
+			return a.collect(ctx, futures, outputStream)
+		})
 
 		a.err = a.errGroup.Wait()
 	}()
@@ -88,11 +108,251 @@ func (a *ColumnTextsToBlock) Start(ctx context.Context) <-chan *data.Block {
 	return outputStream
 }
 
+// dispatch hands out column texts to workers, registering a future for each of them
+func (a *ColumnTextsToBlock) dispatch(ctx context.Context, jobs chan<- *textsJob, futures chan<- chan *textsBlockResult) error {
+	defer close(jobs)
+	defer close(futures)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result, ok := <-a.ctStream:
+			if !ok {
+				return nil
+			}
+
+			future := make(chan *textsBlockResult, 1)
+			select {
+			case futures <- future:
+			case <-ctx.Done():
+				result.Close()
+				return ctx.Err()
+			}
+			select {
+			case jobs <- &textsJob{result: result, future: future}:
+			case <-ctx.Done():
+				result.Close()
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (a *ColumnTextsToBlock) work(ctx context.Context, jobs <-chan *textsJob) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case job, ok := <-jobs:
+			if !ok {
+				return nil
+			}
+			job.future <- a.safeToBlock(job.result)
+		}
+	}
+}
+
+// safeToBlock recovers a panic of toBlock into an error result, so the future is always completed
+func (a *ColumnTextsToBlock) safeToBlock(result *ColumnTextsResult) (blockResult *textsBlockResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+			blockResult = &textsBlockResult{err: fmt.Errorf("panic while reading into block: %v", r)}
+		}
+	}()
+
+	columnTexts := result.Get()
+	if !a.keepSpaces {
+		colTextsTrimSpace(columnTexts)
+	}
+	blockResult = a.toBlock(columnTexts, result.positions)
+	blockResult.end = result.end
+	result.Close()
+	return blockResult
+}
+
+// collect waits on each future in order of submission and emits its block
+func (a *ColumnTextsToBlock) collect(ctx context.Context, futures <-chan chan *textsBlockResult, outputStream chan<- *data.Block) error {
+	for future := range futures {
+		var result *textsBlockResult
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result = <-future:
+		}
+
+		a.rowsProcessed += result.rowsRead
+		a.rowsSkipped += result.rowsSkipped
+		if result.err != nil {
+			return result.err
+		}
+		if result.block == nil { // all rows skipped
+			continue
+		}
+		if a.opts.OnBlock != nil {
+			a.opts.OnBlock(result.end)
+		}
+		select {
+		case <-ctx.Done():
+			_ = result.block.Close()
+			return ctx.Err()
+		case outputStream <- result.block:
+		}
+	}
+	return nil
+}
+
+// toBlock reads columnTexts into a new block, rows which cannot be read are skipped
+// if OnRowError is set. Result block is nil if all rows are skipped.
+func (a *ColumnTextsToBlock) toBlock(columnTexts [][]string, positions []RowPosition) *textsBlockResult {
+	newBlock := a.sample.StructureCopy(len(columnTexts[0]))
+	rowsRead, colsRead, err := a.readBlock(newBlock, columnTexts)
+	if err == nil {
+		return &textsBlockResult{block: newBlock, rowsRead: rowsRead}
+	}
+	if a.opts.OnRowError == nil || rowsRead >= len(positions) {
+		return &textsBlockResult{rowsRead: rowsRead, err: fmt.Errorf(
+			"reading into block error. row_idx: %v, col_idx: %v, name: %v, type: %v, given: %v, err: %s",
+			rowsRead, colsRead, newBlock.Columns[colsRead].Name, newBlock.Columns[colsRead].Type, columnTexts[colsRead][rowsRead], err,
+		)}
+	}
+	_ = newBlock.Close()
+
+	badRows := a.findBadRows(columnTexts, badRow{row: rowsRead, col: colsRead, err: err})
+	for i, bad := range badRows {
+		texts := make([]string, len(columnTexts))
+		for j := range columnTexts {
+			texts[j] = cloneString(columnTexts[j][bad.row])
+		}
+		if err := a.opts.OnRowError(&RowError{
+			Row:    positions[bad.row].Row,
+			Line:   positions[bad.row].Line,
+			Column: a.sample.Columns[bad.col].Name,
+			Value:  texts[bad.col],
+			Texts:  texts,
+			Err:    bad.err,
+		}); err != nil {
+			return &textsBlockResult{rowsSkipped: i, err: err}
+		}
+		if a.opts.Progress != nil {
+			a.opts.Progress.addRowsSkipped(1)
+		}
+	}
+
+	columnTexts, positions = removeRows(columnTexts, positions, badRows)
+	if len(positions) == 0 {
+		return &textsBlockResult{rowsSkipped: len(badRows)}
+	}
+	newBlock = a.sample.StructureCopy(len(positions))
+	rowsRead, colsRead, err = a.readBlock(newBlock, columnTexts)
+	if err != nil {
+		return &textsBlockResult{rowsRead: rowsRead, rowsSkipped: len(badRows), err: fmt.Errorf(
+			"reading into block error. row_idx: %v, col_idx: %v, name: %v, type: %v, given: %v, err: %s",
+			rowsRead, colsRead, newBlock.Columns[colsRead].Name, newBlock.Columns[colsRead].Type, columnTexts[colsRead][rowsRead], err,
+		)}
+	}
+	return &textsBlockResult{block: newBlock, rowsRead: rowsRead, rowsSkipped: len(badRows)}
+}
+
+// readBlock reads columnTexts into b as in Block.ReadFromColumnTexts, by textsReader if set
+func (a *ColumnTextsToBlock) readBlock(b *data.Block, columnTexts [][]string) (rowsRead, columnsRead int, err error) {
+	if a.textsReader == nil {
+		return b.ReadFromColumnTexts(columnTexts)
+	}
+	if len(columnTexts) != b.NumColumns {
+		return 0, 0, fmt.Errorf("incorrect number of column, given: %v, expected: %v", len(columnTexts), b.NumColumns)
+	}
+	for colIdx, colTexts := range columnTexts {
+		if rowsRead, err = a.readTexts(b.Columns[colIdx].Data, colTexts); err != nil {
+			return rowsRead, colIdx, err
+		}
+	}
+	return rowsRead, len(columnTexts), nil
+}
+
+// readTexts reads texts into data, by textsReader if set
+func (a *ColumnTextsToBlock) readTexts(data column.CHColumnData, texts []string) (int, error) {
+	if a.textsReader == nil {
+		return data.ReadFromTexts(texts)
+	}
+	return a.textsReader.ReadColumnTexts(data, texts)
+}
+
+// rowsPerValidation is the number of texts read at once into a scratch column when looking for bad rows,
+// bounds the texts read again after each bad row
+const rowsPerValidation = 256
+
+// badRow is a row which cannot be read, with the first column failing
+type badRow struct {
+	row int
+	col int
+	err error
+}
+
+// findBadRows returns the bad rows of columnTexts ordered by row, given the first error of reading them.
+// Columns are read as in Block.ReadFromColumnTexts, so all columns before first are valid and
+// the rows of the first column are only validated after the first bad row.
+func (a *ColumnTextsToBlock) findBadRows(columnTexts [][]string, first badRow) []badRow {
+	byRow := map[int]badRow{first.row: first}
+	for col := first.col; col < len(columnTexts); col++ {
+		from := 0
+		if col == first.col {
+			from = first.row + 1
+		}
+		a.validateTexts(col, columnTexts[col], from, func(row int, err error) {
+			if _, ok := byRow[row]; !ok {
+				byRow[row] = badRow{row: row, col: col, err: err}
+			}
+		})
+	}
+
+	badRows := make([]badRow, 0, len(byRow))
+	for _, bad := range byRow {
+		badRows = append(badRows, bad)
+	}
+	sort.Slice(badRows, func(i, j int) bool {
+		return badRows[i].row < badRows[j].row
+	})
+	return badRows
+}
+
+// validateTexts reads texts from the row at from into scratch columns, calling onBad for each row which cannot be read
+func (a *ColumnTextsToBlock) validateTexts(col int, texts []string, from int, onBad func(row int, err error)) {
+	generate := a.sample.Columns[col].GenerateColumn
+	for from < len(texts) {
+		end := from + rowsPerValidation
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		scratch := generate(end - from)
+		n, err := a.readTexts(scratch, texts[from:end])
+		_ = scratch.Close()
+		if err == nil {
+			from = end
+			continue
+		}
+		if from+n >= end { // error without the bad row, treat last row as bad
+			n = end - from - 1
+		}
+		onBad(from+n, err)
+		from += n + 1
+	}
+}
+
 func (a *ColumnTextsToBlock) Finish() (rowsProcessed int, err error) {
 	<-a.done
 	return a.rowsProcessed, a.err
 }
 
+// RowsSkipped returns the number of rows skipped because they could not be read into block
+func (a *ColumnTextsToBlock) RowsSkipped() int {
+	return a.rowsSkipped
+}
+
 func (a *ColumnTextsToBlock) Error() error {
 	return a.err
 }
@@ -104,3 +364,26 @@ func colTextsTrimSpace(colTexts [][]string) {
 		}
 	}
 }
+
+// removeRows removes the bad rows in place, badRows must be ordered by row
+func removeRows(columnTexts [][]string, positions []RowPosition, badRows []badRow) ([][]string, []RowPosition) {
+	keep := func(n int, move func(dst, src int)) int {
+		var kept, next int
+		for i := 0; i < n; i++ {
+			if next < len(badRows) && badRows[next].row == i {
+				next++
+				continue
+			}
+			move(kept, i)
+			kept++
+		}
+		return kept
+	}
+
+	for i, col := range columnTexts {
+		kept := keep(len(col), func(dst, src int) { col[dst] = col[src] })
+		columnTexts[i] = col[:kept]
+	}
+	kept := keep(len(positions), func(dst, src int) { positions[dst] = positions[src] })
+	return columnTexts, positions[:kept]
+}
@@ -306,3 +306,56 @@ func TestColumnTextsToBlock_0_Rows(t *testing.T) {
 
 	assert.Equal(t, expected, actual)
 }
+
+func TestColumnTextsToBlock_Parallelism_KeepsOrder(t *testing.T) {
+	ctx := context.Background()
+	b := getSampleBlock()
+	blockSize := 5
+
+	colTexstStreamer := NewColumnTextsStreamer(b, blockSize, newTestTableReader(100, -1))
+	colTextsStream := colTexstStreamer.Start(ctx)
+	go colTexstStreamer.Finish()
+
+	toBlock := NewColumnTextsToBlockWithOptions(colTextsStream, b, ReadOptions{Parallelism: 4})
+	blockOutputStream := toBlock.Start(ctx)
+
+	var i int
+	for b := range blockOutputStream {
+		assert.Equal(t, blockSize, b.NumRows)
+		assert.Equal(t, fmt.Sprint(i*2*blockSize), b.Columns[0].Data.GetString(0))
+		i++
+	}
+	rowsProcessed, err := toBlock.Finish()
+	assert.NoError(t, err)
+	assert.Equal(t, 20, i)
+	assert.Equal(t, 100, rowsProcessed)
+}
+
+func TestColumnTextsToBlock_toBlock_SkipsBadRows(t *testing.T) {
+	const numRows = 2*rowsPerValidation + 10
+	bad := map[int]bool{1: true, rowsPerValidation: true, rowsPerValidation + 1: true, numRows - 1: true}
+	columnTexts := [][]string{make([]string, numRows), make([]string, numRows)}
+	positions := make([]RowPosition, numRows)
+	for i := 0; i < numRows; i++ {
+		columnTexts[0][i] = fmt.Sprint(i)
+		if bad[i] {
+			columnTexts[0][i] = "x"
+		}
+		columnTexts[1][i] = "s"
+		positions[i] = RowPosition{Row: i, Line: i + 1}
+	}
+
+	var skipped []int
+	toBlock := NewColumnTextsToBlockWithOptions(nil, getSampleBlock(), ReadOptions{
+		OnRowError: func(rowErr *RowError) error {
+			skipped = append(skipped, rowErr.Row)
+			return nil
+		},
+	})
+	result := toBlock.toBlock(columnTexts, positions)
+	assert.NoError(t, result.err)
+	assert.Equal(t, []int{1, rowsPerValidation, rowsPerValidation + 1, numRows - 1}, skipped)
+	assert.Equal(t, len(bad), result.rowsSkipped)
+	assert.Equal(t, numRows-len(bad), result.block.NumRows)
+	assert.Equal(t, "2", result.block.Columns[0].Data.GetString(1))
+}
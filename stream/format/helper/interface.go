@@ -7,3 +7,18 @@ type Writer interface {
 	io.StringWriter
 	io.ByteWriter
 }
+
+// discardWriter is a Writer on which all writes succeed without doing anything
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (discardWriter) WriteString(s string) (int, error) {
+	return len(s), nil
+}
+
+func (discardWriter) WriteByte(byte) error {
+	return nil
+}
@@ -0,0 +1,183 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// ReadOptions configures how a table is read into blocks
+type ReadOptions struct {
+	Limits BlockLimits
+	// OnRowError is called for every row which cannot be read into a block.
+	// Reading continues with the next row if it returns nil, otherwise stops with the returned error.
+	// If OnRowError is nil, the first row error stops reading.
+	OnRowError RowErrorHandler
+	// Progress is updated as rows are read if not nil
+	Progress *ReadProgress
+	// Parallelism is the number of workers reading column texts into blocks, 1 if less.
+	// Blocks keep the order of the input, OnRowError may be called concurrently.
+	Parallelism int
+	// Resume continues a previous read of the same input after the mark if not nil,
+	// the table reader has to be a Resumer
+	Resume *InputMark
+	// OnBlock is called in order of blocks right before each block is emitted,
+	// with the input consumed up to the end of the block
+	OnBlock func(end InputMark)
+}
+
+// InputMark is a point in the input between rows
+type InputMark struct {
+	// Offset is the number of bytes of input before the mark
+	Offset int64
+	// Rows is the number of rows before the mark, including rows skipped
+	Rows int
+}
+
+// RowErrorHandler handles an error of a single row, see ReadOptions.OnRowError
+type RowErrorHandler func(rowErr *RowError) error
+
+// RowError is the error of a single row which could not be read
+type RowError struct {
+	// Row is the 0-based index of the row in the input
+	Row int
+	// Line is the 1-based line in the input where the error was found, 0 if unknown
+	Line int
+	// Column is the name of the column which could not be read, empty if the row could not be parsed
+	Column string
+	// Value is the text of the column which could not be read
+	Value string
+	// Texts are the texts of all columns of the row, nil if the row could not be parsed
+	Texts []string
+	// Raw is the input of a row which could not be parsed, if the table reader is a RawRowReader
+	Raw string
+	Err error
+}
+
+func (e *RowError) Error() string {
+	var sb strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&sb, "line %v, ", e.Line)
+	}
+	fmt.Fprintf(&sb, "row %v", e.Row)
+	if e.Column != "" {
+		fmt.Fprintf(&sb, ", column %v, value %q", e.Column, e.Value)
+	}
+	fmt.Fprintf(&sb, ": %s", e.Err)
+	return sb.String()
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// rowErrorsInMessage is the number of row errors printed by RowErrors.Error
+const rowErrorsInMessage = 10
+
+// RowErrors is a list of row errors
+type RowErrors []*RowError
+
+// Error prints the first rowErrorsInMessage errors, all of them are kept in the list
+func (e RowErrors) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v row errors", len(e))
+	for i, rowErr := range e {
+		if i == rowErrorsInMessage {
+			fmt.Fprintf(&sb, "\n... and %v more", len(e)-i)
+			break
+		}
+		sb.WriteString("\n")
+		sb.WriteString(rowErr.Error())
+	}
+	return sb.String()
+}
+
+// ReadProgress tracks the progress of reading a table, safe for concurrent use
+type ReadProgress struct {
+	bytesRead   int64
+	rowsRead    int64
+	rowsSkipped int64
+}
+
+// BytesRead returns the number of input bytes consumed, 0 if the reader cannot tell
+func (p *ReadProgress) BytesRead() int64 {
+	if p == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&p.bytesRead)
+}
+
+// RowsRead returns the number of rows parsed from the input
+func (p *ReadProgress) RowsRead() int {
+	if p == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&p.rowsRead))
+}
+
+// RowsSkipped returns the number of rows skipped because of row errors
+func (p *ReadProgress) RowsSkipped() int {
+	if p == nil {
+		return 0
+	}
+	return int(atomic.LoadInt64(&p.rowsSkipped))
+}
+
+func (p *ReadProgress) setBytesRead(n int64) {
+	atomic.StoreInt64(&p.bytesRead, n)
+}
+
+func (p *ReadProgress) addRowsRead(n int) {
+	atomic.AddInt64(&p.rowsRead, int64(n))
+}
+
+func (p *ReadProgress) addRowsSkipped(n int) {
+	atomic.AddInt64(&p.rowsSkipped, int64(n))
+}
+
+// PositionReader is implemented by table readers which can tell their position in the input
+type PositionReader interface {
+	// InputPosition returns the number of bytes consumed and the line of the last byte consumed
+	InputPosition() (offset int64, line int)
+}
+
+// RowSkipper is implemented by table readers which can resume reading after a row error
+type RowSkipper interface {
+	// SkipRow discards input until the start of the next row
+	SkipRow() error
+}
+
+// RawRowReader is implemented by table readers which can return the input of a row which could not be parsed
+type RawRowReader interface {
+	// RecordRows makes the reader record the input of each row from its start
+	RecordRows()
+	// RawRow returns the input of the row being read, up to the input consumed, without the line breaks around it
+	RawRow() string
+}
+
+// Resumer is implemented by table readers which can continue a previous read of the same input
+type Resumer interface {
+	// ResumeAt discards input until offset, which must be between rows, so that
+	// the next row is read by ReadColumnTextsCont
+	ResumeAt(offset int64, cols []*column.CHColumn) error
+}
+
+// SpaceKeeper is implemented by table readers of which texts may begin or end with significant spaces
+type SpaceKeeper interface {
+	// KeepSpaces tells if texts are read into blocks as is, instead of trimmed
+	KeepSpaces() bool
+}
+
+// ColumnTextsReader is implemented by table readers of which texts are not read into columns as is,
+// e.g. because NULL is only told apart from other values by their escaping
+type ColumnTextsReader interface {
+	// ReadColumnTexts reads the texts of a column into data, returns the number of texts read
+	ReadColumnTexts(data column.CHColumnData, texts []string) (int, error)
+}
+
+// cloneString copies s, texts read from input share buffers which are reused after the block is built
+func cloneString(s string) string {
+	return string(append([]byte(nil), s...))
+}
@@ -0,0 +1,21 @@
+package helper
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowErrors_Error(t *testing.T) {
+	rowErrs := make(RowErrors, rowErrorsInMessage+5)
+	for i := range rowErrs {
+		rowErrs[i] = &RowError{Row: i, Err: errors.New("bad")}
+	}
+
+	msg := rowErrs.Error()
+	assert.True(t, strings.HasPrefix(msg, "15 row errors\n"))
+	assert.Equal(t, rowErrorsInMessage+2, strings.Count(msg, "\n")+1)
+	assert.True(t, strings.HasSuffix(msg, "\n... and 5 more"))
+}
@@ -0,0 +1,34 @@
+package helper
+
+import (
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+)
+
+// RowRecorder implements RawRowReader for table readers reading from a ZReader,
+// which call StartRow at the start of each row
+type RowRecorder struct {
+	zReader *bytepool.ZReader
+	enabled bool
+}
+
+func NewRowRecorder(zReader *bytepool.ZReader) RowRecorder {
+	return RowRecorder{zReader: zReader}
+}
+
+func (r *RowRecorder) RecordRows() {
+	r.enabled = true
+}
+
+// StartRow starts recording a row if rows are recorded
+func (r *RowRecorder) StartRow() {
+	if r.enabled {
+		r.zReader.Record()
+	}
+}
+
+// RawRow returns the input of the row without the line breaks around it
+func (r *RowRecorder) RawRow() string {
+	return strings.Trim(string(r.zReader.Recorded()), "\r\n")
+}
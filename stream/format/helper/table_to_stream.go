@@ -9,14 +9,25 @@ import (
 )
 
 func TableToBlockStream(ctx context.Context, sample *data.Block, blockSize int, tReader TableReader,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return TableToBlockStreamWithOptions(ctx, sample, ReadOptions{Limits: BlockLimits{Rows: blockSize}}, tReader)
+}
+
+func TableToBlockStreamWithOptions(ctx context.Context, sample *data.Block, opts ReadOptions, tReader TableReader,
 ) (blockStream <-chan *data.Block, yield func() (int, error)) {
 
 	eg, ctx := errgroup.WithContext(ctx)
 
-	colTextsStreamer := NewColumnTextsStreamer(sample, blockSize, tReader)
+	colTextsStreamer := NewColumnTextsStreamerWithOptions(sample, opts, tReader)
 	colTextsStream := colTextsStreamer.Start(ctx)
 
-	toBlockProcess := NewColumnTextsToBlock(colTextsStream, sample)
+	toBlockProcess := NewColumnTextsToBlockWithOptions(colTextsStream, sample, opts)
+	if keeper, ok := tReader.(SpaceKeeper); ok {
+		toBlockProcess.keepSpaces = keeper.KeepSpaces()
+	}
+	if textsReader, ok := tReader.(ColumnTextsReader); ok {
+		toBlockProcess.textsReader = textsReader
+	}
 	blockStream = toBlockProcess.Start(ctx)
 	return blockStream, YieldTableStream(eg, colTextsStreamer, toBlockProcess)
 }
@@ -41,7 +52,7 @@ func YieldTableStream(eg *errgroup.Group, colTextsStreamer *ColumnTextsStreamer,
 			return numRowBlocks, err
 		}
 
-		if numRowTexts != numRowBlocks {
+		if numRowTexts != numRowBlocks+toBlockProcess.RowsSkipped() {
 			return numRowBlocks, fmt.Errorf(
 				"short rows to blocks, rows read: %v, rows processed: %v",
 				numRowTexts, numRowBlocks,
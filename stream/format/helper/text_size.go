@@ -0,0 +1,83 @@
+package helper
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// textSizeEstimator estimates the encoded size of rows read as texts.
+// Each row counts the encoded width of a zero row, plus the length of the texts
+// of columns which size depends on the value, such as String or Array.
+type textSizeEstimator struct {
+	rowSize  int
+	variable []bool
+	// counted is the number of elems of the current frame buffer already counted
+	counted int
+	size    int
+}
+
+func newTextSizeEstimator(cols []*column.CHColumn) *textSizeEstimator {
+	e := &textSizeEstimator{variable: make([]bool, len(cols))}
+	for i, col := range cols {
+		e.rowSize += zeroRowEncodedSize(col)
+		e.variable[i] = isVariableSize(col.Type)
+	}
+	return e
+}
+
+// reset starts the estimate of a new frame buffer
+func (e *textSizeEstimator) reset() {
+	e.counted = 0
+	e.size = 0
+}
+
+// count adds the rows of fb read since the last count and returns the estimated size of fb
+func (e *textSizeEstimator) count(fb *bytepool.FrameBuffer) int {
+	numCols := len(e.variable)
+	if numCols == 0 {
+		return 0
+	}
+	total := fb.StringsBuffer.Len()
+	for ; e.counted < total; e.counted++ {
+		col := e.counted % numCols
+		if col == 0 {
+			e.size += e.rowSize
+		}
+		if e.variable[col] {
+			e.size += fb.StringsBuffer.ElemSize(e.counted)
+		}
+	}
+	return e.size
+}
+
+// zeroRowEncodedSize returns the number of bytes a row of zero value adds to the encoded column
+func zeroRowEncodedSize(col *column.CHColumn) int {
+	return encodedSize(col, 1) - encodedSize(col, 0)
+}
+
+func encodedSize(col *column.CHColumn, numRows int) int {
+	if col.GenerateColumn == nil {
+		return 0
+	}
+	var buf bytes.Buffer
+	data := col.GenerateColumn(numRows)
+	defer data.Close()
+	if err := data.WriteToEncoder(ch_encoding.NewEncoder(&buf)); err != nil {
+		return 0
+	}
+	return buf.Len()
+}
+
+func isVariableSize(t column.CHColumnType) bool {
+	s := strings.ReplaceAll(string(t), string(column.FIXEDSTRING), "")
+	for _, variable := range []column.CHColumnType{column.STRING, column.ARRAY, column.MAP, column.BITMAP64, column.RING, column.POLYGON, column.NESTED} {
+		if strings.Contains(s, string(variable)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,566 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+// jsonUnescape maps the byte after a backslash to the byte it escapes in a JSON string, except \u
+var jsonUnescape = map[byte]byte{
+	'"':  '"',
+	'\\': '\\',
+	'/':  '/',
+	'b':  '\b',
+	'f':  '\f',
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+}
+
+// JSONEachRowBlockStreamFmtReader reads a JSON value per row, in constant memory.
+// Rows of JSONEachRow are objects with a key per column, in any order. Columns without a key are filled with
+// the default of the table column if set with SetColumnDefaults and a literal, else the default value of their type,
+// NULL if nullable. Keys of unknown columns are skipped, unless input_format_skip_unknown_fields is set to false.
+// The rows may also be wrapped in an array and separated by commas.
+// Rows of JSONCompactEachRow are arrays with a value per column, in the order of the table columns,
+// optionally after a line with the names and one with the types of the columns.
+// Arrays, maps and tuples are read from JSON arrays and objects.
+type JSONEachRowBlockStreamFmtReader struct {
+	zReader *bytepool.ZReader
+	compact bool
+	// headerLines are the lines before the data, names and types of columns
+	headerLines int
+
+	colIdxByName map[string]int
+	// texts and filled are the values of the row being read and whether they were found, ordered as columns
+	texts  []bytes.Buffer
+	filled []bool
+	key    bytes.Buffer
+	// skipped holds values which are not written into texts, such as those of unknown columns
+	skipped bytes.Buffer
+	// nestedString holds the strings within arrays and objects
+	nestedString bytes.Buffer
+	// inRow is true from the opening until the closing bracket of a row, used to skip a row on error
+	inRow bool
+
+	skipUnknown bool
+	defaults    columnDefaults
+	helper.RowRecorder
+}
+
+func NewJSONEachRowBlockStreamFmtReader(r io.Reader, compact, withNames, withTypes bool) *JSONEachRowBlockStreamFmtReader {
+	var headerLines int
+	if withNames {
+		headerLines++
+	}
+	if withTypes {
+		headerLines++
+	}
+	zReader := bytepool.NewZReaderDefault(&r)
+	return &JSONEachRowBlockStreamFmtReader{
+		zReader:     zReader,
+		RowRecorder: helper.NewRowRecorder(zReader),
+		compact:     compact,
+		headerLines: headerLines,
+		skipUnknown: true,
+	}
+}
+
+// SetColumnDefaults sets the defaults of columns without a key
+func (j *JSONEachRowBlockStreamFmtReader) SetColumnDefaults(exprs map[string]string) {
+	j.defaults = newColumnDefaults(exprs)
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) BlockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStream(ctx, sample, blockSize, j)
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) BlockStreamFmtReadWithOptions(
+	ctx context.Context, sample *data.Block, opts helper.ReadOptions,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStreamWithOptions(ctx, sample, opts, j)
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) ReadFirstColumnTexts(
+	fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn,
+) (int, error) {
+	return helper.ReadFirstColumnTexts(fb, numRows, cols, j)
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) ReadColumnTextsCont(
+	fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn,
+) (int, error) {
+	return helper.ReadColumnTextsCont(fb, numRows, cols, j)
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) ReadFirstRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	if err := j.discardHeader(); err != nil {
+		return err
+	}
+	return j.ReadRowCont(fb, cols)
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) ReadRowCont(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	j.StartRow()
+	if j.compact {
+		return j.readCompactRow(fb, cols)
+	}
+	return j.readRow(fb, cols)
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) discardHeader() error {
+	for i := 0; i < j.headerLines; i++ {
+		if err := helper.DiscardLine(j.zReader); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) InputPosition() (int64, int) {
+	return j.zReader.Position(), j.zReader.Line()
+}
+
+// ResumeAt discards the header lines and the input until offset
+func (j *JSONEachRowBlockStreamFmtReader) ResumeAt(offset int64, cols []*column.CHColumn) error {
+	if err := j.discardHeader(); err != nil {
+		return err
+	}
+	return helper.DiscardUntilPosition(j.zReader, offset)
+}
+
+// SkipRow discards the rest of the row which failed to be read,
+// or the rest of the line if the failed row has not started
+func (j *JSONEachRowBlockStreamFmtReader) SkipRow() error {
+	if j.inRow {
+		j.inRow = false
+		closing := "}"
+		if j.compact {
+			closing = "]"
+		}
+		_, err := helper.DiscardUntilUnnested(j.zReader, closing)
+		return err
+	}
+	if j.zReader.AtLineStart() {
+		return nil
+	}
+	return helper.DiscardLine(j.zReader)
+}
+
+// readRow reads an object, e.g. {"b": "x", "a": 1}, into the texts of cols
+func (j *JSONEachRowBlockStreamFmtReader) readRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	b, err := j.readRowStart()
+	if err != nil {
+		return err
+	}
+	if b != '{' {
+		return fmt.Errorf("expect byte: %q, but got: %q", '{', b)
+	}
+	return j.readObject(fb, cols)
+}
+
+// readObject reads the fields of an object into the texts of cols, the opening brace is read already
+func (j *JSONEachRowBlockStreamFmtReader) readObject(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	j.inRow = true
+	j.resetRow(cols)
+	if err := j.readFields(cols); err != nil {
+		return unexpectedEOF(err)
+	}
+	j.inRow = false
+
+	for i, col := range cols {
+		fb.NewElem()
+		if !j.filled[i] {
+			text, err := j.defaults.text(col, jsonDefaultText)
+			if err != nil {
+				return err
+			}
+			fb.WriteString(text)
+			continue
+		}
+		fb.Write(j.texts[i].Bytes())
+	}
+	return nil
+}
+
+// readFields reads the fields of an object until its closing brace
+func (j *JSONEachRowBlockStreamFmtReader) readFields(cols []*column.CHColumn) error {
+	b, err := helper.ReadNextNonSpaceByte(j.zReader)
+	if err != nil {
+		return err
+	}
+	if b == '}' {
+		return nil
+	}
+	j.zReader.UnreadCurrentBuffer(1)
+	for b != '}' {
+		if err := j.readField(cols); err != nil {
+			return err
+		}
+		if b, err = helper.ReadNextNonSpaceByte(j.zReader); err != nil {
+			return err
+		}
+		if b != ',' && b != '}' {
+			return fmt.Errorf("expect byte: %q or %q, but got: %q", ',', '}', b)
+		}
+	}
+	return nil
+}
+
+// readRowStart returns the first byte of the next row, skipping the brackets and commas around rows
+func (j *JSONEachRowBlockStreamFmtReader) readRowStart() (byte, error) {
+	for {
+		b, err := helper.ReadNextNonSpaceByte(j.zReader)
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case '[', ',':
+		case ']':
+			helper.FlushZReader(j.zReader)
+			return 0, io.EOF
+		default:
+			return b, nil
+		}
+	}
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) resetRow(cols []*column.CHColumn) {
+	if j.colIdxByName == nil {
+		j.colIdxByName = make(map[string]int, len(cols))
+		for i, col := range cols {
+			j.colIdxByName[col.Name] = i
+		}
+		j.texts = make([]bytes.Buffer, len(cols))
+		j.filled = make([]bool, len(cols))
+	}
+	for i := range j.filled {
+		j.filled[i] = false
+	}
+}
+
+// readField reads a key and its value, e.g. "a": 1
+func (j *JSONEachRowBlockStreamFmtReader) readField(cols []*column.CHColumn) error {
+	if err := helper.AssertNextByteEqual(j.zReader, '"'); err != nil {
+		return err
+	}
+	j.key.Reset()
+	if err := j.readString(&j.key); err != nil {
+		return err
+	}
+	if err := helper.AssertNextByteEqual(j.zReader, ':'); err != nil {
+		return fmt.Errorf("read colon error after key %q: %s", j.key.String(), err)
+	}
+
+	i, ok := j.colIdxByName[string(j.key.Bytes())]
+	if !ok { // unknown column
+		if !j.skipUnknown {
+			return fmt.Errorf("unknown column: %q", j.key.String())
+		}
+		j.skipped.Reset()
+		return j.readValue(&j.skipped, nil)
+	}
+	if j.filled[i] {
+		return fmt.Errorf("duplicate key: %q", cols[i].Name)
+	}
+	j.filled[i] = true
+	j.texts[i].Reset()
+	if err := j.readValue(&j.texts[i], cols[i]); err != nil {
+		return fmt.Errorf(errReadElem, cols[i].Type, cols[i].Name, i, err)
+	}
+	return nil
+}
+
+// readCompactRow reads an array, e.g. [1, "x"], into the texts of cols
+func (j *JSONEachRowBlockStreamFmtReader) readCompactRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	if err := helper.AssertNextByteEqual(j.zReader, '['); err != nil {
+		return err
+	}
+	j.inRow = true
+	if err := j.readValues(fb, cols); err != nil {
+		return unexpectedEOF(err)
+	}
+	j.inRow = false
+	return nil
+}
+
+// readValues reads a value per column of an array until its closing bracket
+func (j *JSONEachRowBlockStreamFmtReader) readValues(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	for i, col := range cols {
+		if i > 0 {
+			b, err := helper.ReadNextNonSpaceByte(j.zReader)
+			if err != nil {
+				return err
+			}
+			if b != ',' {
+				if b == ']' {
+					j.inRow = false
+					return fmt.Errorf("expect %v values, got %v", len(cols), i)
+				}
+				return fmt.Errorf("expect byte: %q, but got: %q", ',', b)
+			}
+		}
+		fb.NewElem()
+		if err := j.readValue(fb, col); err != nil {
+			return fmt.Errorf(errReadElem, col.Type, col.Name, i, err)
+		}
+	}
+
+	b, err := helper.ReadNextNonSpaceByte(j.zReader)
+	if err != nil {
+		return err
+	}
+	if b != ']' {
+		if b == ',' {
+			return fmt.Errorf("expect %v values, got more", len(cols))
+		}
+		return fmt.Errorf("expect byte: %q, but got: %q", ']', b)
+	}
+	return nil
+}
+
+// readValue writes the text of the next JSON value into w. Strings are unescaped, and quoted again for string
+// columns to keep their content as is. Arrays and objects are written as they are, and null is NULL
+// for nullable columns or the default of the column type otherwise. col is nil for values to discard.
+func (j *JSONEachRowBlockStreamFmtReader) readValue(w helper.Writer, col *column.CHColumn) error {
+	b, err := helper.ReadNextNonSpaceByte(j.zReader)
+	if err != nil {
+		return err
+	}
+
+	switch b {
+	case '"':
+		quoted := col != nil && isStringColumn(col.Data)
+		if quoted {
+			w.WriteByte('"')
+		}
+		if err := j.readString(w); err != nil {
+			return err
+		}
+		if quoted {
+			w.WriteByte('"')
+		}
+		return nil
+	case '[', '{':
+		w.WriteByte(b)
+		return j.readNested(w)
+	case 'n':
+		j.zReader.UnreadCurrentBuffer(1)
+		j.skipped.Reset()
+		if err := j.readToken(&j.skipped); err != nil {
+			return err
+		}
+		if j.skipped.String() != column.NULLSmall {
+			return fmt.Errorf("invalid value: %q", j.skipped.String())
+		}
+		if col != nil {
+			w.WriteString(jsonNullText(col))
+		}
+		return nil
+	}
+
+	j.zReader.UnreadCurrentBuffer(1)
+	return j.readToken(w)
+}
+
+// readString writes the unescaped content of a string into w, the opening quote is read already
+func (j *JSONEachRowBlockStreamFmtReader) readString(w helper.Writer) error {
+	for {
+		buf, err := j.zReader.ReadNextBuffer()
+		if err != nil {
+			return err
+		}
+
+		i := bytes.IndexAny(buf, `"\`)
+		if i < 0 {
+			w.Write(buf)
+			continue
+		}
+		w.Write(buf[:i])
+		j.zReader.UnreadCurrentBuffer(len(buf) - i - 1)
+		if buf[i] == '"' {
+			return nil
+		}
+		if err := j.readEscaped(w); err != nil {
+			return err
+		}
+	}
+}
+
+// readEscaped writes the character escaped by the bytes after a backslash into w
+func (j *JSONEachRowBlockStreamFmtReader) readEscaped(w helper.Writer) error {
+	b, err := j.zReader.ReadByte()
+	if err != nil {
+		return err
+	}
+	if unescaped, ok := jsonUnescape[b]; ok {
+		w.WriteByte(unescaped)
+		return nil
+	}
+	if b != 'u' {
+		return fmt.Errorf("invalid escape sequence: \\%c", b)
+	}
+
+	r, err := j.readHexRune()
+	if err != nil {
+		return err
+	}
+	if utf16.IsSurrogate(r) { // the low surrogate follows as another \u sequence
+		if err := helper.AssertNextByteEqualSameLine(j.zReader, '\\'); err != nil {
+			return err
+		}
+		if err := helper.AssertNextByteEqualSameLine(j.zReader, 'u'); err != nil {
+			return err
+		}
+		low, err := j.readHexRune()
+		if err != nil {
+			return err
+		}
+		r = utf16.DecodeRune(r, low)
+	}
+
+	var encoded [utf8.UTFMax]byte
+	w.Write(encoded[:utf8.EncodeRune(encoded[:], r)])
+	return nil
+}
+
+func (j *JSONEachRowBlockStreamFmtReader) readHexRune() (rune, error) {
+	var hexBuf [4]byte
+	for i := range hexBuf {
+		b, err := j.zReader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		hexBuf[i] = b
+	}
+	var decoded [2]byte
+	if _, err := hex.Decode(decoded[:], hexBuf[:]); err != nil {
+		return 0, fmt.Errorf("invalid unicode escape: %s", err)
+	}
+	return rune(decoded[0])<<8 | rune(decoded[1]), nil
+}
+
+// readNested writes an array or object into w until its closing bracket, the opening one is written already.
+// Strings in it are unescaped and quoted again by writeNestedString.
+func (j *JSONEachRowBlockStreamFmtReader) readNested(w helper.Writer) error {
+	depth := 1
+	for depth > 0 {
+		b, err := j.zReader.ReadByte()
+		if err != nil {
+			return err
+		}
+
+		switch b {
+		case '"':
+			j.nestedString.Reset()
+			if err := j.readString(&j.nestedString); err != nil {
+				return err
+			}
+			if err := writeNestedString(w, j.nestedString.Bytes()); err != nil {
+				return err
+			}
+			continue
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		}
+		w.WriteByte(b)
+	}
+	return nil
+}
+
+// nestedQuotes are the quotes of strings in the text of arrays, maps and tuples, by order of preference
+var nestedQuotes = []byte{'\'', '"', '`'}
+
+// writeNestedString writes s quoted as a string within the text of an array, map or tuple.
+// The text of these is split by quotes and not unescaped when read into columns,
+// so s is quoted with a quote which it does not contain.
+func writeNestedString(w helper.Writer, s []byte) error {
+	if bytes.HasSuffix(s, []byte{'\\'}) {
+		return fmt.Errorf("string in nested value cannot end with a backslash: %q", s)
+	}
+	for _, quote := range nestedQuotes {
+		if bytes.IndexByte(s, quote) >= 0 {
+			continue
+		}
+		w.WriteByte(quote)
+		w.Write(s)
+		w.WriteByte(quote)
+		return nil
+	}
+	return fmt.Errorf("string in nested value cannot contain all of the quotes %q: %q", nestedQuotes, s)
+}
+
+// readToken writes the bytes of a number, true, false or null into w
+func (j *JSONEachRowBlockStreamFmtReader) readToken(w helper.Writer) error {
+	for {
+		buf, err := j.zReader.ReadNextBuffer()
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		i := bytes.IndexAny(buf, ",]} \t\r\n")
+		if i < 0 {
+			w.Write(buf)
+			continue
+		}
+		w.Write(buf[:i])
+		j.zReader.UnreadCurrentBuffer(len(buf) - i)
+		return nil
+	}
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF for io.EOF within a row, which is otherwise taken as the end of rows
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// jsonNullText returns the text of null for col
+func jsonNullText(col *column.CHColumn) string {
+	if _, ok := col.Data.(*column.NullableColumnData); ok {
+		return column.NULL
+	}
+	return jsonDefaultText(col)
+}
+
+// jsonDefaultText returns the text of the default value of col, used for missing keys
+func jsonDefaultText(col *column.CHColumn) string {
+	switch col.Data.(type) {
+	case *column.NullableColumnData:
+		return column.NULL
+	case *column.TupleColumnData: // all texts of a block need the square brackets of tuples read from JSON arrays
+		zero := col.Data.ZeroString()
+		return "[" + zero[1:len(zero)-1] + "]"
+	}
+	return col.Data.ZeroString()
+}
+
+// isStringColumn returns true if values of the column are strings
+func isStringColumn(data column.CHColumnData) bool {
+	switch data := data.(type) {
+	case *column.StringColumnData, *column.FixedStringColumnData:
+		return true
+	case *column.NullableColumnData:
+		return isStringColumn(data.GetInnerColumnData())
+	}
+	return false
+}
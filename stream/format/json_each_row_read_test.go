@@ -0,0 +1,192 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func readRowStrings(t *testing.T, format, input string, names []string, types []column.CHColumnType) ([][]string, error) {
+	return readRowStringsWithSettings(t, format, []byte(input), names, types, nil)
+}
+
+// readRowStringsWithSettings reads the rows of input in format with settings, in blocks of 2 rows
+func readRowStringsWithSettings(t *testing.T, format string, input []byte, names []string, types []column.CHColumnType,
+	settings map[string]interface{},
+) ([][]string, error) {
+	r, err := BlockStreamFmtReaderFactory(format, bytes.NewReader(input), settings)
+	require.NoError(t, err)
+	sample, err := data.NewBlock(names, types, 0)
+	require.NoError(t, err)
+
+	blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+	var rows [][]string
+	for b := range blockStream {
+		for i := 0; i < b.NumRows; i++ {
+			row := make([]string, b.NumColumns)
+			for j, col := range b.Columns {
+				row[j] = col.Data.GetString(i)
+			}
+			rows = append(rows, row)
+		}
+	}
+	_, err = yield()
+	return rows, err
+}
+
+func TestJSONEachRowBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
+	names := []string{"a", "b", "c"}
+	types := []column.CHColumnType{"Int32", "String", "Nullable(String)"}
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:   "Should read an object per line",
+			format: "JSONEachRow",
+			input:  "{\"a\": 1, \"b\": \"x\", \"c\": \"y\"}\n{\"a\": 2, \"b\": \"z\", \"c\": null}\n",
+			want:   [][]string{{"1", "x", "y"}, {"2", "z", column.NULLDisplay}},
+		},
+		{
+			name:   "Should read keys in any order",
+			format: "NDJSON",
+			input:  `{"c": "y", "a": 1, "b": "x"}`,
+			want:   [][]string{{"1", "x", "y"}},
+		},
+		{
+			name:   "Should fill missing keys with defaults and skip unknown keys",
+			format: "JSONLines",
+			input:  "{\"b\": \"x\", \"d\": [1, {\"e\": \"}\"}]}\n{}\n",
+			want:   [][]string{{"0", "x", column.NULLDisplay}, {"0", "", column.NULLDisplay}},
+		},
+		{
+			name:   "Should unescape strings and keep their content",
+			format: "JSONEachRow",
+			input:  `{"a": 1, "b": "'q'\t\"\\é😀", "c": "NULL"}`,
+			want:   [][]string{{"1", "'q'\t\"\\é😀", "NULL"}},
+		},
+		{
+			name:   "Should read rows in an array",
+			format: "JSONEachRow",
+			input:  `[{"a": 1}, {"a": 2}, {"a": 3}]`,
+			want: [][]string{
+				{"1", "", column.NULLDisplay}, {"2", "", column.NULLDisplay}, {"3", "", column.NULLDisplay},
+			},
+		},
+		{
+			name:   "Should read compact rows",
+			format: "JSONCompactEachRow",
+			input:  "[1, \"x\", null]\n[2, \"y\", \"z\"]",
+			want:   [][]string{{"1", "x", column.NULLDisplay}, {"2", "y", "z"}},
+		},
+		{
+			name:   "Should skip names and types of compact rows",
+			format: "JSONCompactEachRowWithNamesAndTypes",
+			input:  "[\"a\", \"b\", \"c\"]\n[\"Int32\", \"String\", \"Nullable(String)\"]\n[1, \"x\", \"y\"]\n",
+			want:   [][]string{{"1", "x", "y"}},
+		},
+		{
+			name:    "Should fail on duplicate keys",
+			format:  "JSONEachRow",
+			input:   `{"a": 1, "a": 2}`,
+			wantErr: true,
+		},
+		{
+			name:    "Should fail on a truncated row",
+			format:  "JSONEachRow",
+			input:   "{\"a\": 1}\n{\"a\": 2, ",
+			wantErr: true,
+		},
+		{
+			name:    "Should fail on too few compact values",
+			format:  "JSONCompactEachRow",
+			input:   `[1, "x"]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readRowStrings(t, tt.format, tt.input, names, types)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestJSONEachRowBlockStreamFmtReader_Nested(t *testing.T) {
+	rows, err := readRowStrings(t, "JSONEachRow",
+		`{"arr": ["a", "b,c"], "m": {"k": 1, "l": 2}, "tup": [1, "x"]}`+"\n"+`{"arr": [], "m": {}}`,
+		[]string{"arr", "m", "tup"},
+		[]column.CHColumnType{"Array(String)", "Map(String, Int32)", "Tuple(Int32, String)"},
+	)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"['a', 'b,c']", "{'k': 1, 'l': 2}", "(1, 'x')"},
+		{"[]", "{}", "(0, '')"},
+	}, rows)
+}
+
+func TestJSONEachRowBlockStreamFmtReader_NestedEscapes(t *testing.T) {
+	sample, err := data.NewBlock(
+		[]string{"arr", "m"}, []column.CHColumnType{"Array(String)", "Map(String, String)"}, 0,
+	)
+	require.NoError(t, err)
+	readValues := func(input string) ([][]interface{}, error) {
+		r, err := BlockStreamFmtReaderFactory("JSONEachRow", bytes.NewReader([]byte(input)), nil)
+		require.NoError(t, err)
+		blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+		var values [][]interface{}
+		for b := range blockStream {
+			for i := 0; i < b.NumRows; i++ {
+				values = append(values, []interface{}{b.Columns[0].Data.GetValue(i), b.Columns[1].Data.GetValue(i)})
+			}
+		}
+		_, err = yield()
+		return values, err
+	}
+
+	values, err := readValues(`{"arr": ["x\"y", "\u00e9", "a\/b", "it's"], "m": {"k\"": "v\u00e9", "s": "\/"}}`)
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{{
+		[]interface{}{`x"y`, "é", "a/b", "it's"},
+		map[string]string{`k"`: "vé", "s": "/"},
+	}}, values)
+
+	// a string with all the quotes cannot be quoted within a nested value
+	_, err = readValues(`{"arr": ["'\"` + "`" + `"], "m": {}}`)
+	require.Error(t, err)
+}
+
+func TestJSONEachRowBlockStreamFmtReader_SkipUnknownFields(t *testing.T) {
+	names := []string{"a", "b"}
+	types := []column.CHColumnType{"Int32", "String"}
+	input := `{"b": "x", "unknown": {"c": [1, 2]}, "a": 1}` + "\n"
+
+	for _, format := range []string{"JSONEachRow", "JSON"} {
+		in := input
+		if format == "JSON" {
+			in = `{"data": [` + input + `]}`
+		}
+		rows, err := readRowStringsWithSettings(t, format, []byte(in), names, types, nil)
+		require.NoError(t, err)
+		require.Equal(t, [][]string{{"1", "x"}}, rows)
+
+		_, err = readRowStringsWithSettings(t, format, []byte(in), names, types,
+			map[string]interface{}{skipUnknownFieldsSetting: false},
+		)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `unknown column: "unknown"`)
+	}
+}
@@ -0,0 +1,308 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// nestedTimeFormat is the format of dates and times in arrays, maps and tuples
+const nestedTimeFormat = "2006-01-02 15:04:05.999999999"
+
+const hexDigits = "0123456789abcdef"
+
+// JSONEachRowBlockStreamFmtWriter writes a line per row, see JSONEachRowBlockStreamFmtReader.
+// Numbers are written as JSON numbers, except 64 bit and larger integers which are quoted as in ClickHouse,
+// and arrays, maps and tuples as JSON arrays and objects.
+type JSONEachRowBlockStreamFmtWriter struct {
+	zWriter   *bytepool.ZWriter
+	compact   bool
+	withNames bool
+	withTypes bool
+	// row is the current row, written to zWriter at once
+	row bytes.Buffer
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewJSONEachRowBlockStreamFmtWriter(w io.Writer, compact, withNames, withTypes bool) *JSONEachRowBlockStreamFmtWriter {
+	return &JSONEachRowBlockStreamFmtWriter{
+		zWriter:   bytepool.NewZWriterDefault(w),
+		compact:   compact,
+		withNames: withNames,
+		withTypes: withTypes,
+	}
+}
+
+func (j *JSONEachRowBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	j.done = make(chan struct{}, 1)
+	go j.blockStreamFmtWrite(blockStream)
+}
+
+func (j *JSONEachRowBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		j.done <- struct{}{}
+	}()
+	j.totalRowsWrite, j.exception = j.writeBlocks(blockStream)
+}
+
+func (j *JSONEachRowBlockStreamFmtWriter) Yield() (int, error) {
+	<-j.done
+	return j.totalRowsWrite, j.exception
+}
+
+// writeBlocks writes the rows of blocks in blockStream, values are taken from columns directly
+// to write arrays, maps and tuples as JSON
+func (j *JSONEachRowBlockStreamFmtWriter) writeBlocks(blockStream <-chan *data.Block) (int, error) {
+	defer func() {
+		for b := range blockStream {
+			b.Close()
+		}
+	}()
+
+	var (
+		totalRowsWrite int
+		headerWritten  bool
+	)
+	for b := range blockStream {
+		if !headerWritten {
+			if err := j.writeHeader(b.Columns); err != nil {
+				b.Close()
+				return totalRowsWrite, err
+			}
+			headerWritten = true
+		}
+		for i := 0; i < b.NumRows; i++ {
+			if err := j.writeRow(b.Columns, i); err != nil {
+				b.Close()
+				return totalRowsWrite, err
+			}
+			totalRowsWrite++
+		}
+		b.Close()
+	}
+	return totalRowsWrite, j.zWriter.Flush()
+}
+
+// writeHeader writes the names and types of cols for JSONCompactEachRow if enabled
+func (j *JSONEachRowBlockStreamFmtWriter) writeHeader(cols []*column.CHColumn) error {
+	if j.withNames {
+		if err := j.writeHeaderLine(cols, func(col *column.CHColumn) string { return col.Name }); err != nil {
+			return err
+		}
+	}
+	if j.withTypes {
+		if err := j.writeHeaderLine(cols, func(col *column.CHColumn) string { return string(col.Type) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (j *JSONEachRowBlockStreamFmtWriter) writeHeaderLine(cols []*column.CHColumn, text func(col *column.CHColumn) string) error {
+	j.row.Reset()
+	j.row.WriteByte('[')
+	for i, col := range cols {
+		if i > 0 {
+			j.row.WriteString(", ")
+		}
+		appendJSONString(&j.row, text(col))
+	}
+	j.row.WriteString("]\n")
+	_, err := j.zWriter.Write(j.row.Bytes())
+	return err
+}
+
+// writeRow writes row of cols as an object, or an array if compact, followed by a newline
+func (j *JSONEachRowBlockStreamFmtWriter) writeRow(cols []*column.CHColumn, row int) error {
+	j.row.Reset()
+	if j.compact {
+		j.row.WriteByte('[')
+	} else {
+		j.row.WriteByte('{')
+	}
+	for i, col := range cols {
+		if i > 0 {
+			j.row.WriteByte(',')
+		}
+		if !j.compact {
+			appendJSONString(&j.row, col.Name)
+			j.row.WriteByte(':')
+		}
+		appendJSONColumnValue(&j.row, col.Data, row)
+	}
+	if j.compact {
+		j.row.WriteString("]\n")
+	} else {
+		j.row.WriteString("}\n")
+	}
+	_, err := j.zWriter.Write(j.row.Bytes())
+	return err
+}
+
+// appendJSONColumnValue appends the value of data at row to buf
+func appendJSONColumnValue(buf *bytes.Buffer, data column.CHColumnData, row int) {
+	switch data := data.(type) {
+	case *column.NullableColumnData:
+		if data.GetValue(row) == nil {
+			buf.WriteString(column.NULLSmall)
+			return
+		}
+		appendJSONColumnValue(buf, data.GetInnerColumnData(), row)
+	case *column.Int8ColumnData, *column.Int16ColumnData, *column.Int32ColumnData,
+		*column.UInt8ColumnData, *column.UInt16ColumnData, *column.UInt32ColumnData, *column.DecimalColumnData:
+		buf.WriteString(data.GetString(row))
+	case *column.BoolColumnData:
+		buf.WriteString(strconv.FormatBool(data.GetString(row) != "0"))
+	case *column.Float32ColumnData, *column.Float64ColumnData,
+		*column.ArrayColumnData, *column.MapColumnData, *column.TupleColumnData, *column.LowCardinalityColumnData:
+		appendJSONValue(buf, data.GetValue(row))
+	default:
+		appendJSONString(buf, data.GetString(row))
+	}
+}
+
+// appendJSONValue appends v, a value of a column or of an element of a column, to buf
+func appendJSONValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString(column.NULLSmall)
+	case string:
+		appendJSONString(buf, v)
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int8, int16, int32, uint8, uint16, uint32:
+		fmt.Fprint(buf, v)
+	case int64, uint64, *big.Int:
+		appendJSONString(buf, fmt.Sprint(v))
+	case float32:
+		appendJSONFloat(buf, float64(v), 32)
+	case float64:
+		appendJSONFloat(buf, v, 64)
+	case decimal.Decimal:
+		buf.WriteString(v.String())
+	case time.Time:
+		appendJSONString(buf, v.Format(nestedTimeFormat))
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONValue(buf, elem)
+		}
+		buf.WriteByte(']')
+	case fmt.Stringer:
+		appendJSONString(buf, v.String())
+	default:
+		appendJSONReflectValue(buf, reflect.ValueOf(v))
+	}
+}
+
+// appendJSONReflectValue appends maps and slices of other than interface{} to buf
+func appendJSONReflectValue(buf *bytes.Buffer, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Map:
+		keys := make([]string, v.Len())
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for i := 0; iter.Next(); i++ {
+			keys[i] = fmt.Sprint(iter.Key().Interface())
+			values[keys[i]] = iter.Value().Interface()
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONString(buf, key)
+			buf.WriteByte(':')
+			appendJSONValue(buf, values[key])
+		}
+		buf.WriteByte('}')
+	case reflect.Slice, reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			appendJSONValue(buf, v.Index(i).Interface())
+		}
+		buf.WriteByte(']')
+	default:
+		appendJSONString(buf, fmt.Sprint(v.Interface()))
+	}
+}
+
+// appendJSONFloat appends f to buf, quoted if it is not a number in JSON such as nan and inf
+func appendJSONFloat(buf *bytes.Buffer, f float64, bitSize int) {
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		appendJSONString(buf, s)
+		return
+	}
+	buf.WriteString(s)
+}
+
+// appendJSONString appends s to buf as a JSON string, invalid UTF-8 is written as is
+func appendJSONString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b >= utf8.RuneSelf || (b >= 0x20 && b != '"' && b != '\\') {
+			i++
+			continue
+		}
+		buf.WriteString(s[start:i])
+		switch b {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hexDigits[b>>4])
+			buf.WriteByte(hexDigits[b&0xF])
+		}
+		i++
+		start = i
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
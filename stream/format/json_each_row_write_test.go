@@ -0,0 +1,87 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestJSONEachRowBlockStreamFmtWriter_BlockStreamFmtWrite(t *testing.T) {
+	names := []string{"i", "l", "s", "n", "arr", "m", "tup"}
+	types := []column.CHColumnType{
+		"Int32", "Int64", "String", "Nullable(Float64)", "Array(String)", "Map(String, Int32)", "Tuple(Int32, String)",
+	}
+	texts := [][]string{
+		{"1", "2"},
+		{"10", "20"},
+		{"a\"b\n", "é"},
+		{"1.5", "NULL"},
+		{"['x', 'y']", "[]"},
+		{"{'k': 1, 'l': 2}", "{}"},
+		{"(1, 'x')", "(2, 'y')"},
+	}
+	newBlock := func() *data.Block {
+		b, err := data.NewBlock(names, types, 2)
+		require.NoError(t, err)
+		for i, col := range b.Columns {
+			_, err := col.Data.ReadFromTexts(texts[i])
+			require.NoError(t, err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "JSONEachRow",
+			want: `{"i":1,"l":"10","s":"a\"b\n","n":1.5,"arr":["x","y"],"m":{"k":1,"l":2},"tup":[1,"x"]}` + "\n" +
+				`{"i":2,"l":"20","s":"é","n":null,"arr":[],"m":{},"tup":[2,"y"]}` + "\n",
+		},
+		{
+			format: "JSONCompactEachRowWithNamesAndTypes",
+			want: `["i", "l", "s", "n", "arr", "m", "tup"]` + "\n" +
+				`["Int32", "Int64", "String", "Nullable(Float64)", "Array(String)", "Map(String, Int32)", "Tuple(Int32, String)"]` + "\n" +
+				`[1,"10","a\"b\n",1.5,["x","y"],{"k":1,"l":2},[1,"x"]]` + "\n" +
+				`[2,"20","é",null,[],{},[2,"y"]]` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			blockStream := make(chan *data.Block, 1)
+			blockStream <- newBlock()
+			close(blockStream)
+
+			var buf bytes.Buffer
+			w, err := BlockStreamFmtWriterFactory(tt.format, &buf, nil)
+			require.NoError(t, err)
+			w.BlockStreamFmtWrite(blockStream)
+			n, err := w.Yield()
+			require.NoError(t, err)
+			require.Equal(t, 2, n)
+			require.Equal(t, tt.want, buf.String())
+
+			// the output is read back into the same values
+			rows, err := readRowStrings(t, tt.format, buf.String(), names, types)
+			require.NoError(t, err)
+			want := newBlock()
+			defer want.Close()
+			for i, row := range rows {
+				for j, col := range want.Columns {
+					require.Equal(t, col.Data.GetString(i), row[j])
+				}
+			}
+		})
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	var buf bytes.Buffer
+	appendJSONString(&buf, "a\"\\\t\x01é")
+	require.Equal(t, `"a\"\\\t\u0001é"`, buf.String())
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"strings"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
@@ -15,17 +14,29 @@ import (
 
 // TODO: add option to be able to read \uXXXX as an escaped character
 
+// JSONBlockStreamFmtReader reads the rows in the data field of JSON, which are objects read like those of
+// JSONEachRowBlockStreamFmtReader, matching keys to columns by name.
 type JSONBlockStreamFmtReader struct {
-	zReader      *bytepool.ZReader
-	colIdxByName map[string]int
+	zReader *bytepool.ZReader
+	// objects reads the rows from zReader
+	objects *JSONEachRowBlockStreamFmtReader
+	helper.RowRecorder
 }
 
 func NewJSONBlockStreamFmtReader(r io.Reader) *JSONBlockStreamFmtReader {
+	zReader := bytepool.NewZReaderDefault(&r)
 	return &JSONBlockStreamFmtReader{
-		zReader: bytepool.NewZReaderDefault(&r),
+		zReader:     zReader,
+		RowRecorder: helper.NewRowRecorder(zReader),
+		objects:     &JSONEachRowBlockStreamFmtReader{zReader: zReader, skipUnknown: true},
 	}
 }
 
+// SetColumnDefaults sets the defaults of columns without a key
+func (j *JSONBlockStreamFmtReader) SetColumnDefaults(exprs map[string]string) {
+	j.objects.SetColumnDefaults(exprs)
+}
+
 func (j *JSONBlockStreamFmtReader) ReadFirstRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) (err error) {
 	// return j.readRow(fb, cols)
 	if err := j.readRow(fb, cols); err != nil {
@@ -35,6 +46,8 @@ func (j *JSONBlockStreamFmtReader) ReadFirstRow(fb *bytepool.FrameBuffer, cols [
 }
 
 func (j *JSONBlockStreamFmtReader) ReadRowCont(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	// restarted by readRow after the separator, so that the input is recorded if the separator is not found
+	j.StartRow()
 	b, err := helper.ReadNextNonSpaceByte(j.zReader)
 	if err != nil {
 		return err
@@ -54,86 +67,42 @@ func (j *JSONBlockStreamFmtReader) ReadRowCont(fb *bytepool.FrameBuffer, cols []
 // readRow reads a row of data
 // Expected row format: {col:val, col:val, col:val}
 func (j *JSONBlockStreamFmtReader) readRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	j.StartRow()
 	if err := helper.AssertNextByteEqual(j.zReader, '{'); err != nil {
 		return err
 	}
+	return j.objects.readObject(fb, cols)
+}
 
-	// store the order of columns read
-	order := make([]int, len(cols))
-	sb := bytepool.NewStringsBuffer()
-
-	for i := range cols {
-		if i > 0 {
-			if err := helper.AssertNextByteEqual(j.zReader, ','); err != nil {
-				return err
-			}
-		}
-
-		columnName, err := j.readColumnName()
-		if err != nil {
-			return fmt.Errorf("error reading column name at idx %v: %s", i, err)
-		}
-
-		colIdx, ok := j.colIdxByName[columnName]
-		if !ok {
-			return fmt.Errorf("key error at idx %v, no key found for: %v", i, columnName)
-		}
-		order[i] = colIdx
-
-		if err := helper.AssertNextByteEqual(j.zReader, ':'); err != nil {
-			return fmt.Errorf("read colon error at idx %v: %s", i, err)
-		}
-
-		sb.NewElem()
-		if err := j.readElem(sb, cols[colIdx], i == len(cols)-1); err != nil {
-			return fmt.Errorf(errReadElem, cols[colIdx].Type, cols[colIdx].Name, i, err)
-		}
-	}
-
-	// get results (order based on user)
-	unordered_result := make([]string, len(cols))
-	sb.ExportTo(unordered_result)
-
-	// order based on sample data block from server
-	result := make([]string, len(cols))
-	for _, i := range order {
-		result[i] = unordered_result[order[i]]
-	}
-
-	for _, s := range result {
-		fb.NewElem()
-		fb.WriteString(s)
+// ResumeAt skips the meta field and the rows of data until offset, the separator after it is read by ReadRowCont
+func (j *JSONBlockStreamFmtReader) ResumeAt(offset int64, cols []*column.CHColumn) error {
+	if err := j.skipMeta(); err != nil {
+		return err
 	}
-
-	return helper.AssertNextByteEqual(j.zReader, '}')
+	return helper.DiscardUntilPosition(j.zReader, offset)
 }
 
-func (j *JSONBlockStreamFmtReader) readElem(w helper.Writer, col *column.CHColumn, last bool) error {
-	if err := helper.AssertNextByteEqual(j.zReader, '"'); err != nil {
-		if err == io.EOF {
-			return io.EOF
-		}
-
-		j.zReader.UnreadCurrentBuffer(1)
-		return j.readElemUnquoted(w, col, last)
+// SkipRow discards the rest of the row which failed to be read,
+// or until the next row if the failed row has not started
+func (j *JSONBlockStreamFmtReader) SkipRow() error {
+	if j.objects.inRow {
+		j.objects.inRow = false
+		_, err := helper.DiscardUntilUnnested(j.zReader, "}")
+		return err
 	}
 
-	_, err := helper.ReadStringUntilByte(w, j.zReader, '"')
-	return err
-}
-
-func (j *JSONBlockStreamFmtReader) readElemUnquoted(w helper.Writer, col *column.CHColumn, last bool) error {
-	if last {
-		return helper.ReadCHElemTillStop(w, j.zReader, col.Data, '}')
+	// leave the separator to be read by ReadRowCont
+	if _, err := helper.DiscardUntilUnnested(j.zReader, ",]"); err != nil {
+		return err
 	}
-	return helper.ReadCHElemTillStop(w, j.zReader, col.Data, ',')
+	j.zReader.UnreadCurrentBuffer(1)
+	return nil
 }
 
 func (j *JSONBlockStreamFmtReader) ReadFirstColumnTexts(fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn) (int, error) {
 	if err := j.skipMeta(); err != nil {
 		return 0, err
 	}
-	j.mapNameIdx(cols)
 	return helper.ReadFirstColumnTexts(fb, numRows, cols, j)
 }
 
@@ -146,17 +115,13 @@ func (j *JSONBlockStreamFmtReader) BlockStreamFmtRead(ctx context.Context, sampl
 	return helper.TableToBlockStream(ctx, sample, blockSize, j)
 }
 
-func (j *JSONBlockStreamFmtReader) readColumnName() (string, error) {
-	if err := helper.AssertNextByteEqual(j.zReader, '"'); err != nil {
-		return "", err
-	}
-
-	var sb strings.Builder
-	if _, err := helper.ReadStringUntilByte(&sb, j.zReader, '"'); err != nil {
-		return "", err
-	}
+func (j *JSONBlockStreamFmtReader) InputPosition() (int64, int) {
+	return j.zReader.Position(), j.zReader.Line()
+}
 
-	return sb.String(), nil
+func (j *JSONBlockStreamFmtReader) BlockStreamFmtReadWithOptions(ctx context.Context, sample *data.Block, opts helper.ReadOptions,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStreamWithOptions(ctx, sample, opts, j)
 }
 
 // readStringUntilQuoteCont assumes that the previous columnTextsPool has no quote desired
@@ -276,13 +241,6 @@ func (j *JSONBlockStreamFmtReader) skipMeta() error {
 	return nil
 }
 
-func (j *JSONBlockStreamFmtReader) mapNameIdx(cols []*column.CHColumn) {
-	j.colIdxByName = make(map[string]int)
-	for i, col := range cols {
-		j.colIdxByName[col.Name] = i
-	}
-}
-
 func (j *JSONBlockStreamFmtReader) checkOptionalSquareClosingBracket() error {
 	b, err := helper.ReadNextNonSpaceByte(j.zReader)
 	if err != nil {
@@ -251,3 +251,21 @@ func TestJSONBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
 		})
 	}
 }
+
+func TestJSONBlockStreamFmtReader_RowErrors(t *testing.T) {
+	input := `{"data": [
+{"a": 1, "b": "x"},
+{"a": "bad", "c": {"nested": [1, "}"]}, "b": "y"},
+{"a": 3, "c": {"nested": [1, "}"]}, "b": "z"},
+{"b": "d"},
+{"a": 5, "b": "w"}
+]}`
+	r := NewJSONBlockStreamFmtReader(bytes.NewReader([]byte(input)))
+
+	// unknown keys are skipped and missing keys are filled with defaults
+	values, rowErrs, progress := readSkippingRowErrors(t, r)
+	require.Equal(t, []interface{}{int32(1), int32(3), int32(0), int32(5)}, values)
+	require.Equal(t, []int{3}, rowErrorLines(rowErrs))
+	require.Equal(t, "a", rowErrs[0].Column)
+	require.Equal(t, 1, progress.RowsSkipped())
+}
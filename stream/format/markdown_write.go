@@ -0,0 +1,106 @@
+package format
+
+import (
+	"io"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+// markdownEscaper escapes text in cells of Markdown tables, which cannot contain pipes or line breaks
+var markdownEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"|", "\\|",
+	"\r\n", "<br>",
+	"\n", "<br>",
+	"\r", "<br>",
+)
+
+// MarkdownBlockStreamFmtWriter writes all rows as a single Markdown table, with a header of the column names
+type MarkdownBlockStreamFmtWriter struct {
+	zWriter *bytepool.ZWriter
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewMarkdownBlockStreamFmtWriter(w io.Writer) *MarkdownBlockStreamFmtWriter {
+	return &MarkdownBlockStreamFmtWriter{
+		zWriter: bytepool.NewZWriterDefault(w),
+	}
+}
+
+func (m *MarkdownBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	m.done = make(chan struct{}, 1)
+	go m.blockStreamFmtWrite(blockStream)
+}
+
+func (m *MarkdownBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		m.done <- struct{}{}
+	}()
+	m.totalRowsWrite, m.exception = helper.WriteTableFromBlockStream(blockStream, m)
+}
+
+func (m *MarkdownBlockStreamFmtWriter) Yield() (int, error) {
+	<-m.done
+	return m.totalRowsWrite, m.exception
+}
+
+func (m *MarkdownBlockStreamFmtWriter) WriteFirstFrame(frame [][]string, cols []*column.CHColumn) (int, error) {
+	if err := m.writeRow(getColNames(cols)); err != nil {
+		return 0, err
+	}
+	if err := m.zWriter.WriteString("|" + strings.Repeat(":-|", len(cols)) + "\n"); err != nil {
+		return 0, err
+	}
+	return helper.WriteFirstFrame(frame, cols, m)
+}
+
+func (m *MarkdownBlockStreamFmtWriter) WriteFrameCont(frame [][]string, cols []*column.CHColumn) (int, error) {
+	return helper.WriteFrameCont(frame, cols, m)
+}
+
+func (m *MarkdownBlockStreamFmtWriter) Flush() error {
+	return m.zWriter.Flush()
+}
+
+func (m *MarkdownBlockStreamFmtWriter) WriteFirstRow(record []string, cols []*column.CHColumn) error {
+	return m.writeRow(record)
+}
+
+func (m *MarkdownBlockStreamFmtWriter) WriteRowCont(record []string, cols []*column.CHColumn) error {
+	return m.writeRow(record)
+}
+
+// writeRow writes the cells of record between pipes and ends the row with a newline
+func (m *MarkdownBlockStreamFmtWriter) writeRow(record []string) error {
+	if err := m.zWriter.WriteByte('|'); err != nil {
+		return err
+	}
+	for _, field := range record {
+		if err := m.zWriter.WriteByte(' '); err != nil {
+			return err
+		}
+		if _, err := markdownEscaper.WriteString(m.zWriter, field); err != nil {
+			return err
+		}
+		if err := m.zWriter.WriteString(" |"); err != nil {
+			return err
+		}
+	}
+	return m.zWriter.WriteByte(newLine)
+}
@@ -0,0 +1,25 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestMarkdownBlockStreamFmtWriter(t *testing.T) {
+	names := []string{"id", "text"}
+	types := []column.CHColumnType{"Int32", "Nullable(String)"}
+	texts := [][]string{{"1", "2", "3"}, {"a|b", "line\nbreak", "NULL"}}
+
+	want := `| id | text |
+|:-|:-|
+| 1 | a\|b |
+| 2 | line<br>break |
+| 3 | ᴺᵁᴸᴸ |
+`
+	for _, rowsPerBlock := range []int{1, 3} {
+		require.Equal(t, want, string(writeBlocks(t, "Markdown", names, types, texts, rowsPerBlock, nil)))
+	}
+}
@@ -0,0 +1,159 @@
+package format
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"runtime/debug"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+// nativeReadBufferSize is large enough to look ahead a whole compressed block to detect compression
+const nativeReadBufferSize = 2 * ch_encoding.BlockMaxSize
+
+// NativeBlockStreamFmtReader reads blocks in the Native format, compressed or not, as written by
+// NativeBlockStreamFmtWriter. Columns of the table are matched to columns of the blocks by name.
+// Columns of the same type are used as is, others are converted, see column.ReadFromArrow.
+// Blocks are read as they were written, the block size is not used.
+type NativeBlockStreamFmtReader struct {
+	r io.Reader
+
+	totalRowsRead int
+	exception     error
+	done          chan struct{}
+}
+
+func NewNativeBlockStreamFmtReader(r io.Reader) *NativeBlockStreamFmtReader {
+	return &NativeBlockStreamFmtReader{r: r}
+}
+
+func (n *NativeBlockStreamFmtReader) BlockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	stream := make(chan *data.Block, 1)
+	n.done = make(chan struct{}, 1)
+	go n.blockStreamFmtRead(ctx, sample, stream)
+	return stream, n.yield
+}
+
+func (n *NativeBlockStreamFmtReader) blockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockStream chan<- *data.Block,
+) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		n.done <- struct{}{}
+	}()
+	defer close(blockStream)
+	n.totalRowsRead, n.exception = n.readBlocks(ctx, sample, blockStream)
+}
+
+func (n *NativeBlockStreamFmtReader) yield() (int, error) {
+	<-n.done
+	return n.totalRowsRead, n.exception
+}
+
+func (n *NativeBlockStreamFmtReader) readBlocks(
+	ctx context.Context, sample *data.Block, blockStream chan<- *data.Block,
+) (int, error) {
+	r := bufio.NewReaderSize(n.r, nativeReadBufferSize)
+	decoder := ch_encoding.NewDecoder(fullReader{r})
+	if isCompressedNative(r) {
+		decoder = ch_encoding.NewDecoderWithCompress(r)
+		decoder.SetCompress(true)
+	}
+
+	var totalRowsRead int
+	for {
+		nativeBlock, err := data.ReadNativeBlockFromDecoder(decoder, nil)
+		if err == io.EOF {
+			return totalRowsRead, nil
+		}
+		if err != nil {
+			return totalRowsRead, err
+		}
+		b, err := matchNativeBlock(nativeBlock, sample)
+		if err != nil {
+			return totalRowsRead, err
+		}
+
+		select {
+		case blockStream <- b:
+			totalRowsRead += b.NumRows
+		case <-ctx.Done():
+			b.Close()
+			return totalRowsRead, ctx.Err()
+		}
+	}
+}
+
+// matchNativeBlock returns a block with the columns of sample from nativeBlock, which is closed
+func matchNativeBlock(nativeBlock *data.Block, sample *data.Block) (*data.Block, error) {
+	defer nativeBlock.Close()
+
+	b := sample.StructureCopy(nativeBlock.NumRows)
+	for _, col := range b.Columns {
+		nativeCol := findNativeColumn(nativeBlock, col.Name)
+		if nativeCol == nil {
+			b.Close()
+			return nil, errors.ErrorfWithCaller("column %v not found in Native input", col.Name)
+		}
+		if nativeCol.Type == col.Type {
+			// the data is moved to b, so that it is not closed with nativeBlock
+			_ = col.Close()
+			col.Data, nativeCol.Data = nativeCol.Data, col.GenerateColumn(0)
+			continue
+		}
+
+		arr := column.NewArrowArray(nativeCol.Data, nativeBlock.NumRows)
+		_, err := column.ReadFromArrow(col.Data, arr)
+		arr.Release()
+		if err != nil {
+			b.Close()
+			return nil, errors.ErrorfWithCaller("failed to convert column %v from %v to %v: %v", col.Name, nativeCol.Type, col.Type, err)
+		}
+	}
+	return b, nil
+}
+
+func findNativeColumn(b *data.Block, name string) *column.CHColumn {
+	for _, col := range b.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+// isCompressedNative reports whether r starts with a compressed block
+func isCompressedNative(r *bufio.Reader) bool {
+	header, err := r.Peek(ch_encoding.HeaderSize)
+	if err != nil {
+		return false
+	}
+	n, ok := ch_encoding.CompressedBlockLen(header)
+	if !ok {
+		return false
+	}
+	b, err := r.Peek(n)
+	return err == nil && ch_encoding.IsCompressedBlock(b)
+}
+
+// fullReader reads until buf is full, the decoder expects reads of data of columns not to be short
+type fullReader struct {
+	r io.Reader
+}
+
+func (f fullReader) Read(buf []byte) (int, error) {
+	return io.ReadFull(f.r, buf)
+}
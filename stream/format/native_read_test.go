@@ -0,0 +1,73 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestNativeBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
+	input := writeBlocks(t, "Native",
+		[]string{"a", "b", "c"},
+		[]column.CHColumnType{"Int32", "Nullable(String)", "Array(Int32)"},
+		[][]string{{"1", "2", "3"}, {"x", "NULL", "z"}, {"[1, 2]", "[]", "[3]"}},
+		2, nil,
+	)
+
+	tests := []struct {
+		name    string
+		input   []byte
+		names   []string
+		types   []column.CHColumnType
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:  "Should match columns by name",
+			input: input,
+			names: []string{"c", "a"},
+			types: []column.CHColumnType{"Array(Int32)", "Int32"},
+			want:  [][]string{{"[1, 2]", "1"}, {"[]", "2"}, {"[3]", "3"}},
+		},
+		{
+			name:  "Should convert columns of other types",
+			input: input,
+			names: []string{"a", "b", "c"},
+			types: []column.CHColumnType{"Nullable(Int64)", "String", "Array(String)"},
+			want:  [][]string{{"1", "x", "['1', '2']"}, {"2", "", "[]"}, {"3", "z", "['3']"}},
+		},
+		{
+			name:    "Should fail if a column is not in the input",
+			input:   input,
+			names:   []string{"a", "d"},
+			types:   []column.CHColumnType{"Int32", "Int32"},
+			wantErr: true,
+		},
+		{
+			name:    "Should fail if the input ends within a block",
+			input:   input[:len(input)-1],
+			names:   []string{"a"},
+			types:   []column.CHColumnType{"Int32"},
+			wantErr: true,
+		},
+		{
+			name:  "Should read no rows of empty input",
+			input: []byte{},
+			names: []string{"a"},
+			types: []column.CHColumnType{"Int32"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readRowStrings(t, "Native", string(tt.input), tt.names, tt.types)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
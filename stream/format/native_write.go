@@ -0,0 +1,115 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+const nativeCompressionMethodSetting = "output_format_native_compression_method"
+
+// NativeBlockStreamFmtWriter writes blocks in the Native format, the column oriented binary encoding of blocks
+// used by ClickHouse, which keeps the exact types and values of columns.
+// Blocks are compressed with LZ4 or ZSTD in blocks of ch_encoding.BlockMaxSize if the compression method is set.
+type NativeBlockStreamFmtWriter struct {
+	w      io.Writer
+	method ch_encoding.CompressionMethodByte
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewNativeBlockStreamFmtWriter(w io.Writer, settings map[string]interface{}) (*NativeBlockStreamFmtWriter, error) {
+	method, err := resolveNativeCompressionMethod(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &NativeBlockStreamFmtWriter{
+		w:      w,
+		method: method,
+	}, nil
+}
+
+func (n *NativeBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	n.done = make(chan struct{}, 1)
+	go n.blockStreamFmtWrite(blockStream)
+}
+
+func (n *NativeBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		n.done <- struct{}{}
+	}()
+	n.totalRowsWrite, n.exception = n.writeBlocks(blockStream)
+}
+
+func (n *NativeBlockStreamFmtWriter) Yield() (int, error) {
+	<-n.done
+	return n.totalRowsWrite, n.exception
+}
+
+func (n *NativeBlockStreamFmtWriter) writeBlocks(blockStream <-chan *data.Block) (int, error) {
+	defer func() {
+		for b := range blockStream {
+			b.Close()
+		}
+	}()
+
+	w := bufio.NewWriter(n.w)
+	encoder := ch_encoding.NewEncoder(w)
+	if n.method != ch_encoding.NONE {
+		encoder = ch_encoding.NewEncoderWithCompressMethod(w, n.method)
+		encoder.SelectCompress(true)
+	}
+
+	var totalRowsWrite int
+	for b := range blockStream {
+		err := data.WriteNativeBlockToEncoder(encoder, b)
+		if err != nil {
+			b.Close()
+			return totalRowsWrite, err
+		}
+		totalRowsWrite += b.NumRows
+		b.Close()
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return totalRowsWrite, err
+	}
+	return totalRowsWrite, w.Flush()
+}
+
+// resolveNativeCompressionMethod returns the compression method of the setting, which is NONE if not set
+func resolveNativeCompressionMethod(settings map[string]interface{}) (ch_encoding.CompressionMethodByte, error) {
+	method, ok := settings[nativeCompressionMethodSetting]
+	if !ok {
+		return ch_encoding.NONE, nil
+	}
+	name, ok := method.(string)
+	if !ok {
+		return 0, errors.ErrorfWithCaller("expected type: string for %v, got: %T", nativeCompressionMethodSetting, method)
+	}
+
+	switch strings.ToUpper(name) {
+	case "", "NONE":
+		return ch_encoding.NONE, nil
+	case "LZ4":
+		return ch_encoding.LZ4, nil
+	case "ZSTD":
+		return ch_encoding.ZSTD, nil
+	}
+	return 0, errors.ErrorfWithCaller("unknown %v: %v, expected one of NONE, LZ4, ZSTD", nativeCompressionMethodSetting, name)
+}
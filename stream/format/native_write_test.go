@@ -0,0 +1,87 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestNativeBlockStreamFmtWriter_RoundTrip(t *testing.T) {
+	names := []string{"i", "s", "dec", "dec256", "dt64", "n", "lc", "arr", "m", "tup", "bm", "i128", "ip6"}
+	types := []column.CHColumnType{
+		"Int64", "String", "Decimal(18, 6)", "Decimal(76, 20)", "DateTime64(9, 'UTC')", "Nullable(String)",
+		"LowCardinality(Nullable(String))", "Array(Array(UInt8))", "Map(String, Decimal(9, 2))",
+		"Tuple(String, Nullable(Float64))", "BitMap64", "Int128", "IPv6",
+	}
+	texts := [][]string{
+		{"-9223372036854775808", "0", "9223372036854775807"},
+		{"a\tb", "", "NULL"},
+		{"123456789012.123456", "-0.000001", "0"},
+		{"12345678901234567890123456789012345678901234567890.12345678901234567890", "-1", "0"},
+		{"2022-01-02 03:04:05.123456789", "1970-01-01 00:00:00.000000001", "2100-12-31 23:59:59.999999999"},
+		{"NULL", "x", ""},
+		{"x", "NULL", "x"},
+		{"[[1], []]", "[]", "[[255, 0]]"},
+		{"{'a': 1.25}", "{}", "{'b': -0.01, 'c': 0}"},
+		{"('x', NULL)", "('', 1e-300)", "('z', -2)"},
+		{"[1]", "[]", "[4294967296]"},
+		{"-170141183460469231731687303715884105728", "0", "170141183460469231731687303715884105727"},
+		{"::1", "2001:db8::8a2e:370:7334", "::"},
+	}
+
+	for _, method := range []string{"", "LZ4", "zstd"} {
+		t.Run(method, func(t *testing.T) {
+			output := writeBlocks(t, "Native", names, types, texts, 2, map[string]interface{}{
+				nativeCompressionMethodSetting: method,
+			})
+			if method != "" {
+				wantMethod := map[string]byte{"LZ4": ch_encoding.LZ4, "zstd": ch_encoding.ZSTD}[method]
+				require.Equal(t, wantMethod, output[ch_encoding.ChecksumSize])
+			}
+
+			rows, err := readRowStrings(t, "Native", string(output), names, types)
+			require.NoError(t, err)
+			want, err := data.NewBlock(names, types, 3)
+			require.NoError(t, err)
+			_, _, err = want.ReadFromColumnTexts(texts)
+			require.NoError(t, err)
+			require.Len(t, rows, 3)
+			for i, row := range rows {
+				for j, col := range want.Columns {
+					require.Equal(t, col.Data.GetString(i), row[j], "row %v column %v", i, names[j])
+				}
+			}
+			want.Close()
+		})
+	}
+}
+
+func TestResolveNativeCompressionMethod(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     ch_encoding.CompressionMethodByte
+		wantErr  bool
+	}{
+		{name: "Should default to no compression", want: ch_encoding.NONE},
+		{name: "Should accept LZ4", settings: map[string]interface{}{nativeCompressionMethodSetting: "lz4"}, want: ch_encoding.LZ4},
+		{name: "Should accept ZSTD", settings: map[string]interface{}{nativeCompressionMethodSetting: "ZSTD"}, want: ch_encoding.ZSTD},
+		{name: "Should reject unknown methods", settings: map[string]interface{}{nativeCompressionMethodSetting: "gzip"}, wantErr: true},
+		{name: "Should reject other types", settings: map[string]interface{}{nativeCompressionMethodSetting: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveNativeCompressionMethod(tt.settings)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
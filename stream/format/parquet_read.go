@@ -0,0 +1,153 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/apache/arrow/go/v10/arrow"
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/file"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/errors"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+// parquetBatchSize is the number of rows decoded from the file at a time
+const parquetBatchSize = 64 * 1024
+
+// ParquetBlockStreamFmtReader reads a Parquet file, columns of the table are matched to columns of the file by name.
+// Parquet needs random access to the file, input which is not an io.ReaderAt and io.Seeker such as *os.File
+// is read into memory first.
+type ParquetBlockStreamFmtReader struct {
+	input        io.Reader
+	recordReader pqarrow.RecordReader
+	record       arrow.Record
+	// row is the index of the current row in record
+	row int
+	// fields are the indices of the columns of record for each column of the table
+	fields []int
+	// text is the buffer of the text of the current value
+	text []byte
+}
+
+func NewParquetBlockStreamFmtReader(input io.Reader) *ParquetBlockStreamFmtReader {
+	return &ParquetBlockStreamFmtReader{input: input}
+}
+
+func (p *ParquetBlockStreamFmtReader) BlockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStream(ctx, sample, blockSize, p)
+}
+
+func (p *ParquetBlockStreamFmtReader) BlockStreamFmtReadWithOptions(
+	ctx context.Context, sample *data.Block, opts helper.ReadOptions,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStreamWithOptions(ctx, sample, opts, p)
+}
+
+func (p *ParquetBlockStreamFmtReader) ReadFirstColumnTexts(
+	fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn,
+) (int, error) {
+	return helper.ReadFirstColumnTexts(fb, numRows, cols, p)
+}
+
+func (p *ParquetBlockStreamFmtReader) ReadColumnTextsCont(
+	fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn,
+) (int, error) {
+	return helper.ReadColumnTextsCont(fb, numRows, cols, p)
+}
+
+func (p *ParquetBlockStreamFmtReader) ReadFirstRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	if err := p.open(cols); err != nil {
+		return err
+	}
+	return helper.ReadRow(fb, cols, p)
+}
+
+func (p *ParquetBlockStreamFmtReader) ReadRowCont(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	return helper.ReadRow(fb, cols, p)
+}
+
+// open reads the schema of the file and finds the column of the file for each of cols
+func (p *ParquetBlockStreamFmtReader) open(cols []*column.CHColumn) error {
+	input, ok := p.input.(parquet.ReaderAtSeeker)
+	if !ok {
+		b, err := io.ReadAll(p.input)
+		if err != nil {
+			return err
+		}
+		input = bytes.NewReader(b)
+	}
+
+	parquetReader, err := file.NewParquetReader(input)
+	if err != nil {
+		return err
+	}
+	fileReader, err := pqarrow.NewFileReader(
+		parquetReader, pqarrow.ArrowReadProperties{BatchSize: parquetBatchSize}, memory.DefaultAllocator,
+	)
+	if err != nil {
+		return err
+	}
+	schema, err := fileReader.Schema()
+	if err != nil {
+		return err
+	}
+
+	p.fields = make([]int, len(cols))
+	for i, col := range cols {
+		indices := schema.FieldIndices(col.Name)
+		if len(indices) == 0 {
+			return errors.ErrorfWithCaller("column %v not found in Parquet file", col.Name)
+		}
+		p.fields[i] = indices[0]
+	}
+
+	p.recordReader, err = fileReader.GetRecordReader(context.Background(), nil, nil)
+	return err
+}
+
+func (p *ParquetBlockStreamFmtReader) ReadElem(fb *bytepool.FrameBuffer, cols []*column.CHColumn, idx int) error {
+	if idx == 0 {
+		if err := p.nextRow(); err != nil {
+			return err
+		}
+	}
+	var err error
+	p.text, err = column.AppendArrowText(p.text[:0], cols[idx].Data, p.record.Column(p.fields[idx]), p.row)
+	if err != nil {
+		return err
+	}
+	_, err = fb.Write(p.text)
+	return err
+}
+
+// nextRow moves to the next row, reading the next record if all rows of the current one are read
+func (p *ParquetBlockStreamFmtReader) nextRow() error {
+	p.row++
+	for p.record == nil || p.row >= int(p.record.NumRows()) {
+		if p.recordReader == nil { // already read until the end
+			return io.EOF
+		}
+		record, err := p.recordReader.Read()
+		if record == nil && err == nil {
+			err = io.EOF
+		}
+		if err != nil {
+			p.record = nil
+			p.recordReader.Release()
+			p.recordReader = nil
+			return err
+		}
+		p.record = record
+		p.row = 0
+	}
+	return nil
+}
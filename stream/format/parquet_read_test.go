@@ -0,0 +1,87 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestParquetBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
+	input := writeParquet(t,
+		[]string{"a", "b", "c"},
+		[]column.CHColumnType{"Int32", "Nullable(String)", "Nullable(Array(Int32))"},
+		[][]string{{"1", "2"}, {"x", "NULL"}, {"[1, 2]", "NULL"}},
+		2, nil,
+	)
+
+	tests := []struct {
+		name    string
+		names   []string
+		types   []column.CHColumnType
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:  "Should match columns by name",
+			names: []string{"c", "a"},
+			types: []column.CHColumnType{"Array(Int64)", "Int64"},
+			want:  [][]string{{"[1, 2]", "1"}, {"[]", "2"}},
+		},
+		{
+			name:  "Should read nulls as default of columns which are not nullable",
+			names: []string{"b"},
+			types: []column.CHColumnType{"String"},
+			want:  [][]string{{"x"}, {""}},
+		},
+		{
+			name:    "Should fail if a column is not in the file",
+			names:   []string{"a", "d"},
+			types:   []column.CHColumnType{"Int32", "Int32"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readRowStrings(t, "PARQUET", string(input), tt.names, tt.types)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestParquetBlockStreamFmtReader_NotSeekable(t *testing.T) {
+	input := writeParquet(t, []string{"a"}, []column.CHColumnType{"String"}, [][]string{{"x", "y", "z"}}, 3, nil)
+
+	// a MultiReader is not an io.ReaderAt so the input is read into memory
+	r := NewParquetBlockStreamFmtReader(io.MultiReader(bytes.NewReader(input)))
+	sample, err := data.NewBlock([]string{"a"}, []column.CHColumnType{"String"}, 0)
+	require.NoError(t, err)
+
+	blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+	var values []string
+	for b := range blockStream {
+		for i := 0; i < b.NumRows; i++ {
+			values = append(values, b.Columns[0].Data.GetString(i))
+		}
+		b.Close()
+	}
+	n, err := yield()
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, []string{"x", "y", "z"}, values)
+}
+
+func TestParquetBlockStreamFmtReader_InvalidInput(t *testing.T) {
+	_, err := readRowStrings(t, "Parquet", "a,b\n1,2\n", []string{"a"}, []column.CHColumnType{"Int32"})
+	require.Error(t, err)
+}
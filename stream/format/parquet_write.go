@@ -0,0 +1,156 @@
+package format
+
+import (
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/compress"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+const (
+	parquetRowGroupSizeSetting = "output_format_parquet_row_group_size"
+	// defaultParquetRowGroupSize is the default number of rows in a row group, same as ClickHouse
+	defaultParquetRowGroupSize = 1000000
+)
+
+// ParquetBlockStreamFmtWriter writes blocks as a Parquet file with snappy compression.
+// Rows are buffered in memory until a row group is complete, LowCardinality columns are dictionary encoded.
+// The Parquet schema is taken from the first block, see data.ArrowSchema for the mapping of types.
+type ParquetBlockStreamFmtWriter struct {
+	w            io.Writer
+	rowGroupSize int64
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewParquetBlockStreamFmtWriter(w io.Writer, settings map[string]interface{}) (*ParquetBlockStreamFmtWriter, error) {
+	rowGroupSize, err := resolveParquetRowGroupSize(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &ParquetBlockStreamFmtWriter{
+		w:            w,
+		rowGroupSize: rowGroupSize,
+	}, nil
+}
+
+func (p *ParquetBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	p.done = make(chan struct{}, 1)
+	go p.blockStreamFmtWrite(blockStream)
+}
+
+func (p *ParquetBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		p.done <- struct{}{}
+	}()
+	p.totalRowsWrite, p.exception = p.writeBlocks(blockStream)
+}
+
+func (p *ParquetBlockStreamFmtWriter) Yield() (int, error) {
+	<-p.done
+	return p.totalRowsWrite, p.exception
+}
+
+// writeBlocks writes each block as a record of the row group being buffered.
+// Nothing is written if blockStream has no blocks as there is no schema.
+func (p *ParquetBlockStreamFmtWriter) writeBlocks(blockStream <-chan *data.Block) (int, error) {
+	defer func() {
+		for b := range blockStream {
+			b.Close()
+		}
+	}()
+
+	var (
+		totalRowsWrite int
+		fileWriter     *pqarrow.FileWriter
+	)
+	for b := range blockStream {
+		if fileWriter == nil {
+			schema := data.ArrowSchema(b.Columns)
+			// w is hidden behind a plain io.Writer as closing the file writer would close w too
+			w := struct{ io.Writer }{p.w}
+			var err error
+			fileWriter, err = pqarrow.NewFileWriter(schema, w, p.writerProperties(b.Columns), pqarrow.DefaultWriterProps())
+			if err != nil {
+				b.Close()
+				return totalRowsWrite, err
+			}
+		}
+
+		if b.NumRows > 0 {
+			record := data.BlockToArrowRecord(b)
+			err := fileWriter.WriteBuffered(record)
+			record.Release()
+			if err != nil {
+				b.Close()
+				return totalRowsWrite, err
+			}
+		}
+		totalRowsWrite += b.NumRows
+		b.Close()
+	}
+
+	if fileWriter == nil {
+		return totalRowsWrite, nil
+	}
+	return totalRowsWrite, fileWriter.Close()
+}
+
+func (p *ParquetBlockStreamFmtWriter) writerProperties(cols []*column.CHColumn) *parquet.WriterProperties {
+	props := []parquet.WriterProperty{
+		parquet.WithMaxRowGroupLength(p.rowGroupSize),
+		parquet.WithCompression(compress.Codecs.Snappy),
+		parquet.WithDictionaryDefault(false),
+	}
+	for _, col := range cols {
+		if _, ok := col.Data.(*column.LowCardinalityColumnData); ok {
+			props = append(props, parquet.WithDictionaryFor(col.Name, true))
+		}
+	}
+	return parquet.NewWriterProperties(props...)
+}
+
+func resolveParquetRowGroupSize(settings map[string]interface{}) (int64, error) {
+	size, ok := settings[parquetRowGroupSizeSetting]
+	if !ok {
+		return defaultParquetRowGroupSize, nil
+	}
+
+	var n int64
+	switch size := size.(type) {
+	case int:
+		n = int64(size)
+	case int64:
+		n = size
+	case uint64:
+		n = int64(size)
+	case string:
+		var err error
+		if n, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, errors.ErrorfWithCaller("invalid %v: %v", parquetRowGroupSizeSetting, err)
+		}
+	default:
+		return 0, errors.ErrorfWithCaller("expected type: int/int64/uint64/string for %v, got: %T", parquetRowGroupSizeSetting, size)
+	}
+	if n <= 0 {
+		return 0, errors.ErrorfWithCaller("%v must be positive, got: %v", parquetRowGroupSizeSetting, n)
+	}
+	return n, nil
+}
@@ -0,0 +1,168 @@
+package format
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/parquet/file"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// writeParquet writes blocks of rows with texts, rows per block, in Parquet
+func writeParquet(t *testing.T, names []string, types []column.CHColumnType, texts [][]string, rowsPerBlock int,
+	settings map[string]interface{},
+) []byte {
+	return writeBlocks(t, "Parquet", names, types, texts, rowsPerBlock, settings)
+}
+
+// writeBlocks writes blocks of rows with texts, rows per block, in format
+func writeBlocks(t *testing.T, format string, names []string, types []column.CHColumnType, texts [][]string,
+	rowsPerBlock int, settings map[string]interface{},
+) []byte {
+	blockStream := make(chan *data.Block, len(texts[0])/rowsPerBlock+1)
+	for start := 0; start < len(texts[0]); start += rowsPerBlock {
+		end := start + rowsPerBlock
+		if end > len(texts[0]) {
+			end = len(texts[0])
+		}
+		b, err := data.NewBlock(names, types, end-start)
+		require.NoError(t, err)
+		for i, col := range b.Columns {
+			_, err := col.Data.ReadFromTexts(texts[i][start:end])
+			require.NoError(t, err)
+		}
+		blockStream <- b
+	}
+	close(blockStream)
+
+	var buf bytes.Buffer
+	w, err := BlockStreamFmtWriterFactory(format, &buf, settings)
+	require.NoError(t, err)
+	w.BlockStreamFmtWrite(blockStream)
+	n, err := w.Yield()
+	require.NoError(t, err)
+	require.Equal(t, len(texts[0]), n)
+	return buf.Bytes()
+}
+
+func TestParquetBlockStreamFmtWriter_RoundTrip(t *testing.T) {
+	names := []string{
+		"i8", "u64", "f", "s", "fs", "d", "dt", "dt64", "dec", "dec256", "b", "n", "lc", "lcn",
+		"arr", "arrn", "m", "tup", "uuid", "ip", "e",
+	}
+	types := []column.CHColumnType{
+		"Int8", "UInt64", "Float64", "String", "FixedString(3)", "Date", "DateTime('UTC')", "DateTime64(3, 'UTC')",
+		"Decimal(10, 2)", "Decimal(76, 3)", "Bool", "Nullable(Int32)", "LowCardinality(String)",
+		"LowCardinality(Nullable(String))", "Array(String)", "Array(Nullable(Int64))", "Map(String, Float32)",
+		"Tuple(Int32, String, Array(Date))", "UUID", "IPv4", "Enum8('a' = 1, 'b' = 2)",
+	}
+	texts := [][]string{
+		{"-1", "2", "3"},
+		{"18446744073709551615", "0", "7"},
+		{"1.5", "-0.25", "1e+100"},
+		{"a'b,c", "", "é\n"},
+		{"abc", "x", ""},
+		{"2022-01-02", "1970-01-01", "2100-12-31"},
+		{"2022-01-02 03:04:05", "1970-01-01 00:00:00", "2038-01-19 03:14:07"},
+		{"2022-01-02 03:04:05.123", "1970-01-01 00:00:00.001", "2001-02-03 04:05:06.000"},
+		{"12.34", "-0.01", "0"},
+		{"123456789.123", "-1", "0"},
+		{"true", "false", "true"},
+		{"1", "NULL", "-3"},
+		{"x", "y", "x"},
+		{"x", "NULL", "x"},
+		{"['a', 'b']", "[]", "['c']"},
+		{"[1, NULL]", "[]", "[NULL]"},
+		{"{'k': 1.5, 'l': 2}", "{}", "{'m': -1}"},
+		{"(1, 'x', ['2022-01-02'])", "(2, 'y', [])", "(3, '', ['1970-01-01', '2000-02-29'])"},
+		{"00000000-0000-0000-0000-000000000000", "123e4567-e89b-12d3-a456-426614174000", "ffffffff-ffff-ffff-ffff-ffffffffffff"},
+		{"127.0.0.1", "0.0.0.0", "10.1.2.3"},
+		{"a", "b", "a"},
+	}
+
+	for _, rowsPerBlock := range []int{1, 2, 3} {
+		output := writeParquet(t, names, types, texts, rowsPerBlock, map[string]interface{}{
+			parquetRowGroupSizeSetting: 2,
+		})
+
+		parquetReader, err := file.NewParquetReader(bytes.NewReader(output))
+		require.NoError(t, err)
+		require.Equal(t, 2, parquetReader.NumRowGroups())
+		require.Equal(t, int64(3), parquetReader.NumRows())
+		rowGroup := parquetReader.MetaData().RowGroup(0)
+		for name, dictionary := range map[string]bool{"s": false, "lc": true, "lcn": true} {
+			chunk, err := rowGroup.ColumnChunk(rowGroup.Schema.ColumnIndexByName(name))
+			require.NoError(t, err)
+			require.Equal(t, dictionary, chunk.HasDictionaryPage(), name)
+		}
+
+		rows, err := readRowStrings(t, "Parquet", string(output), names, types)
+		require.NoError(t, err)
+		want, err := data.NewBlock(names, types, 3)
+		require.NoError(t, err)
+		for i, col := range want.Columns {
+			_, err := col.Data.ReadFromTexts(texts[i])
+			require.NoError(t, err)
+		}
+		require.Len(t, rows, 3)
+		for i, row := range rows {
+			for j, col := range want.Columns {
+				require.Equal(t, col.Data.GetString(i), row[j], "row %v column %v", i, names[j])
+			}
+		}
+		want.Close()
+	}
+}
+
+func TestParquetBlockStreamFmtWriter_NoRows(t *testing.T) {
+	names := []string{"a", "b"}
+	types := []column.CHColumnType{"Int32", "Nullable(String)"}
+
+	blockStream := make(chan *data.Block, 1)
+	b, err := data.NewBlock(names, types, 0)
+	require.NoError(t, err)
+	blockStream <- b
+	close(blockStream)
+
+	var buf bytes.Buffer
+	w, err := NewParquetBlockStreamFmtWriter(&buf, nil)
+	require.NoError(t, err)
+	w.BlockStreamFmtWrite(blockStream)
+	n, err := w.Yield()
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	parquetReader, err := file.NewParquetReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Zero(t, parquetReader.NumRows())
+	require.Equal(t, 2, parquetReader.MetaData().Schema.NumColumns())
+}
+
+func TestResolveParquetRowGroupSize(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		want     int64
+		wantErr  bool
+	}{
+		{name: "Should default to 1000000", want: defaultParquetRowGroupSize},
+		{name: "Should accept int", settings: map[string]interface{}{parquetRowGroupSizeSetting: 10}, want: 10},
+		{name: "Should accept string", settings: map[string]interface{}{parquetRowGroupSizeSetting: "20"}, want: 20},
+		{name: "Should reject zero", settings: map[string]interface{}{parquetRowGroupSizeSetting: 0}, wantErr: true},
+		{name: "Should reject other types", settings: map[string]interface{}{parquetRowGroupSizeSetting: 1.5}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveParquetRowGroupSize(tt.settings)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package format
+
+import (
+	"io"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+// PrettyCompactBlockStreamFmtWriter writes a box per block like PrettyBlockStreamFmtWriter,
+// with numbers and the names of their columns aligned to the right as in PrettyCompact of ClickHouse.
+type PrettyCompactBlockStreamFmtWriter struct {
+	zWriter       *bytepool.ZWriter
+	maxColumnLens []int
+	// rightAligned tells which columns are numbers
+	rightAligned []bool
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewPrettyCompactBlockStreamFmtWriter(w io.Writer) *PrettyCompactBlockStreamFmtWriter {
+	return &PrettyCompactBlockStreamFmtWriter{
+		zWriter: bytepool.NewZWriterDefault(w),
+	}
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		p.totalRowsWrite, p.exception = helper.WriteTableFromBlockStream(blockStream, p)
+	}()
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) Yield() (int, error) {
+	for range p.done {
+	}
+	return p.totalRowsWrite, p.exception
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) WriteFirstFrame(frame [][]string, cols []*column.CHColumn) (int, error) {
+	p.rightAligned = make([]bool, len(cols))
+	for i, col := range cols {
+		p.rightAligned[i] = isNumberColumn(col.Data)
+	}
+	return p.WriteFrameCont(frame, cols)
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) WriteFrameCont(frame [][]string, cols []*column.CHColumn) (int, error) {
+	p.maxColumnLens = countMaxLenForEachCol(cols, frame, p.maxColumnLens)
+	if err := p.writeHeader(cols); err != nil {
+		return 0, err
+	}
+	n, err := helper.WriteFrameCont(frame, cols, p)
+	if err != nil {
+		return n, err
+	}
+	return n, writeBlockFooter(p.zWriter, p.maxColumnLens)
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) Flush() error {
+	return p.zWriter.Flush()
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) WriteFirstRow(record []string, cols []*column.CHColumn) error {
+	return p.WriteRowCont(record, cols)
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) WriteRowCont(record []string, cols []*column.CHColumn) error {
+	for i, field := range record {
+		if err := p.zWriter.WriteString(vertBar); err != nil {
+			return err
+		}
+		if err := p.zWriter.WriteByte(' '); err != nil {
+			return err
+		}
+		if err := p.writeAligned(field, i, " "); err != nil {
+			return err
+		}
+		if err := p.zWriter.WriteByte(' '); err != nil {
+			return err
+		}
+	}
+	return p.zWriter.WriteString(vertBarWithNewLine)
+}
+
+func (p *PrettyCompactBlockStreamFmtWriter) writeHeader(cols []*column.CHColumn) error {
+	if len(cols) == 0 {
+		return nil
+	}
+	if err := p.zWriter.WriteString(topLeftCorner); err != nil {
+		return err
+	}
+	for i, col := range cols {
+		if i > 0 {
+			if err := p.zWriter.WriteString(topSeparator); err != nil {
+				return err
+			}
+		}
+		if err := p.writeAligned(col.Name, i, dash); err != nil {
+			return err
+		}
+	}
+	return p.zWriter.WriteString(topRightCornerWithNewLine)
+}
+
+// writeAligned writes s in the width of column i, padded with excess before s if the column is right aligned
+func (p *PrettyCompactBlockStreamFmtWriter) writeAligned(s string, i int, excess string) error {
+	if !p.rightAligned[i] {
+		return writePrettyWithOffset(p.zWriter, s, p.maxColumnLens[i], excess)
+	}
+	for j := spaceCount(s); j < p.maxColumnLens[i]; j++ {
+		if err := p.zWriter.WriteString(excess); err != nil {
+			return err
+		}
+	}
+	return p.zWriter.WriteString(s)
+}
+
+// isNumberColumn tells if data holds integers, floats or decimals, which may be nullable
+func isNumberColumn(data column.CHColumnData) bool {
+	switch data := data.(type) {
+	case *column.Int8ColumnData, *column.Int16ColumnData, *column.Int32ColumnData, *column.Int64ColumnData,
+		*column.UInt8ColumnData, *column.UInt16ColumnData, *column.UInt32ColumnData, *column.UInt64ColumnData,
+		*column.Float32ColumnData, *column.Float64ColumnData, *column.DecimalColumnData, *column.BigIntColumnData:
+		return true
+	case *column.NullableColumnData:
+		return isNumberColumn(data.GetInnerColumnData())
+	}
+	return false
+}
@@ -0,0 +1,24 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestPrettyCompactBlockStreamFmtWriter(t *testing.T) {
+	names := []string{"name", "n", "price"}
+	types := []column.CHColumnType{"String", "Nullable(Int64)", "Decimal(10, 2)"}
+	texts := [][]string{{"hello", "你好", "x"}, {"1", "NULL", "123456"}, {"1.5", "-10.25", "0"}}
+
+	require.Equal(t, `┌─name──┬────n─┬──price─┐
+│ hello │    1 │   1.50 │
+│ 你好  │ ᴺᵁᴸᴸ │ -10.25 │
+└───────┴──────┴────────┘
+┌─name─┬──────n─┬─price─┐
+│ x    │ 123456 │  0.00 │
+└──────┴────────┴───────┘
+`, string(writeBlocks(t, "PrettyCompact", names, types, texts, 2, nil)))
+}
@@ -0,0 +1,222 @@
+package format
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ReaderConstructor returns the reader of a format from r, which is already decompressed, with the query settings
+// of the insert or query
+type ReaderConstructor func(r io.Reader, settings map[string]interface{}) (BlockStreamFmtReader, error)
+
+// WriterConstructor returns the writer of a format to w, with the settings of the export
+type WriterConstructor func(w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error)
+
+var registry = struct {
+	sync.RWMutex
+	readers map[string]ReaderConstructor
+	writers map[string]WriterConstructor
+}{
+	readers: make(map[string]ReaderConstructor),
+	writers: make(map[string]WriterConstructor),
+}
+
+// RegisterReader makes the reader of ctor available by name, case insensitive, to BlockStreamFmtReaderFactory,
+// and so to inserts and external tables. A reader registered with the name of a built-in format replaces it.
+// RegisterReader panics if name is empty or ctor is nil.
+func RegisterReader(name string, ctor ReaderConstructor) {
+	if name == "" || ctor == nil {
+		panic("format: RegisterReader with empty name or nil constructor")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.readers[strings.ToUpper(name)] = ctor
+}
+
+// RegisterWriter makes the writer of ctor available by name, case insensitive, to BlockStreamFmtWriterFactory,
+// and so to exports of query results. A writer registered with the name of a built-in format replaces it.
+// RegisterWriter panics if name is empty or ctor is nil.
+func RegisterWriter(name string, ctor WriterConstructor) {
+	if name == "" || ctor == nil {
+		panic("format: RegisterWriter with empty name or nil constructor")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	registry.writers[strings.ToUpper(name)] = ctor
+}
+
+// ReaderFormats returns the sorted upper case names of the formats which can be read, including aliases
+func ReaderFormats() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.readers)+len(FormatAliases))
+	for name := range registry.readers {
+		names = append(names, name)
+	}
+	for alias, t := range FormatAliases {
+		if _, ok := registry.readers[Formats[t]]; ok {
+			names = append(names, alias)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupReader(name string) (ReaderConstructor, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	ctor, ok := registry.readers[name]
+	return ctor, ok
+}
+
+func lookupWriter(name string) (WriterConstructor, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	ctor, ok := registry.writers[name]
+	return ctor, ok
+}
+
+func init() {
+	registerBuiltinReaders()
+	registerBuiltinWriters()
+}
+
+func registerBuiltinReaders() {
+	RegisterReader(Formats[CSVWITHNAMES], func(r io.Reader, settings map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewCSVBlockStreamFmtReader(r, true, settings)
+	})
+	RegisterReader(Formats[CSV], func(r io.Reader, settings map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewCSVBlockStreamFmtReader(r, false, settings)
+	})
+	RegisterReader(Formats[VALUES], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewValuesBlockStreamReader(r), nil
+	})
+	RegisterReader(Formats[JSON], func(r io.Reader, settings map[string]interface{}) (BlockStreamFmtReader, error) {
+		reader := NewJSONBlockStreamFmtReader(r)
+		if err := resolveBoolSetting(settings, skipUnknownFieldsSetting, &reader.objects.skipUnknown); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	})
+	RegisterReader(Formats[TABSEPARATED], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewTSVBlockStreamFmtReader(r, false, false, false), nil
+	})
+	RegisterReader(Formats[TABSEPARATEDWITHNAMES], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewTSVBlockStreamFmtReader(r, true, false, false), nil
+	})
+	RegisterReader(Formats[TABSEPARATEDWITHNAMESANDTYPES], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewTSVBlockStreamFmtReader(r, true, true, false), nil
+	})
+	RegisterReader(Formats[TABSEPARATEDRAW], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewTSVBlockStreamFmtReader(r, false, false, true), nil
+	})
+	RegisterReader(Formats[JSONEACHROW], func(r io.Reader, settings map[string]interface{}) (BlockStreamFmtReader, error) {
+		reader := NewJSONEachRowBlockStreamFmtReader(r, false, false, false)
+		if err := resolveBoolSetting(settings, skipUnknownFieldsSetting, &reader.skipUnknown); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	})
+	RegisterReader(Formats[JSONCOMPACTEACHROW], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewJSONEachRowBlockStreamFmtReader(r, true, false, false), nil
+	})
+	RegisterReader(Formats[JSONCOMPACTEACHROWWITHNAMES], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewJSONEachRowBlockStreamFmtReader(r, true, true, false), nil
+	})
+	RegisterReader(Formats[JSONCOMPACTEACHROWWITHNAMESANDTYPES], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewJSONEachRowBlockStreamFmtReader(r, true, true, true), nil
+	})
+	RegisterReader(Formats[PARQUET], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewParquetBlockStreamFmtReader(r), nil
+	})
+	RegisterReader(Formats[ARROWSTREAM], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewArrowStreamBlockStreamFmtReader(r), nil
+	})
+	RegisterReader(Formats[NATIVE], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewNativeBlockStreamFmtReader(r), nil
+	})
+	RegisterReader(Formats[ROWBINARY], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewRowBinaryBlockStreamFmtReader(r, false, false), nil
+	})
+	RegisterReader(Formats[ROWBINARYWITHNAMES], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewRowBinaryBlockStreamFmtReader(r, true, false), nil
+	})
+	RegisterReader(Formats[ROWBINARYWITHNAMESANDTYPES], func(r io.Reader, _ map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewRowBinaryBlockStreamFmtReader(r, true, true), nil
+	})
+}
+
+func registerBuiltinWriters() {
+	RegisterWriter(Formats[PRETTY], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewPrettyBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[PRETTYCOMPACT], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewPrettyCompactBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[VERTICAL], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewVerticalBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[MARKDOWN], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewMarkdownBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[TOML], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewTOMLBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[CSVWITHNAMES], func(w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewCSVBlockStreamFmtWriter(w, true, settings)
+	})
+	RegisterWriter(Formats[CSV], func(w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewCSVBlockStreamFmtWriter(w, false, settings)
+	})
+	RegisterWriter(Formats[VALUES], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewValuesBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[JSON], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewJSONBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[TABSEPARATED], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewTSVBlockStreamFmtWriter(w, false, false, false), nil
+	})
+	RegisterWriter(Formats[TABSEPARATEDWITHNAMES], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewTSVBlockStreamFmtWriter(w, true, false, false), nil
+	})
+	RegisterWriter(Formats[TABSEPARATEDWITHNAMESANDTYPES], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewTSVBlockStreamFmtWriter(w, true, true, false), nil
+	})
+	RegisterWriter(Formats[TABSEPARATEDRAW], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewTSVBlockStreamFmtWriter(w, false, false, true), nil
+	})
+	RegisterWriter(Formats[JSONEACHROW], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewJSONEachRowBlockStreamFmtWriter(w, false, false, false), nil
+	})
+	RegisterWriter(Formats[JSONCOMPACTEACHROW], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewJSONEachRowBlockStreamFmtWriter(w, true, false, false), nil
+	})
+	RegisterWriter(Formats[JSONCOMPACTEACHROWWITHNAMES], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewJSONEachRowBlockStreamFmtWriter(w, true, true, false), nil
+	})
+	RegisterWriter(Formats[JSONCOMPACTEACHROWWITHNAMESANDTYPES], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewJSONEachRowBlockStreamFmtWriter(w, true, true, true), nil
+	})
+	RegisterWriter(Formats[PARQUET], func(w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewParquetBlockStreamFmtWriter(w, settings)
+	})
+	RegisterWriter(Formats[ARROWSTREAM], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewArrowStreamBlockStreamFmtWriter(w), nil
+	})
+	RegisterWriter(Formats[NATIVE], func(w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewNativeBlockStreamFmtWriter(w, settings)
+	})
+	RegisterWriter(Formats[ROWBINARY], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewRowBinaryBlockStreamFmtWriter(w, false, false), nil
+	})
+	RegisterWriter(Formats[ROWBINARYWITHNAMES], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewRowBinaryBlockStreamFmtWriter(w, true, false), nil
+	})
+	RegisterWriter(Formats[ROWBINARYWITHNAMESANDTYPES], func(w io.Writer, _ map[string]interface{}) (BlockStreamFmtWriter, error) {
+		return NewRowBinaryBlockStreamFmtWriter(w, true, true), nil
+	})
+}
@@ -0,0 +1,66 @@
+package format
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// upperCSVWriter is a custom format which writes CSV in upper case
+type upperCSVWriter struct {
+	BlockStreamFmtWriter
+}
+
+func TestRegisterReaderWriter(t *testing.T) {
+	RegisterReader("PipeSeparated", func(r io.Reader, settings map[string]interface{}) (BlockStreamFmtReader, error) {
+		return NewCSVBlockStreamFmtReader(r, false, map[string]interface{}{csvDelimiterSetting: "|"})
+	})
+	RegisterWriter("UpperCSV", func(w io.Writer, settings map[string]interface{}) (BlockStreamFmtWriter, error) {
+		csvWriter, err := NewCSVBlockStreamFmtWriter(&upperWriter{w: w}, false, settings)
+		return &upperCSVWriter{BlockStreamFmtWriter: csvWriter}, err
+	})
+
+	require.Contains(t, ReaderFormats(), "PIPESEPARATED")
+	require.Contains(t, ReaderFormats(), "NDJSON")
+	require.NotContains(t, ReaderFormats(), "UPPERCSV")
+
+	rows, err := readRowStrings(t, "pipeSeparated", "1|a\n2|b\n", []string{"a", "b"}, []column.CHColumnType{"Int32", "String"})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1", "a"}, {"2", "b"}}, rows)
+
+	var buf bytes.Buffer
+	w, err := BlockStreamFmtWriterFactory("uppercsv", &buf, nil)
+	require.NoError(t, err)
+	require.IsType(t, &upperCSVWriter{}, w)
+	block, err := data.NewBlock([]string{"a"}, []column.CHColumnType{"String"}, 1)
+	require.NoError(t, err)
+	_, err = block.Columns[0].Data.ReadFromTexts([]string{"abc"})
+	require.NoError(t, err)
+	blockStream := make(chan *data.Block, 1)
+	blockStream <- block
+	close(blockStream)
+	w.BlockStreamFmtWrite(blockStream)
+	_, err = w.Yield()
+	require.NoError(t, err)
+	require.Equal(t, `"ABC"`, buf.String())
+
+	_, err = BlockStreamFmtReaderFactory("UpperCSV", strings.NewReader(""), nil)
+	require.Error(t, err)
+
+	require.Panics(t, func() { RegisterReader("", nil) })
+	require.Panics(t, func() { RegisterWriter("NilWriter", nil) })
+}
+
+type upperWriter struct {
+	w io.Writer
+}
+
+func (u *upperWriter) Write(p []byte) (int, error) {
+	return u.w.Write(bytes.ToUpper(p))
+}
@@ -0,0 +1,195 @@
+package format
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/errors"
+)
+
+// RowBinaryBlockStreamFmtReader reads rows in RowBinary as written by RowBinaryBlockStreamFmtWriter.
+// With names, columns of the table are matched to columns of the header by name,
+// and with types, the types of the header are checked against the types of the table before any row is read.
+type RowBinaryBlockStreamFmtReader struct {
+	r         io.Reader
+	withNames bool
+	withTypes bool
+
+	totalRowsRead int
+	exception     error
+	done          chan struct{}
+}
+
+func NewRowBinaryBlockStreamFmtReader(r io.Reader, withNames, withTypes bool) *RowBinaryBlockStreamFmtReader {
+	return &RowBinaryBlockStreamFmtReader{
+		r:         r,
+		withNames: withNames,
+		withTypes: withTypes,
+	}
+}
+
+func (r *RowBinaryBlockStreamFmtReader) BlockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	stream := make(chan *data.Block, 1)
+	r.done = make(chan struct{}, 1)
+	go r.blockStreamFmtRead(ctx, sample, blockSize, stream)
+	return stream, r.yield
+}
+
+func (r *RowBinaryBlockStreamFmtReader) blockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int, blockStream chan<- *data.Block,
+) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		r.done <- struct{}{}
+	}()
+	defer close(blockStream)
+	r.totalRowsRead, r.exception = r.readBlocks(ctx, sample, blockSize, blockStream)
+}
+
+func (r *RowBinaryBlockStreamFmtReader) yield() (int, error) {
+	<-r.done
+	return r.totalRowsRead, r.exception
+}
+
+func (r *RowBinaryBlockStreamFmtReader) readBlocks(
+	ctx context.Context, sample *data.Block, blockSize int, blockStream chan<- *data.Block,
+) (int, error) {
+	if blockSize <= 0 {
+		blockSize = 1
+	}
+	decoder := ch_encoding.NewDecoder(fullReader{bufio.NewReader(r.r)})
+	cols, err := r.readHeader(decoder, sample)
+	if err != nil {
+		return 0, err
+	}
+	readValues := make([]column.RowBinaryValueReader, len(cols))
+	for i, col := range cols {
+		readValues[i] = column.NewRowBinaryValueReader(sample.Columns[col].GenerateColumn(1))
+	}
+
+	var totalRowsRead int
+	for {
+		columnValues := make([][]interface{}, sample.NumColumns)
+		for i := range columnValues {
+			columnValues[i] = make([]interface{}, 0, blockSize)
+		}
+
+		numRows, err := r.readRows(decoder, blockSize, cols, readValues, columnValues)
+		if err != nil {
+			return totalRowsRead, errors.ErrorfWithCaller("error reading row %v: %v", totalRowsRead+numRows, err)
+		}
+		if numRows == 0 {
+			return totalRowsRead, nil
+		}
+
+		b := sample.StructureCopy(numRows)
+		if _, _, err := b.ReadFromColumnValues(columnValues); err != nil {
+			b.Close()
+			return totalRowsRead, err
+		}
+		select {
+		case blockStream <- b:
+			totalRowsRead += numRows
+		case <-ctx.Done():
+			b.Close()
+			return totalRowsRead, ctx.Err()
+		}
+		if numRows < blockSize {
+			return totalRowsRead, nil
+		}
+	}
+}
+
+// readRows reads up to numRows rows into columnValues, cols are the columns of the table for each column of the input.
+// Fewer rows are read only at the end of the input.
+func (r *RowBinaryBlockStreamFmtReader) readRows(decoder *ch_encoding.Decoder, numRows int, cols []int,
+	readValues []column.RowBinaryValueReader, columnValues [][]interface{},
+) (int, error) {
+	for row := 0; row < numRows; row++ {
+		for i, readValue := range readValues {
+			value, err := readValue(decoder)
+			if err == io.EOF && i == 0 {
+				return row, nil
+			}
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				return row, err
+			}
+			columnValues[cols[i]] = append(columnValues[cols[i]], value)
+		}
+	}
+	return numRows, nil
+}
+
+// readHeader returns the index of the column of the table for each column of the input
+func (r *RowBinaryBlockStreamFmtReader) readHeader(decoder *ch_encoding.Decoder, sample *data.Block) ([]int, error) {
+	cols := make([]int, sample.NumColumns)
+	if !r.withNames {
+		for i := range cols {
+			cols[i] = i
+		}
+		return cols, nil
+	}
+
+	n, err := decoder.Uvarint()
+	if err != nil {
+		return nil, errors.ErrorfWithCaller("error reading RowBinary header: %v", err)
+	}
+	if int(n) != sample.NumColumns {
+		return nil, errors.ErrorfWithCaller("RowBinary header has %v columns, expected: %v", n, sample.NumColumns)
+	}
+	for i := range cols {
+		name, err := decoder.String()
+		if err != nil {
+			return nil, errors.ErrorfWithCaller("error reading RowBinary header: %v", err)
+		}
+		if cols[i] = columnIndexByName(sample, name); cols[i] < 0 {
+			return nil, errors.ErrorfWithCaller("column %v of RowBinary header not found in table", name)
+		}
+	}
+	if !r.withTypes {
+		return cols, nil
+	}
+
+	var mismatches []string
+	for _, col := range cols {
+		colType, err := decoder.String()
+		if err != nil {
+			return nil, errors.ErrorfWithCaller("error reading RowBinary header: %v", err)
+		}
+		if want := sample.Columns[col].Type; column.CHColumnType(colType) != want {
+			mismatches = append(mismatches, sample.Columns[col].Name+" is "+colType+", expected: "+string(want))
+		}
+	}
+	if len(mismatches) > 0 {
+		return nil, errors.ErrorfWithCaller("types of RowBinary header do not match the table: %v", strings.Join(mismatches, "; "))
+	}
+	return cols, nil
+}
+
+// columnIndexByName returns the index of the column of b with name, -1 if there is none
+func columnIndexByName(b *data.Block, name string) int {
+	for i, col := range b.Columns {
+		if col.Name == name {
+			return i
+		}
+	}
+	return -1
+}
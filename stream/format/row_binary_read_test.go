@@ -0,0 +1,80 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestRowBinaryBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
+	names := []string{"a", "b"}
+	types := []column.CHColumnType{"Int32", "Nullable(String)"}
+	texts := [][]string{{"1", "2", "3"}, {"x", "NULL", "z"}}
+	withNames := writeBlocks(t, "RowBinaryWithNames", names, types, texts, 3, nil)
+	withTypes := writeBlocks(t, "RowBinaryWithNamesAndTypes", names, types, texts, 3, nil)
+
+	tests := []struct {
+		name    string
+		format  string
+		input   []byte
+		names   []string
+		types   []column.CHColumnType
+		want    [][]string
+		wantErr string
+	}{
+		{
+			name:   "Should match columns by name",
+			format: "RowBinaryWithNames",
+			input:  withNames,
+			names:  []string{"b", "a"},
+			types:  []column.CHColumnType{"Nullable(String)", "Int32"},
+			want:   [][]string{{"x", "1"}, {column.NULLDisplay, "2"}, {"z", "3"}},
+		},
+		{
+			name:    "Should fail if a column of the header is not in the table",
+			format:  "RowBinaryWithNames",
+			input:   withNames,
+			names:   []string{"a", "c"},
+			types:   []column.CHColumnType{"Int32", "String"},
+			wantErr: "column b of RowBinary header not found in table",
+		},
+		{
+			name:    "Should fail if the header has other columns than the table",
+			format:  "RowBinaryWithNames",
+			input:   withNames,
+			names:   []string{"a"},
+			types:   []column.CHColumnType{"Int32"},
+			wantErr: "RowBinary header has 2 columns, expected: 1",
+		},
+		{
+			name:    "Should report all types which do not match",
+			format:  "RowBinaryWithNamesAndTypes",
+			input:   withTypes,
+			names:   []string{"b", "a"},
+			types:   []column.CHColumnType{"String", "Int64"},
+			wantErr: "a is Int32, expected: Int64; b is Nullable(String), expected: String",
+		},
+		{
+			name:    "Should fail if the input ends within a row",
+			format:  "RowBinaryWithNamesAndTypes",
+			input:   withTypes[:len(withTypes)-1],
+			names:   names,
+			types:   types,
+			wantErr: "error reading row 2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readRowStrings(t, tt.format, string(tt.input), tt.names, tt.types)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
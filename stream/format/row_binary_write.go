@@ -0,0 +1,114 @@
+package format
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"runtime/debug"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/ch_encoding"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// RowBinaryBlockStreamFmtWriter writes blocks in RowBinary, values of each row one after another in the binary
+// encoding of their type. With names, the rows are preceded by the number of columns and their names,
+// and with types by their types too.
+type RowBinaryBlockStreamFmtWriter struct {
+	w         io.Writer
+	withNames bool
+	withTypes bool
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewRowBinaryBlockStreamFmtWriter(w io.Writer, withNames, withTypes bool) *RowBinaryBlockStreamFmtWriter {
+	return &RowBinaryBlockStreamFmtWriter{
+		w:         w,
+		withNames: withNames,
+		withTypes: withTypes,
+	}
+}
+
+func (r *RowBinaryBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	r.done = make(chan struct{}, 1)
+	go r.blockStreamFmtWrite(blockStream)
+}
+
+func (r *RowBinaryBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		r.done <- struct{}{}
+	}()
+	r.totalRowsWrite, r.exception = r.writeBlocks(blockStream)
+}
+
+func (r *RowBinaryBlockStreamFmtWriter) Yield() (int, error) {
+	<-r.done
+	return r.totalRowsWrite, r.exception
+}
+
+// writeBlocks writes the header before the rows of the first block.
+// Nothing is written if blockStream has no blocks as there are no columns.
+func (r *RowBinaryBlockStreamFmtWriter) writeBlocks(blockStream <-chan *data.Block) (int, error) {
+	defer func() {
+		for b := range blockStream {
+			b.Close()
+		}
+	}()
+
+	w := bufio.NewWriter(r.w)
+	encoder := ch_encoding.NewEncoder(w)
+	var (
+		totalRowsWrite int
+		headerWritten  bool
+	)
+	for b := range blockStream {
+		if !headerWritten && r.withNames {
+			if err := r.writeHeader(encoder, b.Columns); err != nil {
+				b.Close()
+				return totalRowsWrite, err
+			}
+			headerWritten = true
+		}
+		for row := 0; row < b.NumRows; row++ {
+			for _, col := range b.Columns {
+				if err := column.WriteRowBinary(encoder, col.Data, row); err != nil {
+					b.Close()
+					return totalRowsWrite, err
+				}
+			}
+			totalRowsWrite++
+		}
+		b.Close()
+	}
+	return totalRowsWrite, w.Flush()
+}
+
+func (r *RowBinaryBlockStreamFmtWriter) writeHeader(encoder *ch_encoding.Encoder, cols []*column.CHColumn) error {
+	if err := encoder.Uvarint(uint64(len(cols))); err != nil {
+		return err
+	}
+	for _, col := range cols {
+		if err := encoder.String(col.Name); err != nil {
+			return err
+		}
+	}
+	if !r.withTypes {
+		return nil
+	}
+	for _, col := range cols {
+		if err := encoder.String(string(col.Type)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestRowBinaryBlockStreamFmtWriter_Encoding(t *testing.T) {
+	names := []string{"a", "b", "c", "d"}
+	types := []column.CHColumnType{"Int32", "Nullable(String)", "Array(UInt8)", "LowCardinality(Nullable(String))"}
+	texts := [][]string{{"1", "-2"}, {"ab", "NULL"}, {"[7, 8]", "[]"}, {"x", "NULL"}}
+
+	tests := []struct {
+		format string
+		want   []byte
+	}{
+		{
+			format: "RowBinary",
+			want: []byte{
+				1, 0, 0, 0, 0, 2, 'a', 'b', 2, 7, 8, 0, 1, 'x',
+				0xfe, 0xff, 0xff, 0xff, 1, 0, 1,
+			},
+		},
+		{
+			format: "RowBinaryWithNames",
+			want: []byte{
+				4, 1, 'a', 1, 'b', 1, 'c', 1, 'd',
+				1, 0, 0, 0, 0, 2, 'a', 'b', 2, 7, 8, 0, 1, 'x',
+				0xfe, 0xff, 0xff, 0xff, 1, 0, 1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			require.Equal(t, tt.want, writeBlocks(t, tt.format, names, types, texts, 1, nil))
+		})
+	}
+}
+
+func TestRowBinaryBlockStreamFmtWriter_RoundTrip(t *testing.T) {
+	names := []string{
+		"i8", "u64", "f32", "s", "fs", "d", "d32", "dt", "dt64", "dec", "dec256", "b", "n", "lc", "lcn",
+		"arr", "m", "tup", "uuid", "ip4", "ip6", "e8", "e16", "i128", "bm",
+	}
+	types := []column.CHColumnType{
+		"Int8", "UInt64", "Float32", "String", "FixedString(3)", "Date", "Date32", "DateTime('UTC')",
+		"DateTime64(6, 'UTC')", "Decimal(10, 2)", "Decimal(76, 3)", "Bool", "Nullable(Int32)", "LowCardinality(String)",
+		"LowCardinality(Nullable(String))", "Array(Nullable(String))", "Map(String, Array(Int16))",
+		"Tuple(Int32, String, Array(Date))", "UUID", "IPv4", "IPv6", "Enum8('a' = 1, 'b' = 2)",
+		"Enum16('x' = -1000, 'y' = 1000)", "Int128", "BitMap64",
+	}
+	texts := [][]string{
+		{"-128", "0", "127"},
+		{"18446744073709551615", "0", "7"},
+		{"1.5", "-0.25", "3e+38"},
+		{"a\tb\n'c'", "", "NULL"},
+		{"abc", "x", ""},
+		{"2022-01-02", "1970-01-01", "2100-12-31"},
+		{"1900-01-01", "2022-01-02", "2299-12-31"},
+		{"2022-01-02 03:04:05", "1970-01-01 00:00:00", "2038-01-19 03:14:07"},
+		{"2022-01-02 03:04:05.123456", "1970-01-01 00:00:00.000001", "2001-02-03 04:05:06.000000"},
+		{"12.34", "-0.01", "0"},
+		{"123456789012345678901234567890.123", "-1", "0"},
+		{"true", "false", "true"},
+		{"1", "NULL", "-3"},
+		{"x", "y", "x"},
+		{"x", "NULL", "x"},
+		{"['a', NULL]", "[]", "[NULL]"},
+		{"{'k': [1, -2]}", "{}", "{'m': [3]}"},
+		{"(1, 'x', ['2022-01-02'])", "(2, '', [])", "(3, 'z', ['1970-01-01', '2000-02-29'])"},
+		{"123e4567-e89b-12d3-a456-426614174000", "00000000-0000-0000-0000-000000000000", "ffffffff-ffff-ffff-ffff-ffffffffffff"},
+		{"127.0.0.1", "0.0.0.0", "255.255.255.255"},
+		{"::1", "2001:db8::8a2e:370:7334", "::"},
+		{"a", "b", "a"},
+		{"x", "y", "x"},
+		{"-170141183460469231731687303715884105728", "0", "170141183460469231731687303715884105727"},
+		{"[1]", "[]", "[4294967296]"},
+	}
+
+	for _, format := range []string{"RowBinary", "RowBinaryWithNames", "RowBinaryWithNamesAndTypes"} {
+		t.Run(format, func(t *testing.T) {
+			output := writeBlocks(t, format, names, types, texts, 2, nil)
+
+			rows, err := readRowStrings(t, format, string(output), names, types)
+			require.NoError(t, err)
+			want, err := data.NewBlock(names, types, 3)
+			require.NoError(t, err)
+			_, _, err = want.ReadFromColumnTexts(texts)
+			require.NoError(t, err)
+			require.Len(t, rows, 3)
+			for i, row := range rows {
+				for j, col := range want.Columns {
+					require.Equal(t, col.Data.GetString(i), row[j], "row %v column %v", i, names[j])
+				}
+			}
+			want.Close()
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+// tomlRowsTable is the name of the array of tables holding the rows
+const tomlRowsTable = "[[data]]\n"
+
+// TOMLBlockStreamFmtWriter writes each row as a table of the array of tables data, with a key per column.
+// TOML has no null, NULL columns are left out of their row and NULL elements of arrays, maps and tuples are
+// written as "NULL". Integers which do not fit in 64 bits and decimals are quoted to keep their value,
+// dates are written as local dates and times as offset date-times.
+type TOMLBlockStreamFmtWriter struct {
+	zWriter *bytepool.ZWriter
+	// row is the current row, written to zWriter at once
+	row bytes.Buffer
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewTOMLBlockStreamFmtWriter(w io.Writer) *TOMLBlockStreamFmtWriter {
+	return &TOMLBlockStreamFmtWriter{
+		zWriter: bytepool.NewZWriterDefault(w),
+	}
+}
+
+func (t *TOMLBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	t.done = make(chan struct{}, 1)
+	go t.blockStreamFmtWrite(blockStream)
+}
+
+func (t *TOMLBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		t.done <- struct{}{}
+	}()
+	t.totalRowsWrite, t.exception = t.writeBlocks(blockStream)
+}
+
+func (t *TOMLBlockStreamFmtWriter) Yield() (int, error) {
+	<-t.done
+	return t.totalRowsWrite, t.exception
+}
+
+func (t *TOMLBlockStreamFmtWriter) writeBlocks(blockStream <-chan *data.Block) (int, error) {
+	defer func() {
+		for b := range blockStream {
+			b.Close()
+		}
+	}()
+
+	var totalRowsWrite int
+	for b := range blockStream {
+		for i := 0; i < b.NumRows; i++ {
+			if err := t.writeRow(b.Columns, i, totalRowsWrite == 0); err != nil {
+				b.Close()
+				return totalRowsWrite, err
+			}
+			totalRowsWrite++
+		}
+		b.Close()
+	}
+	return totalRowsWrite, t.zWriter.Flush()
+}
+
+// writeRow writes row of cols as a table of data, separated from the previous row by an empty line
+func (t *TOMLBlockStreamFmtWriter) writeRow(cols []*column.CHColumn, row int, first bool) error {
+	t.row.Reset()
+	if !first {
+		t.row.WriteByte(newLine)
+	}
+	t.row.WriteString(tomlRowsTable)
+	for _, col := range cols {
+		if col.Data.GetValue(row) == nil {
+			continue
+		}
+		appendTOMLKey(&t.row, col.Name)
+		t.row.WriteString(" = ")
+		appendTOMLColumnValue(&t.row, col.Data, row)
+		t.row.WriteByte(newLine)
+	}
+	_, err := t.zWriter.Write(t.row.Bytes())
+	return err
+}
+
+// appendTOMLColumnValue appends the value of data at row to buf, the value is not NULL
+func appendTOMLColumnValue(buf *bytes.Buffer, data column.CHColumnData, row int) {
+	switch data := data.(type) {
+	case *column.NullableColumnData:
+		appendTOMLColumnValue(buf, data.GetInnerColumnData(), row)
+	case *column.DateColumnData, *column.Date32ColumnData:
+		buf.WriteString(data.GetString(row))
+	case *column.BoolColumnData:
+		buf.WriteString(strconv.FormatBool(data.GetString(row) != "0"))
+	case *column.Int8ColumnData, *column.Int16ColumnData, *column.Int32ColumnData, *column.Int64ColumnData,
+		*column.UInt8ColumnData, *column.UInt16ColumnData, *column.UInt32ColumnData, *column.UInt64ColumnData,
+		*column.Float32ColumnData, *column.Float64ColumnData, *column.DecimalColumnData,
+		*column.DateTimeColumnData, *column.DateTime64ColumnData,
+		*column.ArrayColumnData, *column.MapColumnData, *column.TupleColumnData, *column.LowCardinalityColumnData:
+		appendTOMLValue(buf, data.GetValue(row))
+	default:
+		appendTOMLString(buf, data.GetString(row))
+	}
+}
+
+// appendTOMLValue appends v, a value of a column or of an element of a column, to buf
+func appendTOMLValue(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		appendTOMLString(buf, column.NULL)
+	case string:
+		appendTOMLString(buf, v)
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case int8, int16, int32, int64, uint8, uint16, uint32:
+		fmt.Fprint(buf, v)
+	case uint64:
+		if v > math.MaxInt64 { // integers of TOML are 64 bit signed
+			appendTOMLString(buf, strconv.FormatUint(v, 10))
+			return
+		}
+		buf.WriteString(strconv.FormatUint(v, 10))
+	case *big.Int:
+		appendTOMLString(buf, v.String())
+	case float32:
+		appendTOMLFloat(buf, float64(v), 32)
+	case float64:
+		appendTOMLFloat(buf, v, 64)
+	case decimal.Decimal:
+		appendTOMLString(buf, v.String())
+	case time.Time:
+		buf.WriteString(v.Format(time.RFC3339Nano))
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			appendTOMLValue(buf, elem)
+		}
+		buf.WriteByte(']')
+	case fmt.Stringer:
+		appendTOMLString(buf, v.String())
+	default:
+		appendTOMLReflectValue(buf, reflect.ValueOf(v))
+	}
+}
+
+// appendTOMLReflectValue appends maps as inline tables and slices of other than interface{} as arrays to buf
+func appendTOMLReflectValue(buf *bytes.Buffer, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Map:
+		keys := make([]string, v.Len())
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for i := 0; iter.Next(); i++ {
+			keys[i] = fmt.Sprint(iter.Key().Interface())
+			values[keys[i]] = iter.Value().Interface()
+		}
+		sort.Strings(keys)
+
+		if len(keys) == 0 {
+			buf.WriteString("{}")
+			return
+		}
+		buf.WriteString("{ ")
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			appendTOMLKey(buf, key)
+			buf.WriteString(" = ")
+			appendTOMLValue(buf, values[key])
+		}
+		buf.WriteString(" }")
+	case reflect.Slice, reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			appendTOMLValue(buf, v.Index(i).Interface())
+		}
+		buf.WriteByte(']')
+	default:
+		appendTOMLString(buf, fmt.Sprint(v.Interface()))
+	}
+}
+
+// appendTOMLFloat appends f to buf, with a fraction if it is whole so that it is read back as a float
+func appendTOMLFloat(buf *bytes.Buffer, f float64, bitSize int) {
+	switch {
+	case math.IsNaN(f):
+		buf.WriteString("nan")
+	case math.IsInf(f, 1):
+		buf.WriteString("inf")
+	case math.IsInf(f, -1):
+		buf.WriteString("-inf")
+	default:
+		s := strconv.FormatFloat(f, 'g', -1, bitSize)
+		buf.WriteString(s)
+		if !strings.ContainsAny(s, ".e") {
+			buf.WriteString(".0")
+		}
+	}
+}
+
+// appendTOMLKey appends key to buf, quoted unless it is a bare key
+func appendTOMLKey(buf *bytes.Buffer, key string) {
+	if !isTOMLBareKey(key) {
+		appendTOMLString(buf, key)
+		return
+	}
+	buf.WriteString(key)
+}
+
+func isTOMLBareKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		b := key[i]
+		if !(b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' || b == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// appendTOMLString appends s to buf as a TOML basic string, invalid UTF-8 is written as is
+func appendTOMLString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		b := s[i]
+		if b >= utf8.RuneSelf || (b >= 0x20 && b != '"' && b != '\\' && b != 0x7f) {
+			i++
+			continue
+		}
+		buf.WriteString(s[start:i])
+		switch b {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		default:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hexDigits[b>>4])
+			buf.WriteByte(hexDigits[b&0xF])
+		}
+		i++
+		start = i
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
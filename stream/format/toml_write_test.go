@@ -0,0 +1,65 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestTOMLBlockStreamFmtWriter(t *testing.T) {
+	names := []string{"i", "u64", "f", "s", "n", "b", "d", "dt", "dec", "arr", "m", "tup", "col name"}
+	types := []column.CHColumnType{
+		"Int32", "UInt64", "Float64", "String", "Nullable(Int8)", "Bool", "Date", "DateTime('UTC')", "Decimal(10, 2)",
+		"Array(Nullable(String))", "Map(String, UInt8)", "Tuple(Int8, String)", "UUID",
+	}
+	texts := [][]string{
+		{"1", "-2"},
+		{"7", "18446744073709551615"},
+		{"1", "-inf"},
+		{"a\"b\n", ""},
+		{"NULL", "3"},
+		{"true", "false"},
+		{"2022-01-02", "1970-01-01"},
+		{"2022-01-02 03:04:05", "1970-01-01 00:00:00"},
+		{"12.34", "-0.01"},
+		{"['x', NULL]", "[]"},
+		{"{'b': 1, 'a': 2}", "{}"},
+		{"(1, 'x')", "(2, '')"},
+		{"123e4567-e89b-12d3-a456-426614174000", "00000000-0000-0000-0000-000000000000"},
+	}
+
+	want := `[[data]]
+i = 1
+u64 = 7
+f = 1.0
+s = "a\"b\n"
+b = true
+d = 2022-01-02
+dt = 2022-01-02T03:04:05Z
+dec = "12.34"
+arr = ["x", "NULL"]
+m = { a = 2, b = 1 }
+tup = [1, "x"]
+"col name" = "123e4567-e89b-12d3-a456-426614174000"
+
+[[data]]
+i = -2
+u64 = "18446744073709551615"
+f = -inf
+s = ""
+n = 3
+b = false
+d = 1970-01-01
+dt = 1970-01-01T00:00:00Z
+dec = "-0.01"
+arr = []
+m = {}
+tup = [2, ""]
+"col name" = "00000000-0000-0000-0000-000000000000"
+`
+	for _, rowsPerBlock := range []int{1, 2} {
+		require.Equal(t, want, string(writeBlocks(t, "TOML", names, types, texts, rowsPerBlock, nil)))
+	}
+}
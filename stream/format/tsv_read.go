@@ -0,0 +1,258 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+const (
+	tab     = '\t'
+	newLine = '\n'
+)
+
+// tsvUnescape maps the byte after a backslash to the byte it escapes in TabSeparated
+var tsvUnescape = map[byte]byte{
+	'b':  '\b',
+	'f':  '\f',
+	'r':  '\r',
+	'n':  '\n',
+	't':  '\t',
+	'0':  0,
+	'a':  '\a',
+	'v':  '\v',
+	'\\': '\\',
+	'\'': '\'',
+	'"':  '"',
+}
+
+// TSVBlockStreamFmtReader reads the TabSeparated family of formats: one row per line with values separated
+// by tabs. Values are unescaped unless raw, and only an unescaped \N is NULL.
+type TSVBlockStreamFmtReader struct {
+	zReader *bytepool.ZReader
+	// headerLines are the lines before the data, names and types of columns
+	headerLines int
+	raw         bool
+	helper.RowRecorder
+}
+
+func NewTSVBlockStreamFmtReader(input io.Reader, withNames, withTypes, raw bool) *TSVBlockStreamFmtReader {
+	var headerLines int
+	if withNames {
+		headerLines++
+	}
+	if withTypes {
+		headerLines++
+	}
+	zReader := bytepool.NewZReaderDefault(&input)
+	return &TSVBlockStreamFmtReader{
+		zReader:     zReader,
+		headerLines: headerLines,
+		raw:         raw,
+		RowRecorder: helper.NewRowRecorder(zReader),
+	}
+}
+
+func (t *TSVBlockStreamFmtReader) BlockStreamFmtRead(
+	ctx context.Context, sample *data.Block, blockSize int,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStream(ctx, sample, blockSize, t)
+}
+
+func (t *TSVBlockStreamFmtReader) BlockStreamFmtReadWithOptions(
+	ctx context.Context, sample *data.Block, opts helper.ReadOptions,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStreamWithOptions(ctx, sample, opts, t)
+}
+
+func (t *TSVBlockStreamFmtReader) ReadFirstColumnTexts(
+	fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn,
+) (int, error) {
+	return helper.ReadFirstColumnTexts(fb, numRows, cols, t)
+}
+
+func (t *TSVBlockStreamFmtReader) ReadColumnTextsCont(
+	fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn,
+) (int, error) {
+	return helper.ReadColumnTextsCont(fb, numRows, cols, t)
+}
+
+func (t *TSVBlockStreamFmtReader) ReadFirstRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	if err := t.discardHeader(); err != nil {
+		return err
+	}
+	return t.ReadRowCont(fb, cols)
+}
+
+func (t *TSVBlockStreamFmtReader) ReadRowCont(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	t.StartRow()
+	return helper.ReadRow(fb, cols, t)
+}
+
+func (t *TSVBlockStreamFmtReader) discardHeader() error {
+	for i := 0; i < t.headerLines; i++ {
+		if err := helper.DiscardLine(t.zReader); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TSVBlockStreamFmtReader) InputPosition() (int64, int) {
+	return t.zReader.Position(), t.zReader.Line()
+}
+
+// ResumeAt discards the header lines and the input until offset
+func (t *TSVBlockStreamFmtReader) ResumeAt(offset int64, cols []*column.CHColumn) error {
+	if err := t.discardHeader(); err != nil {
+		return err
+	}
+	return helper.DiscardUntilPosition(t.zReader, offset)
+}
+
+// SkipRow discards the rest of current line, unless it has been read until newline already
+func (t *TSVBlockStreamFmtReader) SkipRow() error {
+	if t.zReader.AtLineStart() {
+		return nil
+	}
+	return helper.DiscardLine(t.zReader)
+}
+
+func (t *TSVBlockStreamFmtReader) ReadElem(fb *bytepool.FrameBuffer, cols []*column.CHColumn, idx int) error {
+	if idx > 0 {
+		b, err := t.zReader.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b != tab {
+			if b == newLine {
+				t.zReader.UnreadCurrentBuffer(1)
+			}
+			return fmt.Errorf("expect byte: %q, but got: %q", tab, b)
+		}
+	}
+
+	// values of Nullable columns are unescaped by ReadColumnTexts, after telling NULL apart
+	_, nullable := cols[idx].Data.(*column.NullableColumnData)
+	stop, empty, err := t.readValue(fb, !t.raw && !nullable)
+	if err == io.EOF && (idx > 0 || !empty) {
+		return nil // last row without newline
+	}
+	if err != nil {
+		return err
+	}
+
+	last := idx == len(cols)-1
+	switch {
+	case last && stop == tab:
+		return fmt.Errorf("expect %v values, got more", len(cols))
+	case last:
+		_, err = t.zReader.ReadByte() // newline ends the row
+		return err
+	case stop == newLine:
+		return fmt.Errorf("expect %v values, got %v", len(cols), idx+1)
+	}
+	return nil
+}
+
+// readValue writes the value until the next tab or newline into w, unescaping it if escaped.
+// The stop byte is left unread, empty is true if no byte was read before it.
+func (t *TSVBlockStreamFmtReader) readValue(w helper.Writer, escaped bool) (stop byte, empty bool, err error) {
+	empty = true
+	for {
+		buf, err := t.zReader.ReadNextBuffer()
+		if err != nil {
+			return 0, empty, err
+		}
+
+		i := indexTSVStop(buf, escaped)
+		if i < 0 {
+			w.Write(buf)
+			empty = false
+			continue
+		}
+		w.Write(buf[:i])
+		if i > 0 {
+			empty = false
+		}
+		if buf[i] != '\\' {
+			t.zReader.UnreadCurrentBuffer(len(buf) - i)
+			return buf[i], empty, nil
+		}
+
+		empty = false
+		t.zReader.UnreadCurrentBuffer(len(buf) - i - 1)
+		b, err := t.zReader.ReadByte()
+		if err != nil {
+			return 0, empty, err
+		}
+		if unescaped, ok := tsvUnescape[b]; ok {
+			w.WriteByte(unescaped)
+		} else { // unknown escape sequences such as \N are kept
+			w.WriteByte('\\')
+			w.WriteByte(b)
+		}
+	}
+}
+
+func indexTSVStop(buf []byte, escaped bool) int {
+	stops := "\t\n"
+	if escaped {
+		stops = "\t\n\\"
+	}
+	return bytes.IndexAny(buf, stops)
+}
+
+// ReadColumnTexts reads texts into data, values of Nullable columns are read escaped by ReadElem
+// so that a \N is NULL, while an escaped \\N is the text \N
+func (t *TSVBlockStreamFmtReader) ReadColumnTexts(data column.CHColumnData, texts []string) (int, error) {
+	nullable, ok := data.(*column.NullableColumnData)
+	if !ok {
+		return data.ReadFromTexts(texts)
+	}
+
+	values := make([]string, len(texts))
+	nulls := make([]bool, len(texts))
+	for i, text := range texts {
+		switch {
+		case text == column.NULLAlt:
+			nulls[i] = true
+		case t.raw:
+			values[i] = text
+		default:
+			values[i] = unescapeTSV(text)
+		}
+	}
+	return nullable.ReadFromTextsWithNulls(values, nulls)
+}
+
+// unescapeTSV unescapes s as readValue does
+func unescapeTSV(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			sb.WriteByte(s[i])
+			continue
+		}
+		i++
+		if unescaped, ok := tsvUnescape[s[i]]; ok {
+			sb.WriteByte(unescaped)
+		} else {
+			sb.WriteByte('\\')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,134 @@
+package format
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func readTSV(t *testing.T, format, input string) ([][]string, error) {
+	r, err := BlockStreamFmtReaderFactory(format, strings.NewReader(input), nil)
+	require.NoError(t, err)
+	sample, err := data.NewBlock(
+		[]string{"a", "b", "c"}, []column.CHColumnType{"Int32", "String", "Nullable(String)"}, 0,
+	)
+	require.NoError(t, err)
+
+	blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+	var rows [][]string
+	for b := range blockStream {
+		for i := 0; i < b.NumRows; i++ {
+			rows = append(rows, []string{
+				b.Columns[0].Data.GetString(i),
+				b.Columns[1].Data.GetString(i),
+				b.Columns[2].Data.GetString(i),
+			})
+		}
+	}
+	_, err = yield()
+	return rows, err
+}
+
+func TestTSVBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		input   string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:   "Should read tab separated values",
+			format: "TabSeparated",
+			input:  "1\ta\tx\n2\tb\ty\n3\tc\tz\n",
+			want:   [][]string{{"1", "a", "x"}, {"2", "b", "y"}, {"3", "c", "z"}},
+		},
+		{
+			name:   "Should read last row without newline",
+			format: "TSV",
+			input:  "1\ta\tx\n2\tb\ty",
+			want:   [][]string{{"1", "a", "x"}, {"2", "b", "y"}},
+		},
+		{
+			name:   "Should unescape strings and read \\N as null",
+			format: "TSV",
+			input:  "1\ta\\tb\\nc\\\\d\t\\N\n2\t\\N\t\n",
+			want:   [][]string{{"1", "a\tb\nc\\d", column.NULLDisplay}, {"2", "\\N", ""}},
+		},
+		{
+			name:   "Should read only unescaped \\N as null",
+			format: "TSV",
+			input:  "1\ta\tNULL\n2\tb\tnull\n3\tc\tᴺᵁᴸᴸ\n4\td\t\\\\N\n5\te\t\\N\n",
+			want: [][]string{
+				{"1", "a", "NULL"}, {"2", "b", "null"}, {"3", "c", "ᴺᵁᴸᴸ"}, {"4", "d", "\\N"}, {"5", "e", column.NULLDisplay},
+			},
+		},
+		{
+			name:   "Should not unescape raw strings",
+			format: "TSVRaw",
+			input:  "1\ta\\tb\tc\\\\d\n",
+			want:   [][]string{{"1", "a\\tb", "c\\\\d"}},
+		},
+		{
+			name:   "Should skip names",
+			format: "TabSeparatedWithNames",
+			input:  "a\tb\tc\n1\ta\tx\n",
+			want:   [][]string{{"1", "a", "x"}},
+		},
+		{
+			name:   "Should skip names and types",
+			format: "TSVWithNamesAndTypes",
+			input:  "a\tb\tc\nInt32\tString\tNullable(String)\n1\ta\tx\n",
+			want:   [][]string{{"1", "a", "x"}},
+		},
+		{
+			name:    "Should fail on too few values",
+			format:  "TSV",
+			input:   "1\ta\n",
+			wantErr: true,
+		},
+		{
+			name:    "Should fail on too many values",
+			format:  "TSV",
+			input:   "1\ta\tx\ty\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := readTSV(t, tt.format, tt.input)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, rows)
+		})
+	}
+}
+
+func TestTSVBlockStreamFmtReader_EscapedComposite(t *testing.T) {
+	r, err := BlockStreamFmtReaderFactory("TSV", strings.NewReader("['a\\tb','c\\nd']\t{'k\\t':'v\\\\'}\n"), nil)
+	require.NoError(t, err)
+	sample, err := data.NewBlock(
+		[]string{"a", "m"}, []column.CHColumnType{"Array(String)", "Map(String, String)"}, 0,
+	)
+	require.NoError(t, err)
+
+	blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+	var values [][]interface{}
+	for b := range blockStream {
+		for i := 0; i < b.NumRows; i++ {
+			values = append(values, []interface{}{b.Columns[0].Data.GetValue(i), b.Columns[1].Data.GetValue(i)})
+		}
+	}
+	n, err := yield()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []interface{}{[]interface{}{"a\tb", "c\nd"}, map[string]string{"k\t": "v\\"}}, values[0])
+}
@@ -0,0 +1,143 @@
+package format
+
+import (
+	"io"
+	"log"
+	"runtime/debug"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+// tsvEscaper escapes strings written in TabSeparated
+var tsvEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\t", "\\t",
+	"\n", "\\n",
+	"\r", "\\r",
+	"\b", "\\b",
+	"\f", "\\f",
+	"\x00", "\\0",
+)
+
+// TSVBlockStreamFmtWriter writes the TabSeparated family of formats, see TSVBlockStreamFmtReader
+type TSVBlockStreamFmtWriter struct {
+	zWriter   *bytepool.ZWriter
+	withNames bool
+	withTypes bool
+	raw       bool
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewTSVBlockStreamFmtWriter(w io.Writer, withNames, withTypes, raw bool) *TSVBlockStreamFmtWriter {
+	return &TSVBlockStreamFmtWriter{
+		zWriter:   bytepool.NewZWriterDefault(w),
+		withNames: withNames,
+		withTypes: withTypes,
+		raw:       raw,
+	}
+}
+
+func (t *TSVBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	t.done = make(chan struct{}, 1)
+	go t.blockStreamFmtWrite(blockStream)
+}
+
+func (t *TSVBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		t.done <- struct{}{}
+	}()
+	t.totalRowsWrite, t.exception = helper.WriteTableFromBlockStream(blockStream, t)
+}
+
+func (t *TSVBlockStreamFmtWriter) Yield() (int, error) {
+	<-t.done
+	return t.totalRowsWrite, t.exception
+}
+
+func (t *TSVBlockStreamFmtWriter) WriteFirstFrame(frame [][]string, cols []*column.CHColumn) (int, error) {
+	if t.withNames {
+		if err := t.writeHeader(cols, func(col *column.CHColumn) string { return col.Name }); err != nil {
+			return 0, err
+		}
+	}
+	if t.withTypes {
+		if err := t.writeHeader(cols, func(col *column.CHColumn) string { return string(col.Type) }); err != nil {
+			return 0, err
+		}
+	}
+	return helper.WriteFirstFrame(frame, cols, t)
+}
+
+func (t *TSVBlockStreamFmtWriter) WriteFrameCont(frame [][]string, cols []*column.CHColumn) (int, error) {
+	return helper.WriteFrameCont(frame, cols, t)
+}
+
+func (t *TSVBlockStreamFmtWriter) Flush() error {
+	return t.zWriter.Flush()
+}
+
+func (t *TSVBlockStreamFmtWriter) WriteFirstRow(record []string, cols []*column.CHColumn) error {
+	return t.writeRow(record, cols)
+}
+
+func (t *TSVBlockStreamFmtWriter) WriteRowCont(record []string, cols []*column.CHColumn) error {
+	return t.writeRow(record, cols)
+}
+
+func (t *TSVBlockStreamFmtWriter) writeHeader(cols []*column.CHColumn, text func(col *column.CHColumn) string) error {
+	for i, col := range cols {
+		if i > 0 {
+			if err := t.zWriter.WriteByte(tab); err != nil {
+				return err
+			}
+		}
+		if err := t.writeEscaped(text(col)); err != nil {
+			return err
+		}
+	}
+	return t.zWriter.WriteByte(newLine)
+}
+
+// writeRow writes the values of record separated by tabs and ends the row with a newline
+func (t *TSVBlockStreamFmtWriter) writeRow(record []string, cols []*column.CHColumn) error {
+	for i, col := range cols {
+		if i > 0 {
+			if err := t.zWriter.WriteByte(tab); err != nil {
+				return err
+			}
+		}
+		if err := t.writeValue(record[i], col); err != nil {
+			return err
+		}
+	}
+	return t.zWriter.WriteByte(newLine)
+}
+
+func (t *TSVBlockStreamFmtWriter) writeValue(s string, col *column.CHColumn) error {
+	if _, ok := col.Data.(*column.NullableColumnData); ok && s == column.NULLDisplay {
+		return t.zWriter.WriteString(column.NULLAlt)
+	}
+	if !t.raw {
+		return t.writeEscaped(s)
+	}
+	return t.zWriter.WriteString(s)
+}
+
+func (t *TSVBlockStreamFmtWriter) writeEscaped(s string) error {
+	_, err := tsvEscaper.WriteString(t.zWriter, s)
+	return err
+}
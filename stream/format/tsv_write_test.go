@@ -0,0 +1,82 @@
+package format
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestTSVBlockStreamFmtWriter_RoundTrip(t *testing.T) {
+	for _, format := range []string{"TSV", "TSVWithNames", "TSVWithNamesAndTypes", "TSVRaw"} {
+		t.Run(format, func(t *testing.T) {
+			input := "1\ta\\tb\\nc\\\\d\t\\N\n2\tb\tx\n3\t\t\n"
+			if format == "TSVRaw" {
+				input = "1\ta b\t\\N\n2\tb\tx\n3\t\t\n"
+			}
+			switch format {
+			case "TSVWithNames":
+				input = "a\tb\tc\n" + input
+			case "TSVWithNamesAndTypes":
+				input = "a\tb\tc\nInt32\tString\tNullable(String)\n" + input
+			}
+
+			r, err := BlockStreamFmtReaderFactory(format, strings.NewReader(input), nil)
+			require.NoError(t, err)
+			sample, err := data.NewBlock(
+				[]string{"a", "b", "c"}, []column.CHColumnType{"Int32", "String", "Nullable(String)"}, 0,
+			)
+			require.NoError(t, err)
+			blockStream, yield := r.BlockStreamFmtRead(context.Background(), sample, 2)
+
+			var buf bytes.Buffer
+			w, err := BlockStreamFmtWriterFactory(format, &buf, nil)
+			require.NoError(t, err)
+			w.BlockStreamFmtWrite(blockStream)
+
+			rowsWritten, err := w.Yield()
+			require.NoError(t, err)
+			rowsRead, err := yield()
+			require.NoError(t, err)
+			require.Equal(t, 3, rowsRead)
+			require.Equal(t, 3, rowsWritten)
+			require.Equal(t, input, buf.String())
+		})
+	}
+}
+
+func TestTSVBlockStreamFmtWriter_EscapedComposite(t *testing.T) {
+	sample, err := data.NewBlock(
+		[]string{"a", "c"}, []column.CHColumnType{"Array(String)", "Nullable(String)"}, 2,
+	)
+	require.NoError(t, err)
+	_, _, err = sample.ReadFromColumnValues([][]interface{}{
+		{[]string{"a\tb", "c\nd"}, []string{}},
+		{"\\N", nil},
+	})
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := NewTSVBlockStreamFmtWriter(&buf, false, false, false)
+	blockStream := make(chan *data.Block, 1)
+	blockStream <- sample
+	close(blockStream)
+	w.BlockStreamFmtWrite(blockStream)
+	rowsWritten, err := w.Yield()
+	require.NoError(t, err)
+	require.Equal(t, 2, rowsWritten)
+	require.Equal(t, "['a\\tb', 'c\\nd']\t\\\\N\n[]\t\\N\n", buf.String())
+}
+
+func TestTSVBlockStreamFmtWriter_Escape(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewTSVBlockStreamFmtWriter(&buf, false, false, false)
+	require.NoError(t, w.writeEscaped("a\tb\nc\\d\re\x00"))
+	require.NoError(t, w.Flush())
+	require.Equal(t, `a\tb\nc\\d\re\0`, buf.String())
+}
@@ -2,6 +2,7 @@ package format
 
 import (
 	"context"
+	"fmt"
 	"io"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
@@ -17,13 +18,18 @@ const (
 )
 
 func NewValuesBlockStreamReader(r io.Reader) *ValuesBlockStreamFmtReader {
+	zReader := bytepool.NewZReaderDefault(&r)
 	return &ValuesBlockStreamFmtReader{
-		zReader: bytepool.NewZReaderDefault(&r),
+		zReader:     zReader,
+		RowRecorder: helper.NewRowRecorder(zReader),
 	}
 }
 
 type ValuesBlockStreamFmtReader struct {
 	zReader *bytepool.ZReader
+	helper.RowRecorder
+	// inRow is true from the opening until the closing parenthesis of a row, used to skip a row on error
+	inRow bool
 }
 
 func (v *ValuesBlockStreamFmtReader) BlockStreamFmtRead(ctx context.Context, sample *data.Block, blockSize int,
@@ -31,6 +37,15 @@ func (v *ValuesBlockStreamFmtReader) BlockStreamFmtRead(ctx context.Context, sam
 	return helper.TableToBlockStream(ctx, sample, blockSize, v)
 }
 
+func (v *ValuesBlockStreamFmtReader) InputPosition() (int64, int) {
+	return v.zReader.Position(), v.zReader.Line()
+}
+
+func (v *ValuesBlockStreamFmtReader) BlockStreamFmtReadWithOptions(ctx context.Context, sample *data.Block, opts helper.ReadOptions,
+) (blockStream <-chan *data.Block, yield func() (int, error)) {
+	return helper.TableToBlockStreamWithOptions(ctx, sample, opts, v)
+}
+
 func (v *ValuesBlockStreamFmtReader) ReadFirstColumnTexts(fb *bytepool.FrameBuffer, numRows int, cols []*column.CHColumn) (int, error) {
 	return helper.ReadFirstColumnTexts(fb, numRows, cols, v)
 }
@@ -40,13 +55,41 @@ func (v *ValuesBlockStreamFmtReader) ReadColumnTextsCont(fb *bytepool.FrameBuffe
 }
 
 func (v *ValuesBlockStreamFmtReader) readRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
+	v.StartRow()
 	if err := helper.AssertNextByteEqual(v.zReader, '('); err != nil {
 		return err
 	}
+	v.inRow = true
 	if err := helper.ReadRow(fb, cols, v); err != nil {
 		return err
 	}
-	return helper.AssertNextByteEqual(v.zReader, ')')
+	if err := helper.AssertNextByteEqual(v.zReader, ')'); err != nil {
+		return err
+	}
+	v.inRow = false
+	return nil
+}
+
+// ResumeAt discards the input until offset, the separator after it is read by ReadRowCont
+func (v *ValuesBlockStreamFmtReader) ResumeAt(offset int64, cols []*column.CHColumn) error {
+	return helper.DiscardUntilPosition(v.zReader, offset)
+}
+
+// SkipRow discards the rest of the row which failed to be read,
+// or until the next row if the failed row has not started
+func (v *ValuesBlockStreamFmtReader) SkipRow() error {
+	if v.inRow {
+		v.inRow = false
+		_, err := helper.DiscardUntilUnnested(v.zReader, ")")
+		return err
+	}
+
+	// leave the opening parenthesis to be read by ReadRowCont
+	if _, err := helper.DiscardUntilUnnested(v.zReader, "("); err != nil {
+		return err
+	}
+	v.zReader.UnreadCurrentBuffer(1)
+	return nil
 }
 
 func (v *ValuesBlockStreamFmtReader) ReadFirstRow(fb *bytepool.FrameBuffer, cols []*column.CHColumn) error {
@@ -64,9 +107,16 @@ func (v *ValuesBlockStreamFmtReader) ReadRowCont(fb *bytepool.FrameBuffer, cols
 
 func (v *ValuesBlockStreamFmtReader) ReadElem(fb *bytepool.FrameBuffer, cols []*column.CHColumn, idx int) error {
 	if idx > 0 {
-		if err := helper.AssertNextByteEqual(v.zReader, ','); err != nil {
+		b, err := helper.ReadNextNonSpaceByte(v.zReader)
+		if err != nil {
 			return err
 		}
+		if b != ',' {
+			if b == ')' {
+				v.inRow = false
+			}
+			return fmt.Errorf("expect byte: %q, but got: %q", ',', b)
+		}
 	}
 	return v.readElem(fb, cols[idx], (len(cols)-1 == idx))
 }
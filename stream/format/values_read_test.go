@@ -262,3 +262,21 @@ func TestValuesBlockStreamFmtReader_BlockStreamFmtRead(t *testing.T) {
 		})
 	}
 }
+
+func TestValuesBlockStreamFmtReader_RowErrors(t *testing.T) {
+	input := `(1, 'x'),
+('bad', 'y'),
+(3, 'z', [1, ')'], 'extra'),
+(4.5, 'd'),
+(5, 'w')`
+	r := NewValuesBlockStreamReader(bytes.NewReader([]byte(input)))
+
+	values, rowErrs, progress := readSkippingRowErrors(t, r)
+	require.Equal(t, []interface{}{int32(1), int32(5)}, values)
+	require.Equal(t, []int{2, 3, 4}, rowErrorLines(rowErrs))
+	require.Equal(t, "a", rowErrs[0].Column)
+	require.Equal(t, "", rowErrs[1].Column)
+	require.Equal(t, "(3, 'z', [1, ')'], 'extra')", rowErrs[1].Raw)
+	require.Equal(t, "4.5", rowErrs[2].Value)
+	require.Equal(t, 3, progress.RowsSkipped())
+}
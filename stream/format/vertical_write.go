@@ -0,0 +1,119 @@
+package format
+
+import (
+	"io"
+	"log"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/bytepool"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+// VerticalBlockStreamFmtWriter writes each row as a numbered section with a line per column, like \G of the client.
+// Values are aligned after the column names and rows are separated by an empty line.
+//
+//	Row 1:
+//	──────
+//	id:   1
+//	name: hello
+type VerticalBlockStreamFmtWriter struct {
+	zWriter *bytepool.ZWriter
+	// prefixes are the names of the columns followed by a colon and padding to align the values
+	prefixes []string
+	// rowNum is the number of rows written
+	rowNum int
+
+	totalRowsWrite int
+	exception      error
+	done           chan struct{}
+}
+
+func NewVerticalBlockStreamFmtWriter(w io.Writer) *VerticalBlockStreamFmtWriter {
+	return &VerticalBlockStreamFmtWriter{
+		zWriter: bytepool.NewZWriterDefault(w),
+	}
+}
+
+func (v *VerticalBlockStreamFmtWriter) BlockStreamFmtWrite(blockStream <-chan *data.Block) {
+	v.done = make(chan struct{}, 1)
+	go v.blockStreamFmtWrite(blockStream)
+}
+
+func (v *VerticalBlockStreamFmtWriter) blockStreamFmtWrite(blockStream <-chan *data.Block) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+		}
+	}()
+	defer func() {
+		v.done <- struct{}{}
+	}()
+	v.totalRowsWrite, v.exception = helper.WriteTableFromBlockStream(blockStream, v)
+}
+
+func (v *VerticalBlockStreamFmtWriter) Yield() (int, error) {
+	<-v.done
+	return v.totalRowsWrite, v.exception
+}
+
+func (v *VerticalBlockStreamFmtWriter) WriteFirstFrame(frame [][]string, cols []*column.CHColumn) (int, error) {
+	var maxLen int
+	for _, col := range cols {
+		if n := spaceCount(col.Name); n > maxLen {
+			maxLen = n
+		}
+	}
+	v.prefixes = make([]string, len(cols))
+	for i, col := range cols {
+		v.prefixes[i] = col.Name + ":" + strings.Repeat(" ", maxLen-spaceCount(col.Name)+1)
+	}
+	return helper.WriteFirstFrame(frame, cols, v)
+}
+
+func (v *VerticalBlockStreamFmtWriter) WriteFrameCont(frame [][]string, cols []*column.CHColumn) (int, error) {
+	return helper.WriteFrameCont(frame, cols, v)
+}
+
+func (v *VerticalBlockStreamFmtWriter) Flush() error {
+	return v.zWriter.Flush()
+}
+
+func (v *VerticalBlockStreamFmtWriter) WriteFirstRow(record []string, cols []*column.CHColumn) error {
+	return v.writeRow(record)
+}
+
+func (v *VerticalBlockStreamFmtWriter) WriteRowCont(record []string, cols []*column.CHColumn) error {
+	return v.writeRow(record)
+}
+
+func (v *VerticalBlockStreamFmtWriter) writeRow(record []string) error {
+	if v.rowNum > 0 {
+		if err := v.zWriter.WriteByte(newLine); err != nil {
+			return err
+		}
+	}
+	v.rowNum++
+
+	title := "Row " + strconv.Itoa(v.rowNum) + ":"
+	if err := v.zWriter.WriteString(title + "\n" + strings.Repeat(dash, len(title)) + "\n"); err != nil {
+		return err
+	}
+	for i, field := range record {
+		if err := v.zWriter.WriteString(v.prefixes[i]); err != nil {
+			return err
+		}
+		if err := v.zWriter.WriteString(field); err != nil {
+			return err
+		}
+		if err := v.zWriter.WriteByte(newLine); err != nil {
+			return err
+		}
+	}
+	return nil
+}
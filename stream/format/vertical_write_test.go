@@ -0,0 +1,31 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestVerticalBlockStreamFmtWriter(t *testing.T) {
+	names := []string{"id", "name", "你好"}
+	types := []column.CHColumnType{"Int32", "Nullable(String)", "String"}
+	texts := [][]string{{"1", "2"}, {"hello", "NULL"}, {"x", ""}}
+
+	want := `Row 1:
+──────
+id:   1
+name: hello
+你好: x
+
+Row 2:
+──────
+id:   2
+name: ᴺᵁᴸᴸ
+你好: 
+`
+	for _, rowsPerBlock := range []int{1, 2} {
+		require.Equal(t, want, string(writeBlocks(t, "Vertical", names, types, texts, rowsPerBlock, nil)))
+	}
+}
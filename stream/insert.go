@@ -19,14 +19,26 @@ func HandleInsertFromFmtStream(
 	sendBlock SendBlock, cancelInsert CancelInsert, handleResp CallBackResp,
 	opts ...InsertOption,
 ) (int, error) {
-	sample, err := CallBackUntilFirstBlock(ctx, respStream, handleResp)
+	sample, err := CallBackUntilFirstBlock(ctx, respStream, func(resp response.Packet) {
+		// omitted columns are filled with the defaults of the table columns, sent before the sample block
+		if tableColumns, ok := resp.(*response.TableColumnsPacket); ok {
+			if setter, ok := blockReader.(format.ColumnDefaultsSetter); ok {
+				setter.SetColumnDefaults(tableColumns.ColumnDefaults())
+			}
+		}
+		handleResp(resp)
+	})
 	if err != nil {
 		return 0, err
 	}
 
 	eg, ctx := errgroup.WithContext(ctx)
 	insertProcess := NewInsertProcess(sample, sendBlock, cancelInsert, opts...)
-	blockInputStream, yield := blockReader.BlockStreamFmtRead(ctx, sample, insertProcess.BatchSize())
+	if err := insertProcess.loadCheckpoint(blockReader); err != nil {
+		cancelInsert()
+		return 0, err
+	}
+	blockInputStream, yield := format.BlockStreamFmtReadWithOptions(ctx, blockReader, sample, insertProcess.ReadOptions())
 	insertProcess.Start(ctx, blockInputStream, respStream)
 
 	var rowsRead, rowsSent int
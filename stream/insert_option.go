@@ -1,10 +1,31 @@
 package stream
 
+import (
+	"io"
+	"time"
+
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
 type InsertOption func(process *InsertProcess)
 
 func OptionBatchSize(n int) InsertOption {
 	return func(process *InsertProcess) {
-		process.batchSize = n
+		process.limits.Rows = n
+	}
+}
+
+// OptionBatchBytes flushes a block once its estimated encoded size reaches n bytes, disabled if n is zero
+func OptionBatchBytes(n int) InsertOption {
+	return func(process *InsertProcess) {
+		process.limits.Bytes = n
+	}
+}
+
+// OptionFlushInterval flushes a block once it has been accumulating for d, disabled if d is zero
+func OptionFlushInterval(d time.Duration) InsertOption {
+	return func(process *InsertProcess) {
+		process.limits.Interval = d
 	}
 }
 
@@ -19,3 +40,96 @@ func OptionAddCallBackResp(callback CallBackResp) InsertOption {
 		process.callBackResp = callback
 	}
 }
+
+// OptionBlockParallelism sets the number of workers building blocks from row values
+func OptionBlockParallelism(n int) InsertOption {
+	return func(process *InsertProcess) {
+		process.blockParallelism = n
+	}
+}
+
+// OptionCompress tells whether blocks are sent compressed, so that they can be encoded ahead of time
+func OptionCompress(compress bool) InsertOption {
+	return func(process *InsertProcess) {
+		process.compress = compress
+	}
+}
+
+// OptionProgress calls progress after every block sent, and once more after the last one
+func OptionProgress(progress func(InsertProgress)) InsertOption {
+	return func(process *InsertProcess) {
+		process.progress = progress
+		if process.readProgress == nil {
+			process.readProgress = &helper.ReadProgress{}
+		}
+	}
+}
+
+// OptionDryRun reads all rows into blocks without sending them, ending the insert with no data.
+// Rows which cannot be read are collected and returned as *DryRunError by Finish,
+// unless a handler is set by OptionRowErrorHandler.
+func OptionDryRun() InsertOption {
+	return func(process *InsertProcess) {
+		process.dryRun = true
+	}
+}
+
+// OptionRowErrorHandler calls handler for every row which cannot be read, see helper.ReadOptions.OnRowError
+func OptionRowErrorHandler(handler helper.RowErrorHandler) InsertOption {
+	return func(process *InsertProcess) {
+		process.onRowError = handler
+	}
+}
+
+// OptionMaxRowErrors skips rows which cannot be read instead of failing the insert.
+// The insert fails once more than count rows are skipped and, if ratio is not zero,
+// the skipped rows are more than ratio of the rows read. The ratio is checked before each block is sent.
+func OptionMaxRowErrors(count int, ratio float64) InsertOption {
+	return func(process *InsertProcess) {
+		process.limitRowErrors = true
+		process.maxRowErrors = count
+		process.maxRowErrorsRatio = ratio
+		if process.readProgress == nil {
+			process.readProgress = &helper.ReadProgress{}
+		}
+	}
+}
+
+// OptionRejectWriter writes every row which cannot be read to w, one line per row with
+// its line number, error and column texts. Rows are skipped within the limits of OptionMaxRowErrors,
+// without it the first rejected row fails the insert.
+func OptionRejectWriter(w io.Writer) InsertOption {
+	return func(process *InsertProcess) {
+		process.rejectWriter = w
+		process.limitRowErrors = true
+		if process.readProgress == nil {
+			process.readProgress = &helper.ReadProgress{}
+		}
+	}
+}
+
+// OptionCheckpoint saves the input inserted from formatted input to store once acknowledged by the server,
+// and resumes the insert after the checkpoint in store if there is one. The input has to be the same.
+// The server acknowledges the blocks of an insert only at its end, see OptionCheckpointRows to save
+// checkpoints during the insert. Checkpoints are not saved in dry run.
+func OptionCheckpoint(store CheckpointStore) InsertOption {
+	return func(process *InsertProcess) {
+		process.checkpoints = store
+	}
+}
+
+// OptionCheckpointRows ends the insert once rows rows were sent since the last checkpoint, saves the checkpoint
+// when the server acknowledged it and continues with a new insert of the same query.
+// The insert has to be restartable, see OptionRestartInsert.
+func OptionCheckpointRows(rows int) InsertOption {
+	return func(process *InsertProcess) {
+		process.checkpointRows = rows
+	}
+}
+
+// OptionRestartInsert sets how the insert is restarted after a checkpoint of OptionCheckpointRows
+func OptionRestartInsert(restart RestartInsert) InsertOption {
+	return func(process *InsertProcess) {
+		process.restartInsert = restart
+	}
+}
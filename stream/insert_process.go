@@ -2,12 +2,19 @@ package stream
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
 	"github.com/bytehouse-cloud/driver-go/driver/response"
+	"github.com/bytehouse-cloud/driver-go/stream/format"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
 	"github.com/bytehouse-cloud/driver-go/utils"
 )
 
@@ -16,13 +23,34 @@ type (
 	CancelInsert func()
 	Logf         func(s string, args ...interface{})
 	CallBackResp func(resp response.Packet)
+	// RestartInsert sends the insert query again once the previous insert ended,
+	// and returns the responses of the server to it
+	RestartInsert func() (<-chan response.Packet, error)
 )
 
+// InsertProgress is the progress of an insert reported by OptionProgress
+type InsertProgress struct {
+	// BytesRead is the number of input bytes consumed, 0 if unknown
+	BytesRead int64
+	// RowsRead is the number of rows parsed from input, 0 if rows are not read from a formatted input
+	RowsRead int
+	// RowsSkipped is the number of rows skipped because of row errors
+	RowsSkipped int
+	// RowsSent is the number of rows sent, or validated in dry run
+	RowsSent int
+	// BlocksSent is the number of blocks sent, or validated in dry run
+	BlocksSent int
+}
+
 type InsertProcess struct {
 	// Sample block to copy from when construct blocks
 	sample *data.Block
-	// maximum number of row column buffer can hold before it's flushed across network
-	batchSize int
+	// limits of rows, estimated bytes and time column buffer can hold before it's flushed across network
+	limits helper.BlockLimits
+	// number of workers building blocks from row values
+	blockParallelism int
+	// whether blocks are sent compressed
+	compress bool
 	// Stream to send blocks
 	inputBlockStream <-chan *data.Block
 	// callback function to send block
@@ -35,12 +63,46 @@ type InsertProcess struct {
 	serverResponses <-chan response.Packet
 	// rowsProcessed stores the total rows of data read and processed into blocks
 	rowsSent int
+	// blocksSent stores the total number of non-empty blocks sent
+	blocksSent int
 	// done signal if the process is completed
 	done chan struct{}
 	// stores error of the insert process
 	err error
 	// callback function for logging
 	logf Logf
+	// callback function for progress, readProgress is updated by the reader of the formatted input
+	progress     func(InsertProgress)
+	readProgress *helper.ReadProgress
+	// dryRun validates blocks without sending them
+	dryRun bool
+	// onRowError handles rows which cannot be read, rowErrors collects them in dry run if not set
+	onRowError helper.RowErrorHandler
+	rowErrors  helper.RowErrors
+	rowErrorMu sync.Mutex
+	// rowErrorCount is the number of rows skipped, the insert fails once both
+	// maxRowErrors and maxRowErrorsRatio are exceeded if limitRowErrors is set.
+	// rowParseErrorCount are the rows among them which could not be parsed into texts.
+	rowErrorCount      int
+	rowParseErrorCount int
+	limitRowErrors     bool
+	maxRowErrors       int
+	maxRowErrorsRatio  float64
+	// rejectWriter receives rows which cannot be read with their error
+	rejectWriter io.Writer
+	// checkpoints saves the input inserted, resume is the checkpoint loaded from it to continue after
+	checkpoints CheckpointStore
+	resume      *Checkpoint
+	// blockEnds are the ends in the input of the blocks read and not yet received
+	blockEnds   []helper.InputMark
+	blockEndsMu sync.Mutex
+	// lastSent is the end of the last block sent, saved as checkpoint once the server acknowledges the insert
+	lastSent    *helper.InputMark
+	rowsUnacked int
+	// checkpointRows is the number of rows sent after which the insert is ended with restartInsert,
+	// so that the rows are acknowledged by the server and checkpointed, disabled if zero
+	checkpointRows int
+	restartInsert  RestartInsert
 }
 
 func NewInsertProcess(sample *data.Block, sendBlock SendBlock, cancelInsert CancelInsert, opts ...InsertOption) *InsertProcess {
@@ -97,11 +159,20 @@ func (p *InsertProcess) startLogging(ctx context.Context) {
 		lastRecordedRowsSent int
 	)
 
+	p.logf("insert block limits: rows = %v, bytes = %v, flush interval = %v",
+		p.limits.Rows, p.limits.Bytes, p.limits.Interval,
+	)
+
 	defer func() {
 		duration := time.Since(timeStart)
 		averageSpeed := float64(p.rowsSent) / duration.Seconds()
-		p.logf("total rows sent: %v, average speed = %v rows/s",
+		var averageBlockRows int
+		if p.blocksSent > 0 {
+			averageBlockRows = p.rowsSent / p.blocksSent
+		}
+		p.logf("total rows sent: %v, average speed = %v rows/s, blocks sent: %v, average rows per block: %v",
 			utils.FormatCount(int64(p.rowsSent)), utils.FormatCount(int64(averageSpeed)),
+			p.blocksSent, utils.FormatCount(int64(averageBlockRows)),
 		)
 	}()
 
@@ -128,26 +199,50 @@ func (p *InsertProcess) watchEvents(ctx context.Context) error {
 			case *response.ExceptionPacket:
 				return resp
 			case *response.EndOfStreamPacket:
-				return nil
+				return p.checkpointDone()
 			default:
 				p.callBackResp(resp)
 			}
 		case b, ok := <-p.inputBlockStream:
 			if !ok {
+				if err := p.checkRowErrorRatio(); err != nil {
+					p.cancelInsert()
+					return err
+				}
 				if err := p.sendBlock(&data.Block{}); err != nil {
 					return err
 				}
 				p.inputBlockStream = nil
+				p.reportProgress()
 				continue
 			}
+			end, hasEnd := p.popBlockEnd()
 			if b.NumRows == 0 {
 				continue
 			}
-			if err := p.sendBlock(b); err != nil {
+			if err := p.checkRowErrorRatio(); err != nil {
+				_ = b.Close()
+				p.cancelInsert()
 				return err
 			}
+			if !p.dryRun {
+				if err := p.sendBlock(b); err != nil {
+					return err
+				}
+			}
+			if hasEnd {
+				p.lastSent = &end
+			}
 			p.rowsSent += b.NumRows
+			p.rowsUnacked += b.NumRows
+			p.blocksSent++
 			_ = b.Close()
+			p.reportProgress()
+			if p.shouldCheckpoint() {
+				if err := p.checkpointInsert(ctx); err != nil {
+					return err
+				}
+			}
 		}
 	}
 }
@@ -156,17 +251,303 @@ func (p *InsertProcess) NumColumns() int {
 	return p.sample.NumColumns
 }
 
+// MaxDryRunRowErrors is the number of row errors kept by a dry run, further errors are only counted
+const MaxDryRunRowErrors = 1000
+
+// DryRunError is returned by a dry run which found rows that cannot be read.
+// It unwraps to RowErrors, so errors.As can be used with helper.RowErrors.
+type DryRunError struct {
+	// RowErrors are the first MaxDryRunRowErrors row errors found, ordered by row
+	RowErrors helper.RowErrors
+	// Total is the number of row errors found
+	Total int
+}
+
+func (e *DryRunError) Error() string {
+	return fmt.Sprintf("dry run found %v row errors, kept %s", e.Total, e.RowErrors.Error())
+}
+
+func (e *DryRunError) Unwrap() error {
+	return e.RowErrors
+}
+
+// Finish waits for the insert to complete. In dry run, rows which cannot be read are returned as *DryRunError
 func (p *InsertProcess) Finish() (int, error) {
 	<-p.done
+	if p.err == nil && p.dryRun && len(p.rowErrors) > 0 {
+		p.rowErrorMu.Lock()
+		p.truncateRowErrors()
+		p.rowErrorMu.Unlock()
+		return p.rowsSent, &DryRunError{RowErrors: p.rowErrors, Total: p.RowErrorCount()}
+	}
 	return p.rowsSent, p.err
 }
 
+// ReadOptions returns the options to read formatted input into blocks for this process
+func (p *InsertProcess) ReadOptions() helper.ReadOptions {
+	opts := helper.ReadOptions{
+		Limits:      p.limits,
+		Progress:    p.readProgress,
+		Parallelism: p.blockParallelism,
+	}
+	if p.onRowError != nil || p.dryRun || p.limitRowErrors {
+		opts.OnRowError = p.handleRowError
+	}
+	if p.resume != nil {
+		opts.Resume = &helper.InputMark{Offset: p.resume.Offset, Rows: p.resume.Rows}
+	}
+	if p.checkpoints != nil && !p.dryRun {
+		opts.OnBlock = p.pushBlockEnd
+	}
+	return opts
+}
+
+// loadCheckpoint loads the checkpoint to resume after if checkpoints are set, r has to be able to resume
+func (p *InsertProcess) loadCheckpoint(r format.BlockStreamFmtReader) error {
+	if p.checkpoints == nil {
+		return nil
+	}
+	if _, ok := r.(helper.Resumer); !ok {
+		return fmt.Errorf("input format does not support checkpoints")
+	}
+	checkpoint, ok, err := p.checkpoints.Load()
+	if err != nil {
+		return fmt.Errorf("load checkpoint: %w", err)
+	}
+	if ok {
+		p.resume = &checkpoint
+	}
+	return nil
+}
+
+// pushBlockEnd is called by the reader before each block is emitted
+func (p *InsertProcess) pushBlockEnd(end helper.InputMark) {
+	p.blockEndsMu.Lock()
+	defer p.blockEndsMu.Unlock()
+	p.blockEnds = append(p.blockEnds, end)
+}
+
+// popBlockEnd returns the end of the block received, ok is false if ends are not tracked
+func (p *InsertProcess) popBlockEnd() (end helper.InputMark, ok bool) {
+	p.blockEndsMu.Lock()
+	defer p.blockEndsMu.Unlock()
+	if len(p.blockEnds) == 0 {
+		return end, false
+	}
+	end = p.blockEnds[0]
+	p.blockEnds = p.blockEnds[1:]
+	return end, true
+}
+
+// shouldCheckpoint tells if checkpointRows rows were sent since the last checkpoint and the insert can be restarted
+func (p *InsertProcess) shouldCheckpoint() bool {
+	return p.checkpoints != nil && !p.dryRun && p.restartInsert != nil &&
+		p.checkpointRows > 0 && p.rowsUnacked >= p.checkpointRows && p.lastSent != nil
+}
+
+// checkpointInsert ends the insert, saves the checkpoint of the last block sent once the server acknowledged it,
+// and starts a new insert to send the following blocks
+func (p *InsertProcess) checkpointInsert(ctx context.Context) error {
+	if err := p.sendBlock(&data.Block{}); err != nil {
+		return err
+	}
+	if err := p.waitEndOfInsert(ctx); err != nil {
+		return err
+	}
+	if err := p.saveCheckpoint(false); err != nil {
+		return err
+	}
+
+	serverResponses, err := p.restartInsert()
+	if err != nil {
+		return fmt.Errorf("restart insert: %w", err)
+	}
+	p.serverResponses = serverResponses
+	if _, err := CallBackUntilFirstBlock(ctx, serverResponses, p.callBackResp); err != nil {
+		return fmt.Errorf("restart insert: %w", err)
+	}
+	return nil
+}
+
+// waitEndOfInsert waits for the server to acknowledge the end of the insert and for its responses to be closed,
+// so that the connection is free to send the next query
+func (p *InsertProcess) waitEndOfInsert(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			p.cancelInsert()
+			return context.Canceled
+		case resp, ok := <-p.serverResponses:
+			if !ok {
+				return fmt.Errorf("insert ended without acknowledgement")
+			}
+			switch resp := resp.(type) {
+			case *response.ExceptionPacket:
+				return resp
+			case *response.EndOfStreamPacket:
+				for range p.serverResponses {
+				}
+				return nil
+			default:
+				p.callBackResp(resp)
+			}
+		}
+	}
+}
+
+// checkpointDone saves the end of the last block sent as done once the server acknowledged the insert
+func (p *InsertProcess) checkpointDone() error {
+	if p.checkpoints == nil || p.dryRun {
+		return nil
+	}
+	return p.saveCheckpoint(true)
+}
+
+// saveCheckpoint saves the end of the last block sent, which must have been acknowledged by the server
+func (p *InsertProcess) saveCheckpoint(done bool) error {
+	var checkpoint Checkpoint
+	if p.resume != nil {
+		checkpoint = *p.resume
+	}
+	if p.lastSent != nil {
+		checkpoint.Offset, checkpoint.Rows = p.lastSent.Offset, p.lastSent.Rows
+	}
+	checkpoint.Done = done
+	if err := p.checkpoints.Save(checkpoint); err != nil {
+		return fmt.Errorf("save checkpoint: %w", err)
+	}
+	p.rowsUnacked = 0
+	return nil
+}
+
+// handleRowError can be called concurrently by the stages reading the input
+func (p *InsertProcess) handleRowError(rowErr *helper.RowError) error {
+	p.rowErrorMu.Lock()
+	defer p.rowErrorMu.Unlock()
+
+	p.rowErrorCount++
+	if rowErr.Column == "" {
+		p.rowParseErrorCount++
+	}
+	if p.rejectWriter != nil {
+		if err := writeRejectedRow(p.rejectWriter, rowErr); err != nil {
+			return fmt.Errorf("write rejected row: %w", err)
+		}
+	}
+	if p.onRowError != nil {
+		if err := p.onRowError(rowErr); err != nil {
+			return err
+		}
+	} else if p.dryRun {
+		p.keepRowError(rowErr)
+	}
+
+	// without a ratio the count can be checked right away, the ratio is checked before each block is sent
+	if p.limitRowErrors && !p.dryRun && p.maxRowErrorsRatio == 0 && p.rowErrorCount > p.maxRowErrors {
+		return fmt.Errorf("too many row errors, allowed: %v, last: %w", p.maxRowErrors, rowErr)
+	}
+	return nil
+}
+
+// keepRowError keeps rowErr among the dry run errors. Blocks are read by parallel workers, so errors are not
+// collected in order of rows: up to twice MaxDryRunRowErrors are kept before truncating them to the first rows.
+func (p *InsertProcess) keepRowError(rowErr *helper.RowError) {
+	p.rowErrors = append(p.rowErrors, rowErr)
+	if len(p.rowErrors) >= 2*MaxDryRunRowErrors {
+		p.truncateRowErrors()
+	}
+}
+
+// truncateRowErrors sorts the dry run errors by row and keeps the first MaxDryRunRowErrors of them
+func (p *InsertProcess) truncateRowErrors() {
+	sort.SliceStable(p.rowErrors, func(i, j int) bool {
+		return p.rowErrors[i].Row < p.rowErrors[j].Row
+	})
+	if len(p.rowErrors) > MaxDryRunRowErrors {
+		p.rowErrors = p.rowErrors[:MaxDryRunRowErrors]
+	}
+}
+
+// checkRowErrorRatio compares the rows skipped to all rows in the input so far. Rows which could not be parsed
+// are not counted by ReadProgress.RowsRead, so they are added to have the same ratio wherever a row failed.
+func (p *InsertProcess) checkRowErrorRatio() error {
+	if !p.limitRowErrors || p.dryRun {
+		return nil
+	}
+
+	p.rowErrorMu.Lock()
+	defer p.rowErrorMu.Unlock()
+	if p.rowErrorCount <= p.maxRowErrors {
+		return nil
+	}
+	rowsRead := p.readProgress.RowsRead() + p.rowParseErrorCount
+	if rowsRead > 0 && float64(p.rowErrorCount)/float64(rowsRead) <= p.maxRowErrorsRatio {
+		return nil
+	}
+	return fmt.Errorf("too many row errors: %v of %v rows, allowed: %v or ratio %v",
+		p.rowErrorCount, rowsRead, p.maxRowErrors, p.maxRowErrorsRatio,
+	)
+}
+
+// writeRejectedRow writes the error of the row followed by its column texts separated by tabs,
+// or by its input if the row could not be parsed
+func writeRejectedRow(w io.Writer, rowErr *helper.RowError) error {
+	var sb strings.Builder
+	sb.WriteString(rowErr.Error())
+	for _, text := range rowErr.Texts {
+		sb.WriteByte('\t')
+		sb.WriteString(text)
+	}
+	if rowErr.Texts == nil && rowErr.Raw != "" {
+		sb.WriteByte('\t')
+		sb.WriteString(rowErr.Raw)
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// RowErrorCount returns the number of rows skipped because they could not be read
+func (p *InsertProcess) RowErrorCount() int {
+	p.rowErrorMu.Lock()
+	defer p.rowErrorMu.Unlock()
+	return p.rowErrorCount
+}
+
+func (p *InsertProcess) reportProgress() {
+	if p.progress == nil {
+		return
+	}
+	p.progress(InsertProgress{
+		BytesRead:   p.readProgress.BytesRead(),
+		RowsRead:    p.readProgress.RowsRead(),
+		RowsSkipped: p.readProgress.RowsSkipped(),
+		RowsSent:    p.rowsSent,
+		BlocksSent:  p.blocksSent,
+	})
+}
+
 func (p *InsertProcess) Error() error {
 	return p.err
 }
 
 func (p *InsertProcess) BatchSize() int {
-	return p.batchSize
+	return p.limits.Rows
+}
+
+// BlockLimits returns the limits at which blocks are flushed to server
+func (p *InsertProcess) BlockLimits() helper.BlockLimits {
+	return p.limits
+}
+
+// BlockParallelism returns the number of workers building blocks from row values
+func (p *InsertProcess) BlockParallelism() int {
+	return p.blockParallelism
+}
+
+// Compress returns true if blocks are sent compressed
+func (p *InsertProcess) Compress() bool {
+	return p.compress
 }
 
 func (p *InsertProcess) Sample() *data.Block {
@@ -0,0 +1,258 @@
+package stream
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+	"github.com/bytehouse-cloud/driver-go/driver/response"
+	"github.com/bytehouse-cloud/driver-go/stream/format"
+	"github.com/bytehouse-cloud/driver-go/stream/format/helper"
+)
+
+// insertCSV inserts input to a fake server with a table (a Int32, b String), returns the values of a received
+func insertCSV(t *testing.T, input string, opts ...InsertOption) ([]int32, error) {
+	return insertCSVBrokenAt(t, input, -1, opts...)
+}
+
+// insertCSVBrokenAt is insertCSV with the connection broken when sending the block at index brokenAt
+func insertCSVBrokenAt(t *testing.T, input string, brokenAt int, opts ...InsertOption) ([]int32, error) {
+	sample, err := data.NewBlock([]string{"a", "b"}, []column.CHColumnType{"Int32", "String"}, 0)
+	require.NoError(t, err)
+	respStream := make(chan response.Packet, 2)
+	respStream <- &response.DataPacket{Block: sample}
+	restart := func() (<-chan response.Packet, error) {
+		respStream = make(chan response.Packet, 2)
+		respStream <- &response.DataPacket{Block: sample}
+		return respStream, nil
+	}
+
+	var received []int32
+	var blocks int
+	sendBlock := func(b *data.Block) error {
+		if b.NumRows == 0 {
+			respStream <- &response.EndOfStreamPacket{}
+			close(respStream)
+			return nil
+		}
+		if blocks == brokenAt {
+			return errors.New("broken connection")
+		}
+		blocks++
+		for i := 0; i < b.NumRows; i++ {
+			received = append(received, b.Columns[0].Data.GetValue(i).(int32))
+		}
+		return nil
+	}
+
+	r, err := format.NewCSVBlockStreamFmtReader(strings.NewReader(input), false, nil)
+	require.NoError(t, err)
+	opts = append([]InsertOption{OptionBatchSize(2), OptionRestartInsert(restart)}, opts...)
+	_, err = HandleInsertFromFmtStream(context.Background(), respStream, r,
+		sendBlock, func() {}, func(resp response.Packet) {}, opts...,
+	)
+	return received, err
+}
+
+func TestHandleInsertFromFmtStream_RowErrors(t *testing.T) {
+	const input = "1,a\nx,b\n3,c\n4.5,d\n5,e\n"
+
+	t.Run("first row error fails insert", func(t *testing.T) {
+		_, err := insertCSV(t, input)
+		require.Error(t, err)
+	})
+
+	t.Run("skip rows within max count", func(t *testing.T) {
+		var rejected bytes.Buffer
+		received, err := insertCSV(t, input, OptionMaxRowErrors(2, 0), OptionRejectWriter(&rejected))
+		require.NoError(t, err)
+		require.Equal(t, []int32{1, 3, 5}, received)
+		require.Equal(t,
+			"line 2, row 1, column a, value \"x\": strconv.ParseInt: parsing \"x\": invalid syntax\tx\tb\n"+
+				"line 4, row 3, column a, value \"4.5\": strconv.ParseInt: parsing \"4.5\": invalid syntax\t4.5\td\n",
+			rejected.String(),
+		)
+	})
+
+	t.Run("max count exceeded", func(t *testing.T) {
+		var rejected bytes.Buffer
+		_, err := insertCSV(t, input, OptionMaxRowErrors(1, 0), OptionRejectWriter(&rejected))
+		require.Error(t, err)
+		require.Equal(t, 2, strings.Count(rejected.String(), "\n"))
+	})
+
+	t.Run("within max ratio", func(t *testing.T) {
+		received, err := insertCSV(t, input, OptionMaxRowErrors(0, 0.5))
+		require.NoError(t, err)
+		require.Equal(t, []int32{1, 3, 5}, received)
+	})
+
+	t.Run("max ratio exceeded", func(t *testing.T) {
+		_, err := insertCSV(t, input, OptionMaxRowErrors(0, 0.2))
+		require.Error(t, err)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		var progress InsertProgress
+		received, err := insertCSV(t, input, OptionDryRun(), OptionProgress(func(p InsertProgress) {
+			progress = p
+		}))
+		require.Empty(t, received)
+		var dryRunErr *DryRunError
+		require.ErrorAs(t, err, &dryRunErr)
+		require.Equal(t, 2, dryRunErr.Total)
+		var rowErrs helper.RowErrors
+		require.ErrorAs(t, err, &rowErrs)
+		require.Len(t, rowErrs, 2)
+		require.Equal(t, 4, rowErrs[1].Line)
+		require.Equal(t, InsertProgress{
+			BytesRead:   int64(len(input)),
+			RowsRead:    5,
+			RowsSkipped: 2,
+			RowsSent:    3,
+			BlocksSent:  3,
+		}, progress)
+	})
+}
+
+func TestHandleInsertFromFmtStream_Checkpoint(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(&sb, "%v,s\n", i)
+	}
+	input := sb.String()
+
+	t.Run("checkpoint at end of insert", func(t *testing.T) {
+		store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+		// the insert is not acknowledged, nothing is checkpointed
+		received, err := insertCSVBrokenAt(t, input, 4, OptionCheckpoint(store))
+		require.Error(t, err)
+		require.Equal(t, []int32{0, 1, 2, 3, 4, 5, 6, 7}, received)
+		_, ok, err := store.Load()
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		received, err = insertCSV(t, input, OptionCheckpoint(store))
+		require.NoError(t, err)
+		require.Len(t, received, 10)
+		checkpoint, ok, err := store.Load()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, Checkpoint{Offset: int64(len(input)), Rows: 10, Done: true}, checkpoint)
+
+		// a done load is not inserted again
+		received, err = insertCSV(t, input, OptionCheckpoint(store))
+		require.NoError(t, err)
+		require.Empty(t, received)
+	})
+
+	t.Run("checkpoint every rows", func(t *testing.T) {
+		store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint.json"))
+
+		// blocks of 2 rows, the insert is acknowledged after 2 blocks and broken at the 4th
+		received, err := insertCSVBrokenAt(t, input, 3, OptionCheckpoint(store), OptionCheckpointRows(4))
+		require.Error(t, err)
+		require.Equal(t, []int32{0, 1, 2, 3, 4, 5}, received)
+		checkpoint, ok, err := store.Load()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, Checkpoint{Offset: int64(len("0,s\n1,s\n2,s\n3,s\n")), Rows: 4}, checkpoint)
+
+		// the rows sent after the checkpoint are sent again
+		received, err = insertCSV(t, input, OptionCheckpoint(store), OptionCheckpointRows(4))
+		require.NoError(t, err)
+		require.Equal(t, []int32{4, 5, 6, 7, 8, 9}, received)
+		checkpoint, ok, err = store.Load()
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, Checkpoint{Offset: int64(len(input)), Rows: 10, Done: true}, checkpoint)
+	})
+}
+
+func TestHandleInsertFromFmtStream_ColumnDefaults(t *testing.T) {
+	insertJSON := func(input string) ([][]interface{}, []response.Packet, error) {
+		sample, err := data.NewBlock([]string{"a", "b", "c"}, []column.CHColumnType{"Int32", "String", "Int32"}, 0)
+		require.NoError(t, err)
+		respStream := make(chan response.Packet, 3)
+		respStream <- &response.TableColumnsPacket{
+			Table: "sample_table",
+			Description: "columns format version: 1\n3 columns:\n`a` Int32\n" +
+				"`b` String\tDEFAULT\t\\'none\\'\n`c` Int32\tDEFAULT\ta + 1\n",
+		}
+		respStream <- &response.DataPacket{Block: sample}
+
+		var received [][]interface{}
+		sendBlock := func(b *data.Block) error {
+			if b.NumRows == 0 {
+				respStream <- &response.EndOfStreamPacket{}
+				return nil
+			}
+			for i := 0; i < b.NumRows; i++ {
+				received = append(received, []interface{}{
+					b.Columns[0].Data.GetValue(i), b.Columns[1].Data.GetValue(i), b.Columns[2].Data.GetValue(i),
+				})
+			}
+			return nil
+		}
+
+		r, err := format.BlockStreamFmtReaderFactory("JSONEachRow", strings.NewReader(input), nil)
+		require.NoError(t, err)
+		var packets []response.Packet
+		_, err = HandleInsertFromFmtStream(context.Background(), respStream, r,
+			sendBlock, func() {}, func(resp response.Packet) {
+				packets = append(packets, resp)
+			}, OptionBatchSize(2),
+		)
+		return received, packets, err
+	}
+
+	received, packets, err := insertJSON(`{"a": 1, "c": 3}` + "\n" + `{"a": 2, "b": "x", "c": 4}`)
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{{int32(1), "none", int32(3)}, {int32(2), "x", int32(4)}}, received)
+	require.IsType(t, &response.TableColumnsPacket{}, packets[0])
+
+	// the default of c is not a literal, which only the server can evaluate
+	_, _, err = insertJSON(`{"a": 1}`)
+	require.ErrorContains(t, err, "column c is omitted and its default is not a literal: a + 1")
+}
+
+func TestInsertProcess_KeepRowErrors(t *testing.T) {
+	p := &InsertProcess{dryRun: true}
+	// errors of parallel workers arrive out of order of rows
+	for row := 3*MaxDryRunRowErrors - 1; row >= 0; row-- {
+		require.NoError(t, p.handleRowError(&helper.RowError{Row: row, Err: errors.New("bad row")}))
+	}
+	require.Less(t, len(p.rowErrors), 2*MaxDryRunRowErrors)
+
+	p.truncateRowErrors()
+	require.Len(t, p.rowErrors, MaxDryRunRowErrors)
+	for i, rowErr := range p.rowErrors {
+		require.Equal(t, i, rowErr.Row)
+	}
+	require.Equal(t, 3*MaxDryRunRowErrors, p.RowErrorCount())
+}
+
+func TestWriteRejectedRow(t *testing.T) {
+	var rejected bytes.Buffer
+	require.NoError(t, writeRejectedRow(&rejected, &helper.RowError{
+		Row: 1, Line: 2, Column: "a", Value: "x", Texts: []string{"x", "b"}, Err: errors.New("bad value"),
+	}))
+	// rows which could not be parsed are written as read from input, so that they can be replayed
+	require.NoError(t, writeRejectedRow(&rejected, &helper.RowError{
+		Row: 2, Line: 3, Raw: "(3, 'z', 'extra')", Err: errors.New("bad row"),
+	}))
+	require.Equal(t,
+		"line 2, row 1, column a, value \"x\": bad value\tx\tb\n"+
+			"line 3, row 2: bad row\t(3, 'z', 'extra')\n",
+		rejected.String(),
+	)
+}
@@ -15,18 +15,51 @@ type RecycleColumnValues func(columnValues [][]interface{})
 
 var recycleColumnValuesNoOp RecycleColumnValues = func(columnValues [][]interface{}) {}
 
+type ColumnValuesToBlockOption func(process *ColumnValuesToBlock)
+
+// OptionParallelism sets the number of workers converting column values into blocks.
+// Blocks are emitted in the same order as the column values are received.
+func OptionParallelism(n int) ColumnValuesToBlockOption {
+	return func(process *ColumnValuesToBlock) {
+		process.setParallelism(n)
+	}
+}
+
+// OptionEncode makes workers also encode each block, compressed if compress is true,
+// so that sending the block only copies the encoded bytes.
+func OptionEncode(compress bool) ColumnValuesToBlockOption {
+	return func(process *ColumnValuesToBlock) {
+		process.encode = true
+		process.compress = compress
+	}
+}
+
 type ColumnValuesToBlock struct {
 	sample        *data.Block
 	cvStream      <-chan [][]interface{}
 	rowsProcessed int
 	parallelism   int
+	encode        bool
+	compress      bool
 	errGroup      *errgroup.Group
 	recycle       RecycleColumnValues
 	err           error
 	done          chan struct{}
 }
 
-func NewColumnValuesToBlock(cvStream <-chan [][]interface{}, sample *data.Block) *ColumnValuesToBlock {
+// blockJob is a unit of work for a worker, result is sent to the future in order of submission
+type blockJob struct {
+	columns [][]interface{}
+	future  chan<- *blockResult
+}
+
+type blockResult struct {
+	block    *data.Block
+	rowsRead int
+	err      error
+}
+
+func NewColumnValuesToBlock(cvStream <-chan [][]interface{}, sample *data.Block, opts ...ColumnValuesToBlockOption) *ColumnValuesToBlock {
 	process := &ColumnValuesToBlock{
 		sample:      sample,
 		cvStream:    cvStream,
@@ -34,6 +67,10 @@ func NewColumnValuesToBlock(cvStream <-chan [][]interface{}, sample *data.Block)
 		recycle:     recycleColumnValuesNoOp,
 	}
 
+	for _, opt := range opts {
+		opt(process)
+	}
+
 	return process
 }
 
@@ -42,6 +79,10 @@ func (a *ColumnValuesToBlock) Start(ctx context.Context) <-chan *data.Block {
 	a.errGroup, ctx = errgroup.WithContext(ctx)
 	a.done = make(chan struct{})
 
+	// futures is bounded by parallelism and consumed in order, which keeps the blocks ordered
+	futures := make(chan chan *blockResult, a.parallelism)
+	jobs := make(chan *blockJob, a.parallelism)
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -53,40 +94,17 @@ func (a *ColumnValuesToBlock) Start(ctx context.Context) <-chan *data.Block {
 		defer close(outputStream)
 		defer close(a.done)
 
+		a.errGroup.Go(func() error {
+			return a.dispatch(ctx, jobs, futures)
+		})
 		for i := 0; i < a.parallelism; i++ {
 			a.errGroup.Go(func() error {
-				defer func() {
-					if r := recover(); r != nil {
-						log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
-							r,
-							string(debug.Stack()))
-					}
-				}()
-				for {
-					select {
-					case <-ctx.Done():
-						return ctx.Err()
-					case columns, ok := <-a.cvStream:
-						if !ok {
-							return nil
-						}
-
-						numRows := len(columns[0])
-						newBlock := a.sample.StructureCopy(numRows)
-						rowsRead, colsRead, err := newBlock.ReadFromColumnValues(columns)
-						a.rowsProcessed += rowsRead
-						outputStream <- newBlock
-						if err != nil {
-							return fmt.Errorf(
-								"reading into block error. row_idx: %v, col_idx: %v, name: %v, type: %v, given: %v, err: %s",
-								rowsRead, colsRead, newBlock.Columns[colsRead].Name, newBlock.Columns[colsRead].Type, columns[colsRead][rowsRead], err,
-							)
-						}
-						a.recycle(columns)
-					}
-				}
+				return a.work(ctx, jobs)
 			})
 		}
+		a.errGroup.Go(func() error {
+			return a.collect(ctx, futures, outputStream)
+		})
 
 		a.err = a.errGroup.Wait()
 	}()
@@ -94,12 +112,122 @@ func (a *ColumnValuesToBlock) Start(ctx context.Context) <-chan *data.Block {
 	return outputStream
 }
 
+// dispatch hands out column values to workers, registering a future for each of them
+func (a *ColumnValuesToBlock) dispatch(ctx context.Context, jobs chan<- *blockJob, futures chan<- chan *blockResult) error {
+	defer close(jobs)
+	defer close(futures)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case columns, ok := <-a.cvStream:
+			if !ok {
+				return nil
+			}
+
+			future := make(chan *blockResult, 1)
+			select {
+			case futures <- future:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			select {
+			case jobs <- &blockJob{columns: columns, future: future}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (a *ColumnValuesToBlock) work(ctx context.Context, jobs <-chan *blockJob) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case job, ok := <-jobs:
+			if !ok {
+				return nil
+			}
+			job.future <- a.safeToBlock(job.columns)
+		}
+	}
+}
+
+// safeToBlock recovers a panic of toBlock into an error result, so the future is always completed
+func (a *ColumnValuesToBlock) safeToBlock(columns [][]interface{}) (result *blockResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("A runtime panic has occurred with err = [%s],  stacktrace = [%s]\n",
+				r,
+				string(debug.Stack()))
+			result = &blockResult{err: fmt.Errorf("panic while reading into block: %v", r)}
+		}
+	}()
+	return a.toBlock(columns)
+}
+
+func (a *ColumnValuesToBlock) toBlock(columns [][]interface{}) *blockResult {
+	numRows := len(columns[0])
+	newBlock := a.sample.StructureCopy(numRows)
+	rowsRead, colsRead, err := newBlock.ReadFromColumnValues(columns)
+	if err != nil {
+		return &blockResult{
+			block:    newBlock,
+			rowsRead: rowsRead,
+			err: fmt.Errorf(
+				"reading into block error. row_idx: %v, col_idx: %v, name: %v, type: %v, given: %v, err: %s",
+				rowsRead, colsRead, newBlock.Columns[colsRead].Name, newBlock.Columns[colsRead].Type, columns[colsRead][rowsRead], err,
+			),
+		}
+	}
+	a.recycle(columns)
+
+	if a.encode {
+		if err := newBlock.Encode(a.compress); err != nil {
+			return &blockResult{block: newBlock, rowsRead: rowsRead, err: fmt.Errorf("encode block error: %s", err)}
+		}
+	}
+
+	return &blockResult{block: newBlock, rowsRead: rowsRead}
+}
+
+// collect waits on each future in order of submission and emits its block
+func (a *ColumnValuesToBlock) collect(ctx context.Context, futures <-chan chan *blockResult, outputStream chan<- *data.Block) error {
+	for future := range futures {
+		var result *blockResult
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case result = <-future:
+		}
+
+		a.rowsProcessed += result.rowsRead
+		if result.block == nil {
+			return result.err
+		}
+		select {
+		case outputStream <- result.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if result.err != nil {
+			return result.err
+		}
+	}
+	return nil
+}
+
 func (a *ColumnValuesToBlock) Finish() (rowsProcessed int, err error) {
 	<-a.done
 	return a.rowsProcessed, a.err
 }
 
 func (a *ColumnValuesToBlock) setParallelism(n int) {
+	if n < 1 {
+		n = 1
+	}
 	a.parallelism = n
 }
 
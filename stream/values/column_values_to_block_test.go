@@ -0,0 +1,141 @@
+package values
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data"
+	"github.com/bytehouse-cloud/driver-go/driver/lib/data/column"
+)
+
+func TestColumnValuesToBlock_KeepsOrder(t *testing.T) {
+	sample := getSampleBlock(t)
+	for _, parallelism := range []int{1, 2, 8} {
+		t.Run(fmt.Sprintf("parallelism_%v", parallelism), func(t *testing.T) {
+			cvStream := make(chan [][]interface{})
+			process := NewColumnValuesToBlock(cvStream, sample, OptionParallelism(parallelism), OptionEncode(true))
+			blockStream := process.Start(context.Background())
+
+			const numBlocks = 50
+			go func() {
+				for i := 0; i < numBlocks; i++ {
+					cvStream <- newColumnValues(i, i%7+1)
+				}
+				close(cvStream)
+			}()
+
+			var i, totalRows int
+			for b := range blockStream {
+				assert.Equal(t, i%7+1, b.NumRows)
+				assert.Equal(t, fmt.Sprint(i), b.Columns[0].Data.GetString(0))
+				totalRows += b.NumRows
+				i++
+			}
+			rowsProcessed, err := process.Finish()
+			require.NoError(t, err)
+			assert.Equal(t, numBlocks, i)
+			assert.Equal(t, totalRows, rowsProcessed)
+		})
+	}
+}
+
+func TestColumnValuesToBlock_Error(t *testing.T) {
+	cvStream := make(chan [][]interface{}, 2)
+	process := NewColumnValuesToBlock(cvStream, getSampleBlock(t), OptionParallelism(4))
+	blockStream := process.Start(context.Background())
+
+	cvStream <- newColumnValues(0, 2)
+	cvStream <- [][]interface{}{{"not a number"}, {"a"}}
+	close(cvStream)
+
+	for range blockStream {
+	}
+	_, err := process.Finish()
+	assert.Error(t, err)
+}
+
+func TestColumnValuesToBlock_Panic(t *testing.T) {
+	cvStream := make(chan [][]interface{}, 1)
+	process := NewColumnValuesToBlock(cvStream, getSampleBlock(t), OptionParallelism(2))
+	blockStream := process.Start(context.Background())
+
+	// no columns makes toBlock panic on columns[0]
+	cvStream <- [][]interface{}{}
+	close(cvStream)
+
+	done := make(chan error)
+	go func() {
+		for range blockStream {
+		}
+		_, err := process.Finish()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("panic in worker left the process hanging")
+	}
+}
+
+func BenchmarkColumnValuesToBlock_Parallelism(b *testing.B) {
+	const (
+		blockRows = 8192
+		numBlocks = 32
+	)
+	sample := getSampleBlock(b)
+	columnValues := make([][][]interface{}, numBlocks)
+	for i := range columnValues {
+		columnValues[i] = newColumnValues(i, blockRows)
+	}
+
+	for _, parallelism := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("parallelism_%v", parallelism), func(b *testing.B) {
+			b.ReportAllocs()
+			start := time.Now()
+			for n := 0; n < b.N; n++ {
+				cvStream := make(chan [][]interface{}, parallelism)
+				process := NewColumnValuesToBlock(cvStream, sample, OptionParallelism(parallelism), OptionEncode(true))
+				blockStream := process.Start(context.Background())
+				go func() {
+					for _, cv := range columnValues {
+						cvStream <- cv
+					}
+					close(cvStream)
+				}()
+				for range blockStream {
+				}
+				if _, err := process.Finish(); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(blockRows*numBlocks*b.N)/time.Since(start).Seconds(), "rows/s")
+		})
+	}
+}
+
+func getSampleBlock(t testing.TB) *data.Block {
+	b, err := data.NewBlock(
+		[]string{"id", "payload"},
+		[]column.CHColumnType{column.UINT64, column.STRING},
+		0,
+	)
+	require.NoError(t, err)
+	return b
+}
+
+// newColumnValues returns column values of numRows, where the first id is start
+func newColumnValues(start, numRows int) [][]interface{} {
+	ids := make([]interface{}, numRows)
+	payloads := make([]interface{}, numRows)
+	for i := range ids {
+		ids[i] = uint64(start + i)
+		payloads[i] = fmt.Sprintf("payload of row %v with some padding to compress", start+i)
+	}
+	return [][]interface{}{ids, payloads}
+}
@@ -0,0 +1,75 @@
+package values
+
+import (
+	"reflect"
+	"time"
+)
+
+// EstimateEncodedSize returns an estimate of the number of bytes v takes once encoded into a block.
+// The estimate is meant to bound block sizes and is not exact for every column type.
+func EstimateEncodedSize(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 1
+	case string:
+		return uvarintSize(len(v)) + len(v)
+	case []byte:
+		return uvarintSize(len(v)) + len(v)
+	case bool, int8, uint8:
+		return 1
+	case int16, uint16:
+		return 2
+	case int32, uint32, float32:
+		return 4
+	case int, uint, int64, uint64, float64, time.Time:
+		return 8
+	case *string:
+		if v == nil {
+			return 1
+		}
+		return 1 + EstimateEncodedSize(*v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return 1
+		}
+		return 1 + EstimateEncodedSize(rv.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		size := 8 // offset
+		for i := 0; i < rv.Len(); i++ {
+			size += EstimateEncodedSize(rv.Index(i).Interface())
+		}
+		return size
+	case reflect.Map:
+		size := 8 // offset
+		iter := rv.MapRange()
+		for iter.Next() {
+			size += EstimateEncodedSize(iter.Key().Interface())
+			size += EstimateEncodedSize(iter.Value().Interface())
+		}
+		return size
+	default:
+		return 16
+	}
+}
+
+// EstimateRowsEncodedSize returns the sum of EstimateEncodedSize of all args
+func EstimateRowsEncodedSize(args []interface{}) int {
+	var size int
+	for _, arg := range args {
+		size += EstimateEncodedSize(arg)
+	}
+	return size
+}
+
+func uvarintSize(n int) int {
+	size := 1
+	for n >= 0x80 {
+		n >>= 7
+		size++
+	}
+	return size
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/bytehouse-cloud/driver-go/errors"
 	"github.com/bytehouse-cloud/driver-go/stream/format"
@@ -32,19 +33,35 @@ type InsertQuery struct {
 	Values  string
 }
 
-var (
-	// You can test the insert into regex here: https://regex101.com/r/8OW9OC/1
-	insertIntoRe   = regexp.MustCompile("(?i)\\bINSERT\\s+INTO\\s+(((`[^`]*`)|([^\\s^\\.]*))\\.)?((`[^`]*`)|([^\\s^(]*))\\s*(\\([^)]*\\))?\\s*")
-	insertFormatRe = regexp.MustCompile(fmt.Sprintf(`(?i)\s*\b(%s|%s|%s|%s)\b`,
-		format.Formats[format.CSVWITHNAMES],
-		format.Formats[format.CSV],
-		format.Formats[format.VALUES],
-		format.Formats[format.JSON],
-	))
-)
+// You can test the insert into regex here: https://regex101.com/r/8OW9OC/1
+var insertIntoRe = regexp.MustCompile("(?i)\\bINSERT\\s+INTO\\s+(((`[^`]*`)|([^\\s^\\.]*))\\.)?((`[^`]*`)|([^\\s^(]*))\\s*(\\([^)]*\\))?\\s*")
+
+// insertFormatRe matches the names of formats which can be inserted, compiled again when formats are registered
+var insertFormatRe struct {
+	sync.Mutex
+	formats string
+	re      *regexp.Regexp
+}
+
+// insertFormatRegexp returns the regex of the names of formats registered with format.RegisterReader, including aliases
+func insertFormatRegexp() *regexp.Regexp {
+	names := format.ReaderFormats()
+	for i, name := range names {
+		names[i] = regexp.QuoteMeta(name)
+	}
+	formats := strings.Join(names, "|")
+
+	insertFormatRe.Lock()
+	defer insertFormatRe.Unlock()
+	if insertFormatRe.re == nil || insertFormatRe.formats != formats {
+		insertFormatRe.formats = formats
+		insertFormatRe.re = regexp.MustCompile(fmt.Sprintf(`(?i)\s*\b(%s)\b`, formats))
+	}
+	return insertFormatRe.re
+}
 
 /*
-In this method, we Parse the Insert query into dataFmt (which can be CSV, VALUES, JSON or CSVWITHNAMES),
+In this method, we Parse the Insert query into dataFmt (which can be CSV, VALUES, JSON, CSVWITHNAMES, one of the TabSeparated or the JSONEachRow formats),
 Query (excluding the values but including the format) and the values.
 This is done by first matching the first part of the insert query with our InsertInto regex: INSERT INTO [db.]table [(c1, c2, c3)]
 
@@ -57,9 +74,10 @@ func ParseInsertQuery(query string) (*InsertQuery, error) {
 	if len(insertIntoArr) != 2 {
 		return nil, errors.ErrorfWithCaller("cannot parse invalid insert query")
 	}
-	dataFmt := strings.ToUpper(strings.TrimSpace(insertFormatRe.FindString(insertIntoArr[1])))
+	formatRe := insertFormatRegexp()
+	dataFmt := strings.ToUpper(strings.TrimSpace(formatRe.FindString(insertIntoArr[1])))
 
-	formatArr := insertFormatRe.Split(insertIntoArr[1], 2)
+	formatArr := formatRe.Split(insertIntoArr[1], 2)
 	if len(formatArr) != 2 {
 		return nil, errors.ErrorfWithCaller("cannot parse invalid insert query")
 	}
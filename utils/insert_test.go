@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/bytehouse-cloud/driver-go/stream/format"
 )
 
 func Test_parseInsertQuery(t *testing.T) {
@@ -81,6 +84,38 @@ func Test_parseInsertQuery(t *testing.T) {
 				Values:  "(4294967295,'RED BLUE YELLOW')",
 			},
 		},
+		{
+			name: "Should parse insert Query tsv",
+			args: args{
+				query: "INSERT INTO demo_db_one.sample_table FORMAT TSVWithNames INFILE 'read.tsv'",
+			},
+			want: &InsertQuery{
+				DataFmt: "TSVWITHNAMES",
+				Query:   "INSERT INTO demo_db_one.sample_table FORMAT TSVWITHNAMES",
+				Values:  "INFILE 'read.tsv'",
+			},
+		},
+		{
+			name: "Should parse insert Query tab separated",
+			args: args{
+				query: "INSERT INTO demo_db_one.sample_table FORMAT TabSeparated INFILE 'read.tsv'",
+			},
+			want: &InsertQuery{
+				DataFmt: "TABSEPARATED",
+				Query:   "INSERT INTO demo_db_one.sample_table FORMAT TABSEPARATED",
+				Values:  "INFILE 'read.tsv'",
+			},
+		},
+		{
+			name: "Should parse insert Query parquet",
+			args: args{
+				query: "INSERT INTO demo_db_one.sample_table FORMAT Parquet",
+			},
+			want: &InsertQuery{
+				DataFmt: "PARQUET",
+				Query:   "INSERT INTO demo_db_one.sample_table FORMAT PARQUET",
+			},
+		},
 		{
 			name: "Should parse insert Query json",
 			args: args{
@@ -266,3 +301,15 @@ func Test_IsInsert(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseInsertQuery_RegisteredFormat(t *testing.T) {
+	format.RegisterReader("EventLog", func(r io.Reader, settings map[string]interface{}) (format.BlockStreamFmtReader, error) {
+		return format.NewCSVBlockStreamFmtReader(r, false, settings)
+	})
+
+	got, err := ParseInsertQuery("insert into events format EventLog INFILE 'events.log'")
+	assert.NoError(t, err)
+	assert.Equal(t, "EVENTLOG", got.DataFmt)
+	assert.Equal(t, "insert into events format EVENTLOG", got.Query)
+	assert.Equal(t, "INFILE 'events.log'", got.Values)
+}